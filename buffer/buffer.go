@@ -8,6 +8,14 @@ var _ = fmt.Sprintf("dummy")
 // MaxRuneWidth is sizeof(rune) datatype.
 const MaxRuneWidth = 4
 
+// RuneRawBase is the start of reserved rune range that represent
+// invalid utf8 bytes within a buffer. An invalid byte b, which is
+// always >= 0x80, is held as rune RuneRawBase+b and encoded back
+// as b, so that any file can be loaded and saved byte for byte.
+// The range falls within utf16 low surrogates, which are never
+// decoded from valid utf8.
+const RuneRawBase = rune(0xdc00)
+
 // Statistics is a key,value map of counters.
 type Statistics map[string]interface{}
 
@@ -25,6 +33,17 @@ var ErrorIndexOutofbound = errors.New("buffer.indexOutofbound")
 // ErrorInvalidEncoding
 var ErrorInvalidEncoding = errors.New("buffer.invalidEncoding")
 
+// ErrorUnknownEncoding says the text encoding is not registered.
+var ErrorUnknownEncoding = errors.New("buffer.unknownEncoding")
+
+// ErrorUnrepresentable says text has characters that cannot be
+// encoded in the chosen text encoding.
+var ErrorUnrepresentable = errors.New("buffer.unrepresentable")
+
+// ErrorInvalidNewline says the line ending is not one of lf, crlf
+// or cr.
+var ErrorInvalidNewline = errors.New("buffer.invalidNewline")
+
 // ErrorReadonlyBuffer says buffer cannot be changed.
 var ErrorReadonlyBuffer = errors.New("editbuffer.ronly")
 
@@ -34,6 +53,22 @@ var ErrorOldestChange = errors.New("editbuffer.oldestChange")
 // ErrorLatestChange says there is no more change to redo.
 var ErrorLatestChange = errors.New("editbuffer.latestChange")
 
+// ErrorPrunedChange says the change was discarded from the change
+// tree, by pruning or merging, and cannot be changed any more.
+var ErrorPrunedChange = errors.New("editbuffer.prunedChange")
+
+// ErrorTransaction says there is no transaction to commit or abort,
+// or the change was not made within the transaction.
+var ErrorTransaction = errors.New("editbuffer.transaction")
+
+// ErrorStaleHistory says undo history does not belong to the text
+// of the file, which was changed after the history was written.
+var ErrorStaleHistory = errors.New("editbuffer.staleHistory")
+
+// ErrorUndoFile says there is no undo file to read or write
+// history, "undodir" setting or the buffer's file is missing.
+var ErrorUndoFile = errors.New("editbuffer.undoFile")
+
 // Buffer describes a buffer and APIs to access the buffer,
 // where a buffer can be implemented as linear array, gap-buffer,
 // rope-buffer, line-buffer etc.
@@ -0,0 +1,649 @@
+// Package buffertest implements a conformance suite for the
+// buffer.Buffer interface. Every implementation of Buffer is
+// expected to pass it:
+//
+//	func TestConformance(t *testing.T) {
+//		buffertest.Run(t, func(bs []byte) buffer.Buffer {
+//			return buffer.NewLinearBuffer(bs)
+//		})
+//	}
+package buffertest
+
+import "io"
+import "fmt"
+import "bytes"
+import "reflect"
+import "testing"
+import "unicode/utf8"
+
+import "github.com/prataprc/v/buffer"
+
+// Factory returns a new buffer initialized with bs, bs is utf8
+// encoded text.
+type Factory func(bs []byte) buffer.Buffer
+
+// Text used by the suite, a mix of ascii, multibyte runes, 4-byte
+// runes and newlines.
+var Text = "hello 世界\n🙂 ünïcode\r\n中國;pinyin 左司馬販\n"
+
+// Binary used by the suite, text with invalid utf8 like latin-1,
+// truncated sequences, stray continuation bytes and overlong
+// encoding, that shall be loaded and saved byte for byte.
+var Binary = []byte("caf\xe9 \xe4\xb8 ok\x80\x80\x80\x80 \xc0\xaf 世界\xff")
+
+// Run the conformance suite against buffers created by factory.
+func Run(t *testing.T, factory Factory) {
+	t.Run("Empty", func(t *testing.T) { testEmpty(t, factory) })
+	t.Run("Content", func(t *testing.T) { testContent(t, factory) })
+	t.Run("Slice", func(t *testing.T) { testSlice(t, factory) })
+	t.Run("Split", func(t *testing.T) { testSplit(t, factory) })
+	t.Run("Concat", func(t *testing.T) { testConcat(t, factory) })
+	t.Run("Insert", func(t *testing.T) { testInsert(t, factory) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, factory) })
+	t.Run("InsertIn", func(t *testing.T) { testInsertIn(t, factory) })
+	t.Run("DeleteIn", func(t *testing.T) { testDeleteIn(t, factory) })
+	t.Run("Persistent", func(t *testing.T) { testPersistent(t, factory) })
+	t.Run("Derived", func(t *testing.T) { testDerived(t, factory) })
+	t.Run("Stream", func(t *testing.T) { testStream(t, factory) })
+	t.Run("BackStream", func(t *testing.T) { testBackStream(t, factory) })
+	t.Run("Symmetry", func(t *testing.T) { testSymmetry(t, factory) })
+	t.Run("Stats", func(t *testing.T) { testStats(t, factory) })
+	t.Run("Invalid", func(t *testing.T) { testInvalid(t, factory) })
+	t.Run("RawSeams", func(t *testing.T) { testRawSeams(t, factory) })
+}
+
+func testEmpty(t *testing.T, factory Factory) {
+	buf := factory([]byte{})
+	if x := buf.Length(); x != 0 {
+		t.Fatalf("expected %v, got %v", 0, x)
+	} else if x := buf.Size(); x != 0 {
+		t.Fatalf("expected %v, got %v", 0, x)
+	} else if x := buf.Runes(); len(x) != 0 {
+		t.Fatalf("expected no runes, got %v", x)
+	} else if x := buf.Bytes(); len(x) != 0 {
+		t.Fatalf("expected no bytes, got %v", x)
+	}
+	if x := readAll(t, buf.StreamFrom(0)); len(x) != 0 {
+		t.Fatalf("expected no runes, got %v", x)
+	} else if x := readAll(t, buf.BackStreamFrom(0)); len(x) != 0 {
+		t.Fatalf("expected no runes, got %v", x)
+	} else if x := readAll(t, buf.StreamCount(0, 10)); len(x) != 0 {
+		t.Fatalf("expected no runes, got %v", x)
+	}
+	if x := buf.Slice(0, 0); x == nil || x.Length() != 0 {
+		t.Fatalf("expected empty slice, got %v", x)
+	} else if x := buf.Slice(0, 10); x == nil || x.Length() != 0 {
+		t.Fatalf("expected empty slice, got %v", x)
+	} else if x := buf.Slice(1, 0); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	}
+	if left, right := buf.Split(0); left != buf || right != nil {
+		t.Fatalf("unexpected split %v %v", left, right)
+	}
+	if x := string(buf.Insert(0, []rune("🙂x")).Runes()); x != "🙂x" {
+		t.Fatalf("expected %q, got %q", "🙂x", x)
+	} else if buf.Length() != 0 {
+		t.Fatalf("expected original to remain empty")
+	}
+}
+
+func testContent(t *testing.T, factory Factory) {
+	buf, ref := factory([]byte(Text)), []rune(Text)
+	if x, y := int64(len(ref)), buf.Length(); x != y {
+		t.Fatalf("expected %v, got %v", x, y)
+	} else if x, y := int64(len(Text)), buf.Size(); x != y {
+		t.Fatalf("expected %v, got %v", x, y)
+	} else if x := string(buf.Runes()); x != Text {
+		t.Fatalf("expected %q, got %q", Text, x)
+	} else if x := string(buf.Bytes()); x != Text {
+		t.Fatalf("expected %q, got %q", Text, x)
+	}
+}
+
+func testSlice(t *testing.T, factory Factory) {
+	buf, ref := factory([]byte(Text)), []rune(Text)
+	l := int64(len(ref))
+	for rCur := int64(0); rCur <= l; rCur++ {
+		for rn := int64(0); rCur+rn <= l+1; rn++ {
+			end := rCur + rn
+			if end > l {
+				end = l
+			}
+			slice := buf.Slice(rCur, rn)
+			if slice == nil {
+				t.Fatalf("unexpected nil for Slice(%v, %v)", rCur, rn)
+			} else if x, y := string(ref[rCur:end]), string(slice.Runes()); x != y {
+				t.Fatalf("Slice(%v, %v) expected %q, got %q", rCur, rn, x, y)
+			} else if x, y := end-rCur, slice.Length(); x != y {
+				t.Fatalf("Slice(%v, %v) expected %v, got %v", rCur, rn, x, y)
+			} else if x, y := int64(len(string(ref[rCur:end]))), slice.Size(); x != y {
+				t.Fatalf("Slice(%v, %v) expected %v, got %v", rCur, rn, x, y)
+			}
+		}
+	}
+	// slice of a slice.
+	slice := buf.Slice(3, 20).Slice(2, 10)
+	if x, y := string(ref[5:15]), string(slice.Runes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	}
+	// invalid cursors.
+	if x := buf.Slice(-1, 2); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	} else if x := buf.Slice(l+1, 0); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	} else if x := buf.Slice(2, -1); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	}
+}
+
+func testSplit(t *testing.T, factory Factory) {
+	buf, ref := factory([]byte(Text)), []rune(Text)
+	l := int64(len(ref))
+	for rCur := int64(1); rCur < l; rCur++ {
+		left, right := buf.Split(rCur)
+		if left == nil || right == nil {
+			t.Fatalf("Split(%v) unexpected nil", rCur)
+		} else if x, y := string(ref[:rCur]), string(left.Runes()); x != y {
+			t.Fatalf("Split(%v) expected %q, got %q", rCur, x, y)
+		} else if x, y := string(ref[rCur:]), string(right.Runes()); x != y {
+			t.Fatalf("Split(%v) expected %q, got %q", rCur, x, y)
+		}
+	}
+	if left, right := buf.Split(0); left != nil || right != buf {
+		t.Fatalf("Split(0) unexpected %v %v", left, right)
+	} else if left, right := buf.Split(l); left != buf || right != nil {
+		t.Fatalf("Split(%v) unexpected %v %v", l, left, right)
+	} else if left, right := buf.Split(-1); left != nil || right != nil {
+		t.Fatalf("Split(-1) unexpected %v %v", left, right)
+	}
+	if x := string(buf.Runes()); x != Text {
+		t.Fatalf("expected %q, got %q", Text, x)
+	}
+}
+
+func testConcat(t *testing.T, factory Factory) {
+	buf, ref := factory([]byte(Text)), []rune(Text)
+	for rCur := int64(1); rCur < int64(len(ref)); rCur += 3 {
+		left, right := buf.Split(rCur)
+		if x := string(left.Concat(right).Runes()); x != Text {
+			t.Fatalf("expected %q, got %q", Text, x)
+		}
+	}
+	other := factory([]byte("🙂 more"))
+	if x, y := Text+"🙂 more", string(buf.Concat(other).Runes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	} else if x, y := "🙂 more"+Text, string(other.Concat(buf).Runes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	} else if x := string(buf.Runes()); x != Text {
+		t.Fatalf("expected %q, got %q", Text, x)
+	}
+	empty := factory([]byte{})
+	if x := string(buf.Concat(empty).Runes()); x != Text {
+		t.Fatalf("expected %q, got %q", Text, x)
+	} else if x := string(empty.Concat(buf).Runes()); x != Text {
+		t.Fatalf("expected %q, got %q", Text, x)
+	}
+}
+
+func testInsert(t *testing.T, factory Factory) {
+	buf, ref := factory([]byte(Text)), []rune(Text)
+	l := int64(len(ref))
+	text := []rune("🙂中x")
+	for rCur := int64(0); rCur <= l; rCur++ {
+		newbuf := buf.Insert(rCur, text)
+		x := string(ref[:rCur]) + string(text) + string(ref[rCur:])
+		if y := string(newbuf.Runes()); x != y {
+			t.Fatalf("Insert(%v) expected %q, got %q", rCur, x, y)
+		} else if y := newbuf.Length(); y != l+3 {
+			t.Fatalf("Insert(%v) expected %v, got %v", rCur, l+3, y)
+		} else if y := string(buf.Runes()); y != Text {
+			t.Fatalf("Insert(%v) changed the original to %q", rCur, y)
+		}
+	}
+	// successive inserts, all versions are preserved.
+	versions, refs := []buffer.Buffer{buf}, []string{Text}
+	for i := 0; i < 20; i++ {
+		last, rs := versions[len(versions)-1], []rune(refs[len(refs)-1])
+		rCur := int64(i*7) % (last.Length() + 1)
+		x := string(rs[:rCur]) + "ab🙂" + string(rs[rCur:])
+		versions = append(versions, last.Insert(rCur, []rune("ab🙂")))
+		refs = append(refs, x)
+	}
+	for i, version := range versions {
+		if x := string(version.Runes()); x != refs[i] {
+			t.Fatalf("version %v expected %q, got %q", i, refs[i], x)
+		}
+	}
+	if x := string(buf.Insert(3, []rune{}).Runes()); x != Text {
+		t.Fatalf("expected %q, got %q", Text, x)
+	} else if x := buf.Insert(3, nil); x != buf {
+		t.Fatalf("expected same reference for nil text")
+	}
+	expectPanic(t, buffer.ErrorIndexOutofbound, func() {
+		buf.Insert(-1, text)
+	})
+	expectPanic(t, buffer.ErrorIndexOutofbound, func() {
+		buf.Insert(l+1, text)
+	})
+}
+
+func testDelete(t *testing.T, factory Factory) {
+	buf, ref := factory([]byte(Text)), []rune(Text)
+	l := int64(len(ref))
+	for rCur := int64(0); rCur <= l; rCur++ {
+		for rn := int64(0); rCur+rn <= l; rn += 3 {
+			newbuf := buf.Delete(rCur, rn)
+			x := string(ref[:rCur]) + string(ref[rCur+rn:])
+			if y := string(newbuf.Runes()); x != y {
+				t.Fatalf("Delete(%v, %v) expected %q, got %q", rCur, rn, x, y)
+			} else if y := newbuf.Length(); y != l-rn {
+				t.Fatalf("Delete(%v, %v) expected %v, got %v", rCur, rn, l-rn, y)
+			} else if y := string(buf.Runes()); y != Text {
+				t.Fatalf("Delete(%v, %v) changed the original %q", rCur, rn, y)
+			}
+		}
+	}
+	if x := string(buf.Delete(0, l).Runes()); x != "" {
+		t.Fatalf("expected empty buffer, got %q", x)
+	} else if x := buf.Delete(3, 0); x != buf {
+		t.Fatalf("expected same reference for rn 0")
+	}
+	expectPanic(t, buffer.ErrorIndexOutofbound, func() {
+		buf.Delete(-1, 2)
+	})
+	expectPanic(t, buffer.ErrorIndexOutofbound, func() {
+		buf.Delete(l-1, 2)
+	})
+	expectPanic(t, buffer.ErrorIndexOutofbound, func() {
+		buf.Delete(l+1, 1)
+	})
+}
+
+func testInsertIn(t *testing.T, factory Factory) {
+	buf, ref := factory([]byte(Text)), Text
+	if readonly(t, func() { buf.InsertIn(0, []rune("x")) }) {
+		t.Skip("read-only buffer")
+	}
+	ref = "x" + ref
+	for i, rCur := range []int64{0, 5, 11, 1, 30} {
+		text := []rune("🙂中" + fmt.Sprintf("%v", i))
+		if x := buf.InsertIn(rCur, text); x != buf {
+			t.Fatalf("expected same reference")
+		}
+		rs := []rune(ref)
+		ref = string(rs[:rCur]) + string(text) + string(rs[rCur:])
+		if x := string(buf.Runes()); x != ref {
+			t.Fatalf("InsertIn(%v) expected %q, got %q", rCur, ref, x)
+		}
+	}
+	l := buf.Length()
+	if x := buf.InsertIn(l, []rune("end")); x != buf {
+		t.Fatalf("expected same reference")
+	} else if x := string(buf.Runes()); x != ref+"end" {
+		t.Fatalf("expected %q, got %q", ref+"end", x)
+	}
+	expectPanic(t, buffer.ErrorIndexOutofbound, func() {
+		buf.InsertIn(-1, []rune("x"))
+	})
+	expectPanic(t, buffer.ErrorIndexOutofbound, func() {
+		buf.InsertIn(buf.Length()+1, []rune("x"))
+	})
+}
+
+func testDeleteIn(t *testing.T, factory Factory) {
+	buf, ref := factory([]byte(Text)), []rune(Text)
+	if readonly(t, func() { buf.DeleteIn(0, 1) }) {
+		t.Skip("read-only buffer")
+	}
+	ref = ref[1:]
+	for _, rCur := range []int64{0, 5, 11, 1, 15} {
+		if x := buf.DeleteIn(rCur, 3); x != buf {
+			t.Fatalf("expected same reference")
+		}
+		ref = append(append([]rune{}, ref[:rCur]...), ref[rCur+3:]...)
+		if x, y := string(ref), string(buf.Runes()); x != y {
+			t.Fatalf("DeleteIn(%v) expected %q, got %q", rCur, x, y)
+		}
+	}
+	l := buf.Length()
+	expectPanic(t, buffer.ErrorIndexOutofbound, func() {
+		buf.DeleteIn(-1, 1)
+	})
+	expectPanic(t, buffer.ErrorIndexOutofbound, func() {
+		buf.DeleteIn(l-1, 2)
+	})
+	if x := buf.DeleteIn(0, l); x != buf {
+		t.Fatalf("expected same reference")
+	} else if x := buf.Length(); x != 0 {
+		t.Fatalf("expected empty buffer, got %v", x)
+	}
+}
+
+// testPersistent check that in place edits on a derived buffer
+// leave the original buffer as is.
+func testPersistent(t *testing.T, factory Factory) {
+	buf := factory([]byte(Text))
+	l := buf.Length()
+	derived := buf.Insert(3, []rune("X"))
+	if readonly(t, func() { derived.InsertIn(0, []rune("Q")) }) {
+		t.Skip("read-only buffer")
+	}
+	derived.DeleteIn(20, 5)
+	if x := buf.Bytes(); string(x) != Text {
+		t.Fatalf("expected %q, got %q", Text, x)
+	} else if x := buf.Length(); x != l {
+		t.Fatalf("expected %v, got %v", l, x)
+	}
+	ref := []rune(Text)
+	ref = append(append([]rune("Q"), ref[:3]...), append([]rune("X"), ref[3:]...)...)
+	ref = append(ref[:20:20], ref[25:]...)
+	if x := string(derived.Runes()); x != string(ref) {
+		t.Fatalf("expected %q, got %q", string(ref), x)
+	}
+	derived = buf.Delete(2, 4)
+	derived.InsertIn(l-4, []rune("end"))
+	derived.DeleteIn(0, 10)
+	if x := buf.Bytes(); string(x) != Text {
+		t.Fatalf("expected %q, got %q", Text, x)
+	} else if x := buf.Length(); x != l {
+		t.Fatalf("expected %v, got %v", l, x)
+	}
+}
+
+// testDerived check that buffers derived by persistent APIs and
+// their receiver do not see each other's in place edits.
+func testDerived(t *testing.T, factory Factory) {
+	// no-op edits and splits at either end return the receiver
+	// itself, refer testInsert, testDelete and testSplit.
+	derive := func(buf buffer.Buffer) []buffer.Buffer {
+		l := buf.Length()
+		left, right := buf.Split(5)
+		return []buffer.Buffer{
+			buf.Insert(l, []rune("!!")), buf.Insert(0, []rune("!!")),
+			buf.Insert(3, []rune{}), buf.Delete(l-2, 2), buf.Delete(0, 3),
+			buf.Slice(0, l), buf.Slice(0, l-3), buf.Slice(3, l),
+			left, right,
+			buf.Concat(factory([]byte("!!"))), factory([]byte("!!")).Concat(buf),
+		}
+	}
+	refs := func(bufs []buffer.Buffer) []string {
+		ss := make([]string, 0, len(bufs))
+		for _, buf := range bufs {
+			ss = append(ss, string(buf.Runes()))
+		}
+		return ss
+	}
+
+	buf := factory([]byte(Text))
+	derived := derive(buf)
+	ref := refs(derived)
+	if readonly(t, func() { buf.InsertIn(0, []rune("XYZ")) }) {
+		t.Skip("read-only buffer")
+	}
+	buf.DeleteIn(0, 6)
+	buf.InsertIn(buf.Length(), []rune("XYZ"))
+	if x := refs(derived); !reflect.DeepEqual(x, ref) {
+		t.Fatalf("expected %q, got %q", ref, x)
+	}
+
+	buf = factory([]byte(Text))
+	derived = derive(buf)
+	for _, x := range derived {
+		x.InsertIn(0, []rune("XYZ"))
+		x.DeleteIn(x.Length()-3, 3)
+	}
+	if x := string(buf.Runes()); x != Text {
+		t.Fatalf("expected %q, got %q", Text, x)
+	}
+}
+
+func testStream(t *testing.T, factory Factory) {
+	buf, ref := factory([]byte(Text)), []rune(Text)
+	l := int64(len(ref))
+	for rCur := int64(0); rCur <= l; rCur++ {
+		if x, y := string(ref[rCur:]), string(readAll(t, buf.StreamFrom(rCur))); x != y {
+			t.Fatalf("StreamFrom(%v) expected %q, got %q", rCur, x, y)
+		}
+		for _, count := range []int64{0, 1, 5, l} {
+			end := rCur + count
+			if end > l {
+				end = l
+			}
+			runes := readAll(t, buf.StreamCount(rCur, count))
+			if x, y := string(ref[rCur:end]), string(runes); x != y {
+				t.Fatalf("StreamCount(%v, %v) expected %q, got %q", rCur, count, x, y)
+			}
+		}
+	}
+	if x := buf.StreamFrom(-1); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	} else if x := buf.StreamFrom(l + 1); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	} else if x := buf.StreamCount(l+1, 1); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	}
+	// closed reader returns io.EOF.
+	reader := buf.StreamFrom(0)
+	reader.ReadRune()
+	reader.Close()
+	if _, _, err := reader.ReadRune(); err != io.EOF {
+		t.Fatalf("expected io.EOF after close, got %v", err)
+	}
+}
+
+func testBackStream(t *testing.T, factory Factory) {
+	buf, ref := factory([]byte(Text)), []rune(Text)
+	l := int64(len(ref))
+	for rCur := int64(0); rCur <= l; rCur++ {
+		x := string(reverse(ref[:rCur]))
+		if y := string(readAll(t, buf.BackStreamFrom(rCur))); x != y {
+			t.Fatalf("BackStreamFrom(%v) expected %q, got %q", rCur, x, y)
+		}
+		for _, count := range []int64{0, 1, 5, l} {
+			start := rCur - count
+			if start < 0 {
+				start = 0
+			}
+			x := string(reverse(ref[start:rCur]))
+			runes := readAll(t, buf.BackStreamCount(rCur, count))
+			if y := string(runes); x != y {
+				t.Fatalf("BackStreamCount(%v, %v) expected %q, got %q", rCur, count, x, y)
+			}
+		}
+	}
+	if x := buf.BackStreamFrom(-1); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	} else if x := buf.BackStreamFrom(l + 1); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	} else if x := buf.BackStreamCount(l+1, 1); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	}
+	reader := buf.BackStreamFrom(l)
+	reader.ReadRune()
+	reader.Close()
+	if _, _, err := reader.ReadRune(); err != io.EOF {
+		t.Fatalf("expected io.EOF after close, got %v", err)
+	}
+}
+
+func testSymmetry(t *testing.T, factory Factory) {
+	buf := factory([]byte(Text))
+	l := buf.Length()
+	for rCur := int64(0); rCur <= l; rCur++ {
+		fwd := readAll(t, buf.StreamFrom(rCur))
+		bwd := readAll(t, buf.BackStreamFrom(rCur))
+		all := string(reverse(bwd)) + string(fwd)
+		if all != Text {
+			t.Fatalf("at %v expected %q, got %q", rCur, Text, all)
+		}
+	}
+	// forward and backward over an edited buffer.
+	buf = buf.Insert(4, []rune("🙂🙂")).Delete(10, 3)
+	fwd := readAll(t, buf.StreamFrom(0))
+	bwd := readAll(t, buf.BackStreamFrom(buf.Length()))
+	if x, y := string(fwd), string(reverse(bwd)); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	} else if x, y := string(buf.Runes()), string(fwd); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	}
+}
+
+func testStats(t *testing.T, factory Factory) {
+	stats, err := factory([]byte(Text)).Stats()
+	if err != nil {
+		t.Fatal(err)
+	} else if stats == nil {
+		t.Fatalf("expected statistics")
+	}
+}
+
+// testInvalid check that invalid utf8 is read and saved as is.
+func testInvalid(t *testing.T, factory Factory) {
+	buf, ref := factory(Binary), []rune{}
+	for bs := Binary; len(bs) > 0; {
+		r, size := utf8.DecodeRune(bs)
+		if r == utf8.RuneError && size == 1 {
+			r = buffer.RawRune(bs[0])
+		}
+		ref, bs = append(ref, r), bs[size:]
+	}
+	l := int64(len(ref))
+	if x := buf.Bytes(); !bytes.Equal(x, Binary) {
+		t.Fatalf("expected %q, got %q", Binary, x)
+	} else if x := buf.Size(); x != int64(len(Binary)) {
+		t.Fatalf("expected %v, got %v", len(Binary), x)
+	} else if x := buf.Length(); x != l {
+		t.Fatalf("expected %v, got %v", l, x)
+	} else if x := buf.Runes(); !reflect.DeepEqual(x, ref) {
+		t.Fatalf("expected %q, got %q", ref, x)
+	}
+	if x := readAll(t, buf.StreamFrom(0)); !reflect.DeepEqual(x, ref) {
+		t.Fatalf("expected %q, got %q", ref, x)
+	} else if x := readAll(t, buf.BackStreamFrom(l)); !reflect.DeepEqual(reverse(x), ref) {
+		t.Fatalf("expected %q, got %q", ref, reverse(x))
+	}
+	for rCur := int64(0); rCur <= l; rCur++ {
+		left, right := buf.Slice(0, rCur), buf.Slice(rCur, l)
+		bs := append(left.Bytes(), right.Bytes()...)
+		if !bytes.Equal(bs, Binary) {
+			t.Fatalf("at %v expected %q, got %q", rCur, Binary, bs)
+		}
+	}
+	// edits preserve the invalid bytes around them.
+	x := buf.Insert(4, []rune("é"))
+	if bs := x.Bytes(); string(bs) != string(Binary[:4])+"é"+string(Binary[4:]) {
+		t.Fatalf("unexpected %q", bs)
+	}
+	x = buf.Delete(0, 3)
+	if bs := x.Bytes(); !bytes.Equal(bs, Binary[3:]) {
+		t.Fatalf("expected %q, got %q", Binary[3:], bs)
+	}
+	x = buf.Insert(l, []rune{buffer.RawRune(0xfe)})
+	if bs := x.Bytes(); string(bs) != string(Binary)+"\xfe" {
+		t.Fatalf("unexpected %q", bs)
+	} else if x.Length() != l+1 {
+		t.Fatalf("expected %v, got %v", l+1, x.Length())
+	}
+}
+
+// testRawSeams check that invalid bytes brought together by edits
+// do not combine into a valid rune.
+func testRawSeams(t *testing.T, factory Factory) {
+	raw := func(bs string) []rune {
+		rs := []rune{}
+		for _, b := range []byte(bs) {
+			rs = append(rs, buffer.RawRune(b))
+		}
+		return rs
+	}
+	ref := append(append([]rune("a"), raw("\xe4\xb8\xad")...), 'b')
+	check := func(buf buffer.Buffer) {
+		t.Helper()
+		l := int64(len(ref))
+		if x := buf.Length(); x != l {
+			t.Fatalf("expected %v, got %v", l, x)
+		} else if x := buf.Runes(); !reflect.DeepEqual(x, ref) {
+			t.Fatalf("expected %q, got %q", ref, x)
+		} else if x := readAll(t, buf.StreamFrom(0)); !reflect.DeepEqual(x, ref) {
+			t.Fatalf("expected %q, got %q", ref, x)
+		} else if x := readAll(t, buf.BackStreamFrom(l)); !reflect.DeepEqual(reverse(x), ref) {
+			t.Fatalf("expected %q, got %q", ref, reverse(x))
+		} else if x := buf.Bytes(); string(x) != "a\xe4\xb8\xadb" {
+			t.Fatalf("unexpected %q", x)
+		}
+	}
+
+	check(factory([]byte("a\xe4\xb8X\xadb")).Delete(3, 1))
+	check(factory([]byte("ab")).Insert(1, raw("\xe4\xb8\xad")))
+	check(factory([]byte("a\xe4\xb8b")).Insert(3, raw("\xad")))
+	check(factory([]byte("a\xe4\xadb")).Insert(2, raw("\xb8")))
+	check(factory([]byte("a\xe4\xb8")).Concat(factory([]byte("\xadb"))))
+	buf := factory([]byte("a\xe4\xb8X\xadb"))
+	if readonly(t, func() { buf.DeleteIn(3, 1) }) {
+		t.Skip("read-only buffer")
+	}
+	check(buf)
+	buf = factory([]byte("a\xe4b"))
+	buf.InsertIn(2, raw("\xb8\xad"))
+	check(buf)
+}
+
+// readAll runes from reader, checking the reported size of each
+// rune, until io.EOF.
+func readAll(t *testing.T, reader buffer.RuneReader) []rune {
+	if reader == nil {
+		t.Fatalf("unexpected nil reader")
+	}
+	runes := []rune{}
+	for {
+		r, size, err := reader.ReadRune()
+		if err == io.EOF {
+			return runes
+		} else if err != nil {
+			t.Fatal(err)
+		} else if x := runeSize(r); x != size {
+			t.Fatalf("rune %q expected size %v, got %v", r, x, size)
+		}
+		runes = append(runes, r)
+	}
+}
+
+// readonly return true if fn panics with buffer.ErrorReadonlyBuffer.
+func readonly(t *testing.T, fn func()) (ok bool) {
+	defer func() {
+		if r := recover(); r == buffer.ErrorReadonlyBuffer {
+			ok = true
+		} else if r != nil {
+			panic(r)
+		}
+	}()
+	fn()
+	return false
+}
+
+func expectPanic(t *testing.T, expected error, fn func()) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != expected {
+			t.Fatalf("expected panic %v, got %v", expected, r)
+		}
+	}()
+	fn()
+}
+
+// runeSize return the encoded size of r, raw-runes are encoded
+// as a single byte.
+func runeSize(r rune) int {
+	if _, ok := buffer.RawByte(r); ok {
+		return 1
+	}
+	return utf8.RuneLen(r)
+}
+
+func reverse(runes []rune) []rune {
+	reversed := make([]rune, len(runes))
+	for i, r := range runes {
+		reversed[len(runes)-1-i] = r
+	}
+	return reversed
+}
@@ -0,0 +1,351 @@
+package buffer
+
+import "sort"
+import "time"
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// changeTree hold the state shared by all the changes in a
+// change-tree, every change is numbered in the order it was made,
+// starting from 0 for the root.
+type changeTree struct {
+	seq     int64              // sequence no. of the latest change.
+	changes []*EditBuffer      // changes ordered by sequence no.
+	marks   map[string]*Anchor // named marks.
+	anchors map[*Anchor]bool   // anchors to move when pruning.
+	cached  []*EditBuffer      // changes holding a rebuilt buffer.
+	txns    []transaction      // open transactions, innermost last.
+	pause   time.Duration      // group typed runes within pause.
+	undodir string             // directory to save history in.
+	// retention, refer EditBuffer.SetRetention.
+	maxChanges int64
+	maxBytes   int64
+	maxAge     time.Duration
+	bytes      int64 // bytes held by changes, other than root.
+	discarded  int64 // no. of changes pruned so far.
+	freed      int64 // bytes freed by pruning so far.
+}
+
+// transaction started at change, when seq was the latest change.
+type transaction struct {
+	start *EditBuffer
+	seq   int64
+}
+
+// changeCheckpoint is the maximum no. of changes stored as edits
+// between two checkpoints, which bound the work to rebuild a buffer.
+const changeCheckpoint = 256
+
+// changeCacheSize is the no. of changes, other than checkpoints,
+// that can hold their buffer at any time.
+const changeCacheSize = 16
+
+func newChangeTree(root *EditBuffer) *changeTree {
+	root.seq, root.time = 0, time.Now()
+	return &changeTree{
+		seq:     0,
+		changes: []*EditBuffer{root},
+		marks:   make(map[string]*Anchor),
+		anchors: make(map[*Anchor]bool),
+		bytes:   root.memory(),
+	}
+}
+
+// add change as the latest change in tree.
+func (tree *changeTree) add(change *EditBuffer) {
+	tree.seq++
+	change.tree, change.seq, change.time = tree, tree.seq, time.Now()
+	tree.changes = append(tree.changes, change)
+}
+
+// change return the change numbered seq, or the nearest change
+// before it if it was pruned, or after it if later is true. Return
+// value is clamped to the oldest and latest change.
+func (tree *changeTree) change(seq int64, later bool) *EditBuffer {
+	n := len(tree.changes)
+	i := sort.Search(n, func(i int) bool { return tree.changes[i].seq >= seq })
+	switch {
+	case i < n && tree.changes[i].seq == seq:
+		return tree.changes[i]
+	case later && i < n:
+		return tree.changes[i]
+	case later:
+		return tree.changes[n-1]
+	case i == 0:
+		return tree.changes[0]
+	}
+	return tree.changes[i-1]
+}
+
+// changeAt return the latest change made at or before t, the root
+// if every change was made after t.
+func (tree *changeTree) changeAt(t time.Time) *EditBuffer {
+	i := sort.Search(len(tree.changes), func(i int) bool {
+		return tree.changes[i].time.After(t)
+	})
+	if i == 0 {
+		return tree.changes[0]
+	}
+	return tree.changes[i-1]
+}
+
+// cache the buffer held by change, dropping buffers held by the
+// oldest cached changes.
+func (tree *changeTree) cache(change *EditBuffer) {
+	tree.cached = append(tree.cached, change)
+	for len(tree.cached) > changeCacheSize {
+		if x := tree.cached[0]; !x.checkpoint {
+			x.buffer = nil
+		}
+		tree.cached = tree.cached[1:]
+	}
+}
+
+func (tree *changeTree) stats() Statistics {
+	var checkpoints, cached, deltas, deltaRunes, bufferRunes int64
+	for _, change := range tree.changes {
+		deltas += int64(len(change.edits))
+		deltaRunes += deltaRunesIn(change.edits)
+		if change.checkpoint {
+			checkpoints++
+		} else if change.buffer != nil {
+			cached++
+		}
+		if change.buffer != nil {
+			bufferRunes += change.buffer.Length()
+		}
+	}
+	return Statistics{
+		"changes":     int64(len(tree.changes)),       // no. of changes in tree
+		"checkpoints": checkpoints,                    // no. of changes holding a buffer
+		"cached":      cached,                         // no. of rebuilt buffers held
+		"deltas":      deltas,                         // no. of edits stored
+		"deltaRunes":  deltaRunes,                     // no. of runes in edits
+		"bufferRunes": bufferRunes,                    // no. of runes in held buffers
+		"memory":      (deltaRunes + bufferRunes) * 4, // bytes held
+		"bytes":       tree.bytes,                     // bytes counted for retention
+		"discarded":   tree.discarded,                 // no. of changes pruned
+		"freed":       tree.freed,                     // bytes freed by pruning
+	}
+}
+
+//--------
+// pruning
+//--------
+
+// prune changes beyond the retention limits, without breaking the
+// path from root to cur. Branches off the path are dropped first,
+// starting from the least recently changed, and then the oldest
+// changes along the path are squashed into a new root. Return the
+// discarded changes, branches and bytes.
+func (tree *changeTree) prune(cur *EditBuffer, now time.Time) Statistics {
+	stats := newPruneStats()
+	over := func() bool {
+		if tree.maxChanges > 0 && int64(len(tree.changes)) > tree.maxChanges {
+			return true
+		}
+		return tree.maxBytes > 0 && tree.bytes > tree.maxBytes
+	}
+	expired := func(t time.Time) bool {
+		return tree.maxAge > 0 && t.Before(now.Add(-tree.maxAge))
+	}
+	if !over() && !expired(tree.changes[0].time) { // root is the oldest.
+		return stats
+	}
+
+	onpath := make(map[*EditBuffer]bool)
+	for x := cur; x != nil; x = x.parent {
+		onpath[x] = true
+	}
+	branches := make([][2]*EditBuffer, 0) // {fork, branch}
+	for x := cur; x != nil; x = x.parent {
+		for _, child := range x.children {
+			if !onpath[child] {
+				branches = append(branches, [2]*EditBuffer{x, child})
+			}
+		}
+	}
+	latest := make(map[*EditBuffer]*EditBuffer)
+	for _, b := range branches {
+		latest[b[1]] = b[1].latest()
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		return latest[branches[i][1]].seq < latest[branches[j][1]].seq
+	})
+	for _, b := range branches {
+		if !over() && !expired(latest[b[1]].time) {
+			break
+		}
+		tree.drop(b[0], b[1], onpath, stats)
+	}
+
+	for root := tree.changes[0]; root != cur; root = tree.changes[0] {
+		var next *EditBuffer
+		for _, child := range root.children {
+			if onpath[child] {
+				next = child
+			}
+		}
+		if next == nil || (!over() && !expired(next.time)) {
+			break
+		} else if tree.inTransaction(root) {
+			break
+		}
+		tree.squash(root, next, onpath, stats)
+	}
+	tree.discarded += stats["changes"].(int64)
+	tree.freed += stats["bytes"].(int64)
+	return stats
+}
+
+// drop branch forking from change fork.
+func (tree *changeTree) drop(
+	fork, branch *EditBuffer, onpath map[*EditBuffer]bool, stats Statistics) {
+
+	removed := make(map[*EditBuffer]bool)
+	branch.walk(func(x *EditBuffer) { removed[x] = true })
+	tree.repin(removed, fork)
+
+	children := fork.children[:0]
+	for _, child := range fork.children {
+		if child != branch {
+			children = append(children, child)
+		}
+	}
+	fork.children, fork.branch = children, len(children)-1
+	for i, child := range children {
+		if onpath[child] {
+			fork.branch = i
+		}
+	}
+	if fork.branch < 0 {
+		fork.branch = 0
+	}
+	tree.remove(removed, stats)
+	stats["branches"] = stats["branches"].(int64) + 1
+}
+
+// squash root into next, its child along the current path, which
+// becomes the new root.
+func (tree *changeTree) squash(
+	root, next *EditBuffer, onpath map[*EditBuffer]bool, stats Statistics) {
+
+	for _, child := range append([]*EditBuffer{}, root.children...) {
+		if child != next {
+			tree.drop(root, child, onpath, stats)
+		}
+	}
+	tree.repin(map[*EditBuffer]bool{root: true}, next)
+
+	buffer := next.text()
+	tree.bytes -= next.memory()
+	next.buffer, next.parent, next.edits, next.undo = buffer, nil, nil, nil
+	next.checkpoint, next.chain, next.churn = true, 0, 0
+	tree.bytes += next.memory()
+	tree.remove(map[*EditBuffer]bool{root: true}, stats)
+	stats["squashed"] = stats["squashed"].(int64) + 1
+}
+
+// repin anchors set in removed changes to change.
+func (tree *changeTree) repin(removed map[*EditBuffer]bool, change *EditBuffer) {
+	for anchor := range tree.anchors {
+		if removed[anchor.change] {
+			anchor.reset(change, anchor.resolve(change))
+		}
+	}
+}
+
+// remove changes from tree, removed changes are detached from the
+// tree and any of their handles held by the application can no
+// longer be changed, refer ErrorPrunedChange.
+func (tree *changeTree) remove(removed map[*EditBuffer]bool, stats Statistics) {
+	changes := tree.changes[:0]
+	for _, change := range tree.changes {
+		if !removed[change] {
+			changes = append(changes, change)
+			continue
+		}
+		n := change.memory()
+		change.parent, change.children, change.branch = nil, nil, 0
+		change.pruned = true
+		tree.bytes -= n
+		stats["changes"] = stats["changes"].(int64) + 1
+		stats["bytes"] = stats["bytes"].(int64) + n
+	}
+	tree.changes = changes
+	cached := tree.cached[:0]
+	for _, change := range tree.cached {
+		if !removed[change] {
+			cached = append(cached, change)
+		}
+	}
+	tree.cached = cached
+	for anchor := range tree.anchors {
+		for change := range anchor.resolved {
+			if removed[change] {
+				delete(anchor.resolved, change)
+			}
+		}
+	}
+}
+
+func newPruneStats() Statistics {
+	return Statistics{
+		"changes":  int64(0), // no. of changes discarded
+		"branches": int64(0), // no. of branches dropped
+		"squashed": int64(0), // no. of changes squashed into root
+		"bytes":    int64(0), // bytes freed
+	}
+}
+
+//--------
+// merging
+//--------
+
+// merge changes along the path from start to cur into a single
+// change on top of start, other branches from start made after
+// seq are dropped as well. Return the merged change.
+func (tree *changeTree) merge(start, cur *EditBuffer, seq int64) *EditBuffer {
+	path := make([]*EditBuffer, 0)
+	for x := cur; x != start; x = x.parent {
+		path = append(path, x)
+	}
+	lists := make([][]textEdit, 0, len(path))
+	for i := len(path) - 1; i >= 0; i-- {
+		lists = append(lists, path[i].edits)
+	}
+	buffer := start.text()
+	edits := composeEdits(buffer.Length(), lists...)
+	merged := start.newChange(cur.dot, cur.text(), recordEdits(buffer, edits))
+	merged.sels = cur.sels
+	merged.filename, merged.encoding = cur.filename, cur.encoding
+	merged.newline, merged.mixed = cur.newline, cur.mixed
+
+	removed := make(map[*EditBuffer]bool)
+	for _, child := range start.children {
+		if child == path[len(path)-1] || (child != merged && child.seq > seq) {
+			child.walk(func(x *EditBuffer) { removed[x] = true })
+		}
+	}
+	tree.repin(removed, merged)
+	children := start.children[:0]
+	for _, child := range start.children {
+		if !removed[child] {
+			children = append(children, child)
+		}
+	}
+	start.children, start.branch = children, len(children)-1
+	tree.remove(removed, newPruneStats())
+	return merged
+}
+
+// inTransaction return true if a transaction started at change.
+func (tree *changeTree) inTransaction(change *EditBuffer) bool {
+	for _, txn := range tree.txns {
+		if txn.start == change {
+			return true
+		}
+	}
+	return false
+}
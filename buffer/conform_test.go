@@ -0,0 +1,67 @@
+package buffer_test
+
+import "os"
+import "io/ioutil"
+import "testing"
+
+import "github.com/prataprc/v/buffer"
+import "github.com/prataprc/v/buffer/buffertest"
+
+func TestConformLinear(t *testing.T) {
+	buffertest.Run(t, func(bs []byte) buffer.Buffer {
+		return buffer.NewLinearBuffer(bs)
+	})
+}
+
+func TestConformGap(t *testing.T) {
+	buffertest.Run(t, func(bs []byte) buffer.Buffer {
+		return buffer.NewGapBuffer(bs, 4)
+	})
+}
+
+func TestConformPiece(t *testing.T) {
+	buffertest.Run(t, func(bs []byte) buffer.Buffer {
+		return buffer.NewPieceTable(bs)
+	})
+}
+
+func TestConformRope(t *testing.T) {
+	buffertest.Run(t, func(bs []byte) buffer.Buffer {
+		return buffer.NewRopebuffer(bs, 8)
+	})
+}
+
+func TestConformMmap(t *testing.T) {
+	buffertest.Run(t, func(bs []byte) buffer.Buffer {
+		filename := conformFile(t, bs)
+		mb, err := buffer.NewMmapBuffer(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { mb.Close() })
+		return mb
+	})
+}
+
+func TestConformPaged(t *testing.T) {
+	buffertest.Run(t, func(bs []byte) buffer.Buffer {
+		filename := conformFile(t, bs)
+		pb, err := buffer.NewPagedBuffer(filename, 16, 64, true /*spill*/)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { pb.Close() })
+		return pb
+	})
+}
+
+func conformFile(t *testing.T, bs []byte) string {
+	fd, err := ioutil.TempFile("", "conform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Write(bs)
+	fd.Close()
+	t.Cleanup(func() { os.Remove(fd.Name()) })
+	return fd.Name()
+}
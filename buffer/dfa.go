@@ -0,0 +1,303 @@
+package buffer
+
+import "regexp/syntax"
+import "encoding/binary"
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// dfa is a deterministic automaton built lazily from the Thompson
+// NFA of a compiled program, each state is the list of NFA threads,
+// in priority order, waiting at a position. Threads are grouped by
+// the position they started from, the only thing tracked at run
+// time, so that states are shared by matches at different offsets.
+// Cached states are flushed when they exceed dfaMaxStates.
+type dfa struct {
+	prog    *syntax.Prog
+	longest bool
+	flags   syntax.EmptyOp // empty-width assertions used by prog.
+	nascii  int            // ascii transitions cached per state.
+	fidx    [64]int        // flag index for ascii transitions.
+	begin   *dfaState      // pseudo state, before search begins.
+	states  map[string]*dfaState
+	visited []bool
+	pcs     []uint32 // visited pcs, to clear visited.
+	key     []byte
+	// run time
+	starts, nexts []int64
+}
+
+type dfaState struct {
+	threads []dfaThread
+	matched bool        // a match was found, don't start new threads.
+	bound   int         // longest mode, drop threads in groups >= bound.
+	ascii   []*dfaTrans // transitions over ascii, refer dfa.asciiIndex.
+	next    map[uint64]*dfaTrans
+}
+
+type dfaThread struct {
+	pc    uint32
+	group int
+}
+
+// dfaTrans move to state over a rune, groups map every group in
+// state to the group it continues from the previous state, -1 for
+// threads started at the new position. If match is >= 0, it is the
+// group in previous state that matched before the rune.
+type dfaTrans struct {
+	state  *dfaState
+	groups []int
+	match  int
+}
+
+const dfaMaxStates = 4096
+
+func newDFA(prog *syntax.Prog, longest bool) *dfa {
+	d := &dfa{
+		prog:    prog,
+		longest: longest,
+		visited: make([]bool, len(prog.Inst)),
+	}
+	for _, inst := range prog.Inst {
+		if inst.Op == syntax.InstEmptyWidth {
+			d.flags |= syntax.EmptyOp(inst.Arg)
+		}
+	}
+	// cache ascii transitions in an array, for upto 4 contexts.
+	nbits := 0
+	for bit := syntax.EmptyOp(1); bit < 64; bit <<= 1 {
+		if d.flags&bit != 0 {
+			for flag := syntax.EmptyOp(0); flag < 64; flag++ {
+				if flag&bit != 0 {
+					d.fidx[flag] |= 1 << uint(nbits)
+				}
+			}
+			nbits++
+		}
+	}
+	if nbits <= 2 {
+		d.nascii = 128 << uint(nbits)
+	}
+	d.reset()
+	return d
+}
+
+// search in for the first match at or after pos, return its
+// {start, end} offsets, nil if there is no match.
+func (d *dfa) search(in *runeInput, pos int64) []int64 {
+	var loc []int64
+
+	r := in.at(pos)
+	tr := d.transition(d.begin, -1, d.context(in.at(pos-1), r))
+	starts := append(d.starts[:0], pos)
+	for state := tr.state; ; {
+		if state.matched && len(state.threads) == 0 {
+			break
+		} else if r < 0 {
+			if g := d.final(state); g >= 0 {
+				loc = []int64{starts[g], pos}
+			}
+			break
+		}
+		r1 := in.at(pos + 1)
+		tr = d.transition(state, r, d.context(r, r1))
+		if tr.match >= 0 {
+			loc = []int64{starts[tr.match], pos}
+		}
+		pos++
+		nexts := d.nexts[:0]
+		for _, g := range tr.groups {
+			if g < 0 {
+				nexts = append(nexts, pos)
+			} else {
+				nexts = append(nexts, starts[g])
+			}
+		}
+		starts, d.nexts = nexts, starts
+		state, r = tr.state, r1
+
+		keep := pos // groups are ordered by start.
+		if len(starts) > 0 && starts[0] < keep {
+			keep = starts[0]
+		}
+		if loc != nil && loc[1] < keep {
+			keep = loc[1]
+		}
+		in.trim(keep - 1)
+	}
+	d.starts = starts
+	return loc
+}
+
+// transition return the next state from state over rune r, where
+// flag is the context after r.
+func (d *dfa) transition(
+	state *dfaState, r rune, flag syntax.EmptyOp) *dfaTrans {
+
+	idx := d.asciiIndex(r, flag)
+	if idx >= 0 {
+		if tr := state.ascii[idx]; tr != nil {
+			return tr
+		}
+	} else if tr, ok := state.next[dfaInput(r, flag)]; ok {
+		return tr
+	}
+	if len(d.states) >= dfaMaxStates {
+		d.reset()
+		state.ascii = make([]*dfaTrans, d.nascii)
+		state.next = make(map[uint64]*dfaTrans)
+	}
+
+	threads := make([]dfaThread, 0, len(state.threads))
+	matched, bound, match := state.matched, state.bound, -1
+	for _, t := range state.threads {
+		if d.longest && matched && t.group >= bound {
+			continue
+		}
+		inst := &d.prog.Inst[t.pc]
+		if inst.Op == syntax.InstMatch {
+			if match < 0 {
+				match, bound = t.group, t.group+1
+			}
+			matched = true
+			if !d.longest { // cut off lower priority threads.
+				break
+			}
+		} else if r >= 0 && matchRune(inst, r) {
+			threads = d.closure(threads, inst.Out, flag, t.group)
+		}
+	}
+	if !matched {
+		threads = d.closure(threads, uint32(d.prog.Start), flag, -1)
+	}
+	d.clearVisited()
+
+	// renumber groups in the order of their first thread.
+	groups := make([]int, 0, 4)
+	for i, t := range threads {
+		g := len(groups) - 1
+		if g < 0 || groups[g] != t.group {
+			groups, g = append(groups, t.group), g+1
+		}
+		threads[i].group = g
+	}
+	newBound := 0
+	if d.longest && matched {
+		for _, g := range groups {
+			if g >= 0 && g < bound {
+				newBound++
+			}
+		}
+	}
+
+	tr := &dfaTrans{d.lookup(threads, matched, newBound), groups, match}
+	if idx >= 0 {
+		state.ascii[idx] = tr
+	} else {
+		state.next[dfaInput(r, flag)] = tr
+	}
+	return tr
+}
+
+// final return the group that matched at the end of input, -1 if
+// there is none.
+func (d *dfa) final(state *dfaState) int {
+	for _, t := range state.threads {
+		if d.longest && state.matched && t.group >= state.bound {
+			continue
+		} else if d.prog.Inst[t.pc].Op == syntax.InstMatch {
+			return t.group
+		}
+	}
+	return -1
+}
+
+// closure add pc to threads, following empty transitions.
+func (d *dfa) closure(
+	threads []dfaThread, pc uint32, flag syntax.EmptyOp,
+	group int) []dfaThread {
+
+	if pc == 0 || d.visited[pc] {
+		return threads
+	}
+	d.visited[pc], d.pcs = true, append(d.pcs, pc)
+	switch inst := &d.prog.Inst[pc]; inst.Op {
+	case syntax.InstAlt, syntax.InstAltMatch:
+		threads = d.closure(threads, inst.Out, flag, group)
+		threads = d.closure(threads, inst.Arg, flag, group)
+	case syntax.InstEmptyWidth:
+		if syntax.EmptyOp(inst.Arg)&^flag == 0 {
+			threads = d.closure(threads, inst.Out, flag, group)
+		}
+	case syntax.InstNop, syntax.InstCapture:
+		threads = d.closure(threads, inst.Out, flag, group)
+	case syntax.InstMatch, syntax.InstRune, syntax.InstRune1,
+		syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+		threads = append(threads, dfaThread{pc: pc, group: group})
+	}
+	return threads
+}
+
+// lookup return the cached state for threads, creating one if
+// missing.
+func (d *dfa) lookup(threads []dfaThread, matched bool, bound int) *dfaState {
+	key := append(d.key[:0], 0)
+	if matched {
+		key = binary.AppendUvarint(append(key[:0], 1), uint64(bound))
+	}
+	for _, t := range threads {
+		key = binary.AppendUvarint(key, uint64(t.pc))
+		key = binary.AppendUvarint(key, uint64(t.group))
+	}
+	d.key = key
+	if state, ok := d.states[string(key)]; ok {
+		return state
+	}
+	state := &dfaState{
+		threads: threads,
+		matched: matched,
+		bound:   bound,
+		ascii:   make([]*dfaTrans, d.nascii),
+		next:    make(map[uint64]*dfaTrans),
+	}
+	d.states[string(key)] = state
+	return state
+}
+
+// context return the empty-width assertions, used by prog, that
+// hold between r1 and r2.
+func (d *dfa) context(r1, r2 rune) syntax.EmptyOp {
+	if d.flags == 0 {
+		return 0
+	}
+	return syntax.EmptyOpContext(r1, r2) & d.flags
+}
+
+func (d *dfa) clearVisited() {
+	for _, pc := range d.pcs {
+		d.visited[pc] = false
+	}
+	d.pcs = d.pcs[:0]
+}
+
+func (d *dfa) reset() {
+	d.states = make(map[string]*dfaState)
+	d.begin = &dfaState{
+		ascii: make([]*dfaTrans, d.nascii),
+		next:  make(map[uint64]*dfaTrans),
+	}
+}
+
+// asciiIndex return the index into dfaState.ascii for transition
+// over r, -1 if it is not cached there.
+func (d *dfa) asciiIndex(r rune, flag syntax.EmptyOp) int {
+	if r >= 0 && r < 128 && d.nascii > 0 {
+		return d.fidx[flag]<<7 | int(r)
+	}
+	return -1
+}
+
+// dfaInput key transitions by rune r and the context after r.
+func dfaInput(r rune, flag syntax.EmptyOp) uint64 {
+	return uint64(uint32(r))<<8 | uint64(flag)
+}
@@ -1,11 +1,12 @@
-// +build ignore
-
 package buffer
 
-import "regexp"
+import "sort"
+import "math"
+import "reflect"
+import "time"
 import "fmt"
 
-type Finder func() []int
+var _ = fmt.Sprintf("dummy")
 
 // EditBuffer manages a single edit buffer datastructure that
 // implements Buffer interface{}.
@@ -16,20 +17,39 @@ type Finder func() []int
 //
 // Not thread safe.
 type EditBuffer struct {
-	dot    int64  // cursor within the edit buffer
-	buffer Buffer // buffer data-structure
-	ronly  bool   // buffer is read-only
+	dot    int64      // cursor within the edit buffer
+	sels   Selections // selection set, nil for a single cursor at dot
+	buffer Buffer     // buffer data-structure
+	ronly  bool       // buffer is read-only
 	// parent and children are used to manage the change tree.
 	parent   *EditBuffer
 	children []*EditBuffer
+	branch   int         // child followed by RedoChange
+	tree     *changeTree // shared by all the changes in the tree
+	seq      int64       // sequence no. of this change
+	time     time.Time   // when this change was made
+	edits    []textEdit  // edits from parent, recorded
+	undo     []textEdit  // edits back to parent, computed lazily
+	// changes are stored as edits, with buffer rebuilt on demand,
+	// except for checkpoints.
+	checkpoint bool
+	pruned     bool  // change is no longer in the change-tree
+	chain      int64 // no. of changes since the last checkpoint
+	churn      int64 // no. of runes in edits since the last checkpoint
+	// last rune typed in this change, and selections after it.
+	typed   rune
+	typedAt Selections
 	// buffer settings
-	newline string // list of runes that act as newline
+	newline LineEnding // line ending to save the buffer with
+	mixed   bool       // buffer was loaded with mixed line endings
+	tabstop int64      // no. of columns between tab-stops
+	// file backing the buffer
+	filename string
+	encoding *Encoding // encoding to save the file with
 	// buffer context
 	lines Lines
-	atEol bool           // stick cursor to end-of-line
-	atBol bool           // stick cursor to beginning-of-line
-	reNl  *regexp.Regexp // compiled newline
-	reNlR *regexp.Regexp // compiled newline in reverse order
+	atEol bool // stick cursor to end-of-line
+	atBol bool // stick cursor to beginning-of-line
 }
 
 // NewEditBuffer create a new read-write buffer.
@@ -40,49 +60,250 @@ func NewEditBuffer(dot int64, buffer Buffer, parent *EditBuffer) *EditBuffer {
 		ronly:    false,
 		parent:   parent,
 		children: make([]*EditBuffer, 0),
+		// a new buffer is always a checkpoint.
+		checkpoint: true,
+	}
+	if parent == nil {
+		ebuf.tree = newChangeTree(ebuf)
+	} else {
+		parent.tree.add(ebuf)
 	}
 	ebuf.Initialize(ebuf)
 	return ebuf
 }
 
-// NewReadOnlyBuffer create a new read-only buffer.
+// NewReadOnlyBuffer create a new read-only buffer on top of buffer,
+// that can be any Buffer including a memory mapped MmapBuffer.
+// Edits made after ForceWrite() create new changes, buffer itself
+// is never modified. To open a file refer NewReadOnlyFile, which
+// maps the file and delegates to this constructor.
 func NewReadOnlyBuffer(dot int64, buffer Buffer) *EditBuffer {
 	ebuf := NewEditBuffer(dot, buffer, nil)
 	ebuf.ronly = true
 	return ebuf
 }
 
-// Initialize EditBuffer.
-func (ebuf *EditBuffer) Initialize(parent *EditBuffer) *EditBuffer {
-	var err error
-
-	if ebuf.newline == "" {
-		ebuf.newline = Newline
+// NewReadOnlyFile create a new read-only buffer by memory mapping
+// filename, refer NewReadOnlyBuffer. The file is neither read nor
+// copied, changes made after ForceWrite() are held in an overlay on
+// top of the mapped file.
+// File is expected in DefaultEncoding, line ending is detected
+// from the first LineEndingSample runes.
+func NewReadOnlyFile(filename string) (*EditBuffer, error) {
+	mb, err := NewMmapBuffer(filename)
+	if err != nil {
+		return nil, err
 	}
-	nl := ebuf.newline
-	if ebuf.reNl, err = regexp.Compile(nl); err != nil {
-		panic("impossible regular expression")
+	ebuf := NewReadOnlyBuffer(0, mb)
+	ebuf.filename = filename
+	ebuf.detectLineEnding(LineEndingSample)
+	return ebuf, nil
+}
+
+// NewPagedFile create a new read-write buffer for filename, that
+// is loaded in chunks on demand within PagedBufferBudget bytes of
+// memory. Changed chunks are spilled to a temporary file. File is
+// expected in DefaultEncoding, line ending is detected from the
+// first LineEndingSample runes. Undo history is not loaded, refer
+// NewEncodedFile.
+func NewPagedFile(filename string) (*EditBuffer, error) {
+	pb, err := NewPagedBuffer(filename, 0, 0, true /*spill*/)
+	if err != nil {
+		return nil, err
 	}
-	ebuf.reNlR, err = regexp.Compile(string(reverseRunes([]rune(nl))))
+	ebuf := NewEditBuffer(0, pb, nil)
+	ebuf.filename = filename
+	ebuf.detectLineEnding(LineEndingSample)
+	return ebuf, nil
+}
+
+// NewEncodedFile create a new read-write buffer by reading filename
+// in encoding, an empty encoding will detect it from the file. The
+// buffer remembers the encoding and the dominant line ending, and
+// saves the file with them, refer MixedLineEndings. Undo history
+// saved with the file is not loaded, to restore it configure the
+// "undodir" setting and call ReadHistory on the returned buffer,
+// before making any change.
+func NewEncodedFile(filename, encoding string) (*EditBuffer, error) {
+	text, enc, err := ReadFileEncoded(filename, encoding)
 	if err != nil {
-		err = fmt.Errorf("impossible regular expression: %v", err)
-		panic(err)
+		return nil, err
+	}
+	ebuf := NewEditBuffer(0, NewRopebuffer(text, RopeBufferCapacity), nil)
+	ebuf.filename, ebuf.encoding = filename, enc
+	ebuf.detectLineEnding(-1)
+	return ebuf, nil
+}
+
+// Initialize EditBuffer.
+func (ebuf *EditBuffer) Initialize(parent *EditBuffer) *EditBuffer {
+	if ebuf.tabstop <= 0 {
+		ebuf.tabstop = DefaultTabstop
+	}
+	if ebuf.encoding == nil {
+		ebuf.encoding, _ = LookupEncoding(DefaultEncoding)
 	}
 	return ebuf
 }
 
-// Configure EditBuffer.
+// Configure EditBuffer, "newline" setting overrides the detected
+// line ending, refer ParseLineEnding. "undodir" setting is the
+// directory to save the change-tree in, refer Save and ReadHistory.
 func (ebuf *EditBuffer) Configure(setts map[string]interface{}) *EditBuffer {
-	ebuf.newline = setts["newline"].(string)
+	if name, ok := setts["newline"].(string); ok && name != "" {
+		if err := ebuf.SetLineEnding(name); err != nil {
+			panic(err)
+		}
+	}
+	if tabstop, ok := setts["tabstop"].(int64); ok {
+		ebuf.tabstop = tabstop
+	}
+	if n, ok := setts["undochanges"].(int64); ok {
+		ebuf.tree.maxChanges = n
+	}
+	if n, ok := setts["undobytes"].(int64); ok {
+		ebuf.tree.maxBytes = n
+	}
+	if age, ok := setts["undoage"].(string); ok && age != "" {
+		d, err := time.ParseDuration(age)
+		if err != nil {
+			panic(err)
+		}
+		ebuf.tree.maxAge = d
+	}
+	if dir, ok := setts["undodir"].(string); ok {
+		ebuf.tree.undodir = dir
+	}
+	if pause, ok := setts["undogroup"].(string); ok && pause != "" {
+		d, err := time.ParseDuration(pause)
+		if err != nil {
+			panic(err)
+		}
+		ebuf.tree.pause = d
+	}
 	return ebuf
 }
 
 // GetBuffer return buffer and cursor position.
 func (ebuf *EditBuffer) GetBuffer() (dot int64, buffer Buffer) {
-	dot, buffer = ebuf.dot, ebuf.buffer
+	dot, buffer = ebuf.dot, ebuf.text()
 	return
 }
 
+// Encoding return the encoding used to save the buffer.
+func (ebuf *EditBuffer) Encoding() *Encoding {
+	return ebuf.encoding
+}
+
+// SetEncoding change the encoding used to save the buffer, name
+// can have a "-bom" suffix to save with a byte order mark.
+func (ebuf *EditBuffer) SetEncoding(name string) error {
+	enc, err := LookupEncoding(name)
+	if err != nil {
+		return err
+	}
+	ebuf.encoding = enc
+	return nil
+}
+
+// Save buffer to filename, converting line endings to buffer's
+// line ending and re-encoding it with buffer's encoding, an empty
+// filename saves to the file it was loaded from. Return
+// ErrorUnrepresentable without touching the file if buffer has
+// characters that cannot be encoded, refer Unrepresentable. With
+// "undodir" setting, change-tree is saved along with the file,
+// refer WriteHistory. Paged buffers that failed to read from file
+// are not saved, refer PagedBuffer.Err.
+func (ebuf *EditBuffer) Save(filename string) error {
+	if filename == "" {
+		filename = ebuf.filename
+	}
+	buffer, _ := ConvertLineEndings(ebuf.text(), ebuf.newline)
+	data := buffer.Bytes()
+	if pb, ok := ebuf.text().(*PagedBuffer); ok && pb.Err() != nil {
+		return pb.Err()
+	}
+	err := WriteFileEncoded(filename, data, ebuf.encoding)
+	if err != nil || ebuf.tree.undodir == "" {
+		return err
+	}
+	undofile, err := UndoFile(ebuf.tree.undodir, filename)
+	if err != nil {
+		return err
+	}
+	return ebuf.writeHistory(undofile, buffer)
+}
+
+// Unrepresentable return the cursor of first character that
+// cannot be saved in buffer's encoding, -1 if there is none.
+func (ebuf *EditBuffer) Unrepresentable() int64 {
+	return ebuf.encoding.Unrepresentable(ebuf.text().Bytes())
+}
+
+// LineEnding return the line ending used to save the buffer.
+func (ebuf *EditBuffer) LineEnding() LineEnding {
+	return ebuf.newline
+}
+
+// SetLineEnding change the line ending used to save the buffer,
+// name is one of "lf", "crlf", "cr".
+func (ebuf *EditBuffer) SetLineEnding(name string) error {
+	ending, err := ParseLineEnding(name)
+	if err != nil {
+		return err
+	}
+	ebuf.newline = ending
+	return nil
+}
+
+// MixedLineEndings return true if buffer was loaded with more than
+// one style of line ending, editors are expected to warn the user,
+// since Save will convert them to LineEnding.
+func (ebuf *EditBuffer) MixedLineEndings() bool {
+	return ebuf.mixed
+}
+
+// NormalizeLineEndings convert every line ending in the buffer to
+// buffer's line ending, as a new change. Dot is kept on the same
+// line.
+func (ebuf *EditBuffer) NormalizeLineEndings() (*EditBuffer, error) {
+	buffer, n := ConvertLineEndings(ebuf.text(), ebuf.newline)
+	if n == 0 {
+		return ebuf, nil
+	}
+	start := LineStart(ebuf.text(), ebuf.dot)
+	line, col := int64(0), ebuf.dot-start
+	for ; start > 0; line++ {
+		start = LineStart(ebuf.text(), start-1)
+	}
+	dot := int64(0)
+	for ; line > 0; line-- {
+		_, dot = LineEnd(buffer, dot)
+	}
+	if end, _ := LineEnd(buffer, dot); dot+col > end {
+		col = end - dot
+	}
+	child, err := ebuf.AppendChange(dot+col, buffer)
+	if err != nil {
+		return ebuf, err
+	}
+	child.mixed = false
+	return child, nil
+}
+
+// detectLineEnding set buffer's line ending to the dominant one in
+// the first count runes, all of them if count is -1.
+func (ebuf *EditBuffer) detectLineEnding(count int64) {
+	var reader RuneReader
+	if count < 0 {
+		reader = ebuf.text().StreamFrom(0)
+	} else {
+		reader = ebuf.text().StreamCount(0, count)
+	}
+	lcount := CountLineEndings(reader)
+	ebuf.newline, ebuf.mixed = lcount.Dominant(), lcount.Mixed()
+}
+
 // IsReadonly check whether edit-buffer is read-only.
 func (ebuf *EditBuffer) IsReadonly() bool {
 	return ebuf.ronly
@@ -94,93 +315,420 @@ func (ebuf *EditBuffer) ForceWrite() *EditBuffer {
 	return ebuf
 }
 
-// LineAround return a block of consecutive lines around bCur.
+// LinesAround return a block of consecutive lines around bCur.
 // width number of lines above the line containing bCur, and,
 // width number of lines below the line containing bcur.
 // including the line containing bCur.
 func (ebuf *EditBuffer) LinesAround(bCur int64, width int64) Lines {
+	if bCur < 0 {
+		bCur = ebuf.dot
+	}
+	i, ok := int64(-1), false
 	block := ebuf.lines.blocksFrom(bCur)()
-	if block == nil || (int64(len(block)) < (width*2 + 2)) {
+	if block != nil && int64(len(block)) >= (width*2+1)*2 {
+		i, ok = block.indexof(bCur) // block can start after bCur.
+	}
+	if !ok {
 		block := ebuf.BuildBlock(bCur, width)
 		ebuf.lines = ebuf.lines.mergeBlock(block)
 		return block
 	}
-	i, ok := block.indexof(bCur)
-	if !ok {
-		panic("impossible situation\n")
-	}
-	start := i - width
+	// i index the pair of the line containing bCur.
+	start := i - width*2
 	if start < 0 {
 		start = 0
 	}
-	end := i + width
-	if end >= int64(len(block)) {
-		end = int64(len(block)) - 1
+	end := i + width*2 + 2
+	if end > int64(len(block)) {
+		end = int64(len(block))
 	}
 	lines := make(Lines, 0, width*2+2)
-	for i := start; i <= end; i++ {
-		lines = append(lines, block[i])
-	}
-	return lines
+	return append(lines, block[start:end]...)
 }
 
 // BuildBlock around specified cursor position,
 // if `bCur` is -1 use current cursor position.
-// Return Lines of specified width*2 + 1.
+// Return Lines of specified width*2 + 1, any of "\n", "\r\n"
+// and "\r" end a line.
 func (ebuf *EditBuffer) BuildBlock(bCur int64, width int64) Lines {
 	if bCur < 0 {
 		bCur = ebuf.dot
 	}
-	lnNl := int64(len(ebuf.newline))
-	lines := make(Lines, 0, (width*2+1)*2+2)
-	// gather lines above cursor.
-	iter := Find(ebuf.reNlR, ebuf.buffer.BackStreamFrom(bCur))
-	for i, end := int64(0), int64(-1); i < width+1; i++ {
-		loc := iter()
-		if loc != nil {
-			lines = append(lines, end, int64(loc[0])+lnNl)
-			end = int64(loc[1])
-		} else {
-			lines = append(lines, end, 0)
-			break
+	return Lines(buildBlock(ebuf.text(), bCur, width))
+}
+
+//-------------------
+// APIs to move dot
+//-------------------
+
+// NextGrapheme return cursor after the grapheme cluster starting
+// at dot, so that dot never lands inside a user perceived character.
+func (ebuf *EditBuffer) NextGrapheme(dot int64) int64 {
+	return NextGrapheme(ebuf.text(), dot)
+}
+
+// PrevGrapheme return cursor at the start of the grapheme cluster
+// ending at dot.
+func (ebuf *EditBuffer) PrevGrapheme(dot int64) int64 {
+	return PrevGrapheme(ebuf.text(), dot)
+}
+
+// MoveDot by n grapheme clusters, forward if n is positive and
+// backward if n is negative, stopping at either end of buffer,
+// selections collapse to the cursor at dot. Return the new dot.
+func (ebuf *EditBuffer) MoveDot(n int64) int64 {
+	ebuf.sels = nil
+	for ; n > 0 && ebuf.dot < ebuf.text().Length(); n-- {
+		ebuf.dot = ebuf.NextGrapheme(ebuf.dot)
+	}
+	for ; n < 0 && ebuf.dot > 0; n++ {
+		ebuf.dot = ebuf.PrevGrapheme(ebuf.dot)
+	}
+	return ebuf.dot
+}
+
+// Column return the visual column of dot within its line, with
+// tabs expanded and wide characters taking two columns.
+func (ebuf *EditBuffer) Column(dot int64) int64 {
+	return RuneToColumn(ebuf.text(), dot, ebuf.tabstop)
+}
+
+// MoveColumn move dot to the grapheme cluster occupying column in
+// the current line, like when moving up and down lines, selections
+// collapse to the cursor at dot. Return the new dot.
+func (ebuf *EditBuffer) MoveColumn(column int64) int64 {
+	ebuf.sels = nil
+	ebuf.dot = ColumnToRune(ebuf.text(), ebuf.dot, column, ebuf.tabstop)
+	return ebuf.dot
+}
+
+//----------------------
+// APIs for selections
+//----------------------
+
+// Selections return the selection set, a single cursor at dot if
+// there are no selections.
+func (ebuf *EditBuffer) Selections() Selections {
+	if len(ebuf.sels) == 0 {
+		return Selections{Selection{Anchor: ebuf.dot, Head: ebuf.dot}}
+	}
+	return append(Selections{}, ebuf.sels...)
+}
+
+// SetSelections replace the selection set with sels, overlapping
+// selections are merged, dot is moved to the head of the first
+// selection.
+func (ebuf *EditBuffer) SetSelections(sels ...Selection) error {
+	size := ebuf.text().Length()
+	for _, sel := range sels {
+		if sel.Start() < 0 || sel.End() > size {
+			return ErrorIndexOutofbound
 		}
 	}
-	lines.reverse()
-	// gather lines below cursor.
-	iter = Find(ebuf.reNlR, ebuf.buffer.StreamFrom(bCur))
-	for i, start := int64(0), int64(-1); i < width; i++ {
-		loc := iter()
-		if loc != nil {
-			lines = append(lines, start, int64(loc[1]))
-			start = int64(loc[1])
-		} else {
-			lines = append(lines, start, ebuf.buffer.Size())
-			break
+	if len(sels) == 0 {
+		ebuf.sels = nil
+		return nil
+	}
+	ebuf.sels = NewSelections(sels...)
+	ebuf.dot = ebuf.sels[0].Head
+	return nil
+}
+
+// AddSelection to the selection set, like adding a cursor.
+func (ebuf *EditBuffer) AddSelection(sel Selection) error {
+	return ebuf.SetSelections(append(ebuf.Selections(), sel)...)
+}
+
+// InsertText replace text of every selection with text, like
+// typing with multiple cursors, as a single change. Typed runes
+// are grouped into one change, refer SetGrouping. Selections
+// collapse to cursors after the inserted text. Return the new edit
+// buffer, UndoChange restore both text and selections.
+func (ebuf *EditBuffer) InsertText(text []rune) (*EditBuffer, error) {
+	sels := ebuf.Selections()
+	child, err := ebuf.appendSelections(sels, sels.replaceEdits(text))
+	if err != nil || len(text) != 1 {
+		return child, err
+	}
+	if ebuf.groupTyped(text[0], sels) {
+		child = ebuf.tree.merge(ebuf.parent, child, math.MaxInt64)
+	}
+	child.typed, child.typedAt = text[0], child.Selections()
+	return child, nil
+}
+
+// DeleteText delete text of every selection as a single change,
+// for cursors delete n runes after the cursor, or -n runes before
+// the cursor if n is negative, like backspace. Return the new edit
+// buffer, UndoChange restore both text and selections.
+func (ebuf *EditBuffer) DeleteText(n int64) (*EditBuffer, error) {
+	sels := ebuf.Selections()
+	return ebuf.appendSelections(sels, sels.deleteEdits(ebuf.text(), n))
+}
+
+func (ebuf *EditBuffer) appendSelections(
+	sels Selections, edits []textEdit) (*EditBuffer, error) {
+
+	sels = sels.shift(edits)
+	child, err := ebuf.appendEdits(sels[0].Head, edits)
+	if err != nil {
+		return ebuf, err
+	}
+	if len(sels) > 1 {
+		child.sels = sels
+	}
+	return child, nil
+}
+
+//---------------
+// APIs for marks
+//---------------
+
+// NewAnchor return an anonymous anchor at rCur, that keeps its
+// position in text across changes, including UndoChange and
+// RedoChange, refer Anchor.
+func (ebuf *EditBuffer) NewAnchor(rCur int64, gravity Gravity) (*Anchor, error) {
+	if rCur < 0 || rCur > ebuf.text().Length() {
+		return nil, ErrorIndexOutofbound
+	}
+	anchor := &Anchor{change: ebuf, rCur: rCur, gravity: gravity}
+	ebuf.tree.anchors[anchor] = true
+	return anchor, nil
+}
+
+// ReleaseAnchor when it is no more used.
+func (ebuf *EditBuffer) ReleaseAnchor(anchor *Anchor) {
+	delete(anchor.change.tree.anchors, anchor)
+}
+
+// Position return anchor's position in this change, -1 if anchor
+// belongs to a different change-tree, or if the history between
+// them does not fit the text.
+func (ebuf *EditBuffer) Position(anchor *Anchor) int64 {
+	return anchor.resolve(ebuf)
+}
+
+// MoveAnchor to rCur in this change.
+func (ebuf *EditBuffer) MoveAnchor(anchor *Anchor, rCur int64) error {
+	if rCur < 0 || rCur > ebuf.text().Length() {
+		return ErrorIndexOutofbound
+	}
+	delete(anchor.change.tree.anchors, anchor)
+	anchor.reset(ebuf, rCur)
+	ebuf.tree.anchors[anchor] = true
+	return nil
+}
+
+// SetMark set a named mark at rCur, replacing the mark if it
+// already exists. Marks are shared by all the changes of the
+// change-tree.
+func (ebuf *EditBuffer) SetMark(name string, rCur int64, gravity Gravity) error {
+	anchor, err := ebuf.NewAnchor(rCur, gravity)
+	if err != nil {
+		return err
+	}
+	ebuf.DeleteMark(name)
+	ebuf.tree.marks[name] = anchor
+	return nil
+}
+
+// Mark return the position of named mark in this change, false if
+// there is no such mark or it can not be resolved, refer Position.
+func (ebuf *EditBuffer) Mark(name string) (int64, bool) {
+	anchor, ok := ebuf.tree.marks[name]
+	if !ok {
+		return -1, false
+	}
+	rCur := ebuf.Position(anchor)
+	return rCur, rCur >= 0
+}
+
+// DeleteMark remove the named mark.
+func (ebuf *EditBuffer) DeleteMark(name string) {
+	if anchor, ok := ebuf.tree.marks[name]; ok {
+		ebuf.ReleaseAnchor(anchor)
+	}
+	delete(ebuf.tree.marks, name)
+}
+
+// MarkNames return sorted names of all the marks.
+func (ebuf *EditBuffer) MarkNames() []string {
+	names := make([]string, 0, len(ebuf.tree.marks))
+	for name := range ebuf.tree.marks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//---------------
+// APIs to search
+//---------------
+
+// SearchNext return the first match of re that starts after dot,
+// like "/pattern", as {start, end} rune offsets, nil if there is
+// no match.
+func (ebuf *EditBuffer) SearchNext(re *Regexp) []int64 {
+	iter := Find(re, ebuf.text().StreamFrom(ebuf.dot))
+	for loc := iter(); loc != nil; loc = iter() {
+		if loc[0] > 0 {
+			return []int64{ebuf.dot + loc[0], ebuf.dot + loc[1]}
 		}
 	}
-	// consolidate.
-	for i, j := 1, 1; (i + 1) < len(lines); i += 2 {
-		if lines[i] != lines[i+1] {
-			panic("impossible situation")
-		} else if lines[i] == -1 && lines[i+1] == -1 {
-			continue
+	return nil
+}
+
+// SearchPrev return the nearest match of re before dot, like
+// "?pattern", as {start, end} rune offsets, nil if there is no
+// match, refer FindBackward.
+func (ebuf *EditBuffer) SearchPrev(re *Regexp) []int64 {
+	return FindBackward(re, ebuf.text().BackStreamFrom(ebuf.dot), ebuf.dot)()
+}
+
+// Substitute replace upto n matches of pattern, -1 for all the
+// matches, within runes [start, end) with template, as a single
+// change, so that one UndoChange reverts all the replacements.
+// Dot is moved to the start of last replacement. Return the new
+// edit buffer and {start, end} pairs of the replaced text, refer
+// Substitute for the template syntax.
+func (ebuf *EditBuffer) Substitute(
+	pattern, template string,
+	start, end, n int64) (*EditBuffer, []int64, error) {
+
+	if ebuf.ronly {
+		return ebuf, nil, ErrorReadonlyBuffer
+	}
+	re, err := CompileRegexp(pattern)
+	if err != nil {
+		return ebuf, nil, err
+	}
+	edits, err := substituteEdits(ebuf.text(), re, template, start, end, n)
+	if err != nil || len(edits) == 0 {
+		return ebuf, nil, err
+	}
+	ranges := editRanges(edits)
+	child, err := ebuf.appendEdits(ranges[len(ranges)-2], edits)
+	if err != nil {
+		return ebuf, nil, err
+	}
+	return child, ranges, nil
+}
+
+//---------------------
+// APIs for transactions
+//---------------------
+
+// BeginTransaction start a transaction at this change, changes made
+// until Commit, or Abort, are undone and redone as a single change,
+// like a macro run. Transactions can be nested.
+func (ebuf *EditBuffer) BeginTransaction() {
+	txn := transaction{start: ebuf, seq: ebuf.tree.seq}
+	ebuf.tree.txns = append(ebuf.tree.txns, txn)
+}
+
+// Commit the innermost transaction, changes made since
+// BeginTransaction, along the path to this change, are merged into
+// a single change. Return the merged change, ErrorTransaction if
+// there is no transaction or this change was not made within it.
+func (ebuf *EditBuffer) Commit() (*EditBuffer, error) {
+	txn, err := ebuf.endTransaction()
+	if err != nil {
+		return ebuf, err
+	} else if ebuf == txn.start {
+		return ebuf, nil
+	}
+	merged := ebuf.tree.merge(txn.start, ebuf, txn.seq)
+	ebuf.tree.prune(merged, merged.time)
+	return merged, nil
+}
+
+// Abort the innermost transaction, changes made since
+// BeginTransaction are discarded. Return the change where the
+// transaction started, ErrorTransaction if there is no transaction
+// or this change was not made within it.
+func (ebuf *EditBuffer) Abort() (*EditBuffer, error) {
+	txn, err := ebuf.endTransaction()
+	if err != nil {
+		return ebuf, err
+	}
+	start := txn.start
+	for _, child := range append([]*EditBuffer{}, start.children...) {
+		if child.seq > txn.seq {
+			onpath := make(map[*EditBuffer]bool)
+			ebuf.tree.drop(start, child, onpath, newPruneStats())
 		}
-		lines[j], lines[j+1] = lines[i], lines[i+1]
-		j += 2
 	}
-	return lines
+	return start, nil
+}
+
+// SetGrouping group runes typed one at a time, refer InsertText,
+// into a single change, until a pause longer than pause, a cursor
+// jump, or a new word. Zero pause, the default, disable grouping.
+func (ebuf *EditBuffer) SetGrouping(pause time.Duration) {
+	ebuf.tree.pause = pause
+}
+
+func (ebuf *EditBuffer) endTransaction() (transaction, error) {
+	n := len(ebuf.tree.txns)
+	if n == 0 {
+		return transaction{}, ErrorTransaction
+	}
+	txn, x := ebuf.tree.txns[n-1], ebuf
+	for ; x != nil && x != txn.start; x = x.parent {
+	}
+	if x == nil {
+		return txn, ErrorTransaction
+	}
+	ebuf.tree.txns = ebuf.tree.txns[:n-1]
+	return txn, nil
+}
+
+// groupTyped return true if rune r, typed at sels, continue the
+// runes typed in this change.
+func (ebuf *EditBuffer) groupTyped(r rune, sels Selections) bool {
+	switch {
+	case ebuf.tree.pause <= 0 || ebuf.typed == 0 || ebuf.parent == nil:
+		return false
+	case len(ebuf.children) != 1 || ebuf.tree.inTransaction(ebuf):
+		return false
+	case time.Since(ebuf.time) > ebuf.tree.pause:
+		return false
+	case !reflect.DeepEqual(sels, ebuf.typedAt): // cursor jump
+		return false
+	}
+	return !isWordRune(r) || isWordRune(ebuf.typed)
 }
 
 //---------------------------
 // APIs to manage change-tree
 //---------------------------
 
-// UpdateChange will overwrite the current buffer reference.
+// UpdateChange will overwrite the current buffer reference. The
+// change and its children become checkpoints, holding their own
+// buffer.
 func (ebuf *EditBuffer) UpdateChange(buffer Buffer) (*EditBuffer, error) {
 	if ebuf.ronly {
 		return ebuf, ErrorReadonlyBuffer
 	}
+	changes := append([]*EditBuffer{ebuf}, ebuf.children...)
+	for _, change := range changes {
+		ebuf.tree.bytes -= change.memory()
+	}
+	for _, child := range ebuf.children {
+		child.buffer = child.text()
+		child.checkpoint, child.chain, child.churn = true, 0, 0
+	}
+	if ebuf.parent != nil {
+		ebuf.edits, ebuf.undo = diffEdits(ebuf.parent.text(), buffer), nil
+	}
 	ebuf.buffer = buffer
+	ebuf.checkpoint, ebuf.chain, ebuf.churn = true, 0, 0
+	for _, child := range ebuf.children {
+		child.edits, child.undo = diffEdits(buffer, child.buffer), nil
+	}
+	for _, change := range changes {
+		ebuf.tree.bytes += change.memory()
+	}
 	return ebuf, nil
 }
 
@@ -191,26 +739,280 @@ func (ebuf *EditBuffer) AppendChange(
 
 	if ebuf.ronly {
 		return ebuf, ErrorReadonlyBuffer
+	} else if ebuf.pruned {
+		return ebuf, ErrorPrunedChange
 	}
+	return ebuf.appendChange(dot, buffer, diffEdits(ebuf.text(), buffer))
+}
+
+// appendEdits apply edits to the current buffer as a new change,
+// edits are remembered so that marks can follow them.
+func (ebuf *EditBuffer) appendEdits(
+	dot int64, edits []textEdit) (*EditBuffer, error) {
+
+	if ebuf.ronly {
+		return ebuf, ErrorReadonlyBuffer
+	} else if ebuf.pruned {
+		return ebuf, ErrorPrunedChange
+	}
+	buffer := ebuf.text()
+	edits = recordEdits(buffer, edits)
+	return ebuf.appendChange(dot, applyEdits(buffer, edits), edits)
+}
+
+// appendChange chain a new change, made by recorded edits, to the
+// current edit buffer, and prune the change-tree. Return
+// ErrorPrunedChange if this change is no longer in the tree.
+func (ebuf *EditBuffer) appendChange(
+	dot int64, buffer Buffer, edits []textEdit) (*EditBuffer, error) {
+
+	if ebuf.pruned {
+		return ebuf, ErrorPrunedChange
+	}
+	child := ebuf.newChange(dot, buffer, edits)
+	ebuf.tree.prune(child, child.time)
+	return child, nil
+}
+
+// newChange chain a new change, made by recorded edits, to the
+// current edit buffer. Change is stored as edits, and its buffer
+// is rebuilt on demand, unless it becomes a checkpoint after
+// changeCheckpoint changes, or when its edits are larger than its
+// buffer.
+func (ebuf *EditBuffer) newChange(
+	dot int64, buffer Buffer, edits []textEdit) *EditBuffer {
+
 	child := NewEditBuffer(dot, buffer, ebuf)
+	child.filename, child.encoding = ebuf.filename, ebuf.encoding
+	child.newline, child.mixed = ebuf.newline, ebuf.mixed
+	child.checkpoint, child.edits = false, edits
+	child.chain, child.churn = ebuf.chain+1, ebuf.churn+deltaRunesIn(edits)
 	ebuf.children = append(ebuf.children, child)
-	return child, nil
+	ebuf.branch = len(ebuf.children) - 1
+
+	if child.chain >= changeCheckpoint || child.churn > buffer.Length() {
+		child.checkpoint, child.chain, child.churn = true, 0, 0
+	} else {
+		ebuf.tree.cache(child)
+	}
+	ebuf.tree.bytes += child.memory()
+	return child
+}
+
+// text return the buffer for this change, rebuilding it from the
+// nearest checkpoint if needed.
+func (ebuf *EditBuffer) text() Buffer {
+	if ebuf.buffer != nil {
+		return ebuf.buffer
+	}
+	path, x := make([]*EditBuffer, 0), ebuf
+	for ; x.buffer == nil; x = x.parent {
+		if x.parent == nil { // detached by pruning.
+			panic(ErrorPrunedChange)
+		}
+		path = append(path, x)
+	}
+	buffer := x.buffer
+	for i := len(path) - 1; i >= 0; i-- {
+		buffer = applyEdits(buffer, path[i].edits)
+	}
+	ebuf.buffer = buffer
+	ebuf.tree.cache(ebuf)
+	return buffer
+}
+
+// changeEdits return edits from parent to this change.
+func (ebuf *EditBuffer) changeEdits() []textEdit {
+	return ebuf.edits
+}
+
+// undoEdits return edits from this change back to its parent.
+func (ebuf *EditBuffer) undoEdits() []textEdit {
+	if ebuf.undo == nil && ebuf.parent != nil {
+		ebuf.undo = reverseEdits(ebuf.edits)
+	}
+	return ebuf.undo
+}
+
+// Stats return statistics on the change-tree, memory is an upper
+// bound in bytes, buffers may share their content.
+func (ebuf *EditBuffer) Stats() (Statistics, error) {
+	return ebuf.tree.stats(), nil
+}
+
+// memory return bytes held by this change for retention, the
+// buffer held by root is not counted, it is needed anyway.
+func (ebuf *EditBuffer) memory() int64 {
+	n := deltaRunesIn(ebuf.edits)
+	if ebuf.checkpoint && ebuf.parent != nil {
+		n += ebuf.buffer.Length()
+	}
+	return n * 4
+}
+
+// SetRetention limit the change-tree to maxChanges no. of changes,
+// maxBytes of memory held by changes, other than the oldest, and
+// to changes made within maxAge, zero for no limit. Limits are
+// applied by Prune, which is also done after every new change.
+func (ebuf *EditBuffer) SetRetention(
+	maxChanges, maxBytes int64, maxAge time.Duration) {
+
+	ebuf.tree.maxChanges, ebuf.tree.maxBytes = maxChanges, maxBytes
+	ebuf.tree.maxAge = maxAge
+}
+
+// Prune the change-tree to its retention limits, refer SetRetention.
+// Path from the oldest change to this change is never broken, but
+// branches off the path are dropped, least recently changed first,
+// and old changes along the path are squashed into a new base.
+// Anchors and marks in discarded changes are moved to the nearest
+// change that is kept. Return no. of "changes" and "bytes"
+// discarded, and the no. of "branches" dropped and changes
+// "squashed" into the base. Stats report the totals. Return
+// ErrorPrunedChange if this change is no longer in the tree.
+func (ebuf *EditBuffer) Prune() (Statistics, error) {
+	if ebuf.pruned {
+		return newPruneStats(), ErrorPrunedChange
+	}
+	return ebuf.tree.prune(ebuf, time.Now()), nil
 }
 
-// Undo n changes.
+// CanUndo return ErrorOldestChange if this is the oldest change,
+// either the original buffer or, if "discarded" in Stats is
+// non-zero, the base left after pruning.
+func (ebuf *EditBuffer) CanUndo() error {
+	if ebuf.parent == nil {
+		return ErrorOldestChange
+	}
+	return nil
+}
+
+// CanRedo return ErrorLatestChange if there is no change to redo.
+func (ebuf *EditBuffer) CanRedo() error {
+	if len(ebuf.children) == 0 {
+		return ErrorLatestChange
+	}
+	return nil
+}
+
+// Undo n changes, RedoChange from the returned change will come
+// back along the same branch.
 func (ebuf *EditBuffer) UndoChange(n int64) *EditBuffer {
 	for ebuf.parent != nil && n > 0 {
+		ebuf.parent.branch = ebuf.index()
 		ebuf = ebuf.parent
 		n--
 	}
 	return ebuf
 }
 
-// Redo n changes.
+// Redo n changes, following the selected branch at each change,
+// refer SelectBranch.
 func (ebuf *EditBuffer) RedoChange(n int64) *EditBuffer {
 	for len(ebuf.children) > 0 && n > 0 {
-		ebuf = ebuf.children[len(ebuf.children)-1]
+		ebuf = ebuf.children[ebuf.branch]
 		n--
 	}
 	return ebuf
 }
+
+// Branches return changes made on top of this change, in the order
+// they were made, each one starting a branch of the change-tree.
+func (ebuf *EditBuffer) Branches() []*EditBuffer {
+	return append([]*EditBuffer{}, ebuf.children...)
+}
+
+// SelectBranch select the n-th branch, refer Branches, as the one
+// to follow on RedoChange. By default it is the branch that was
+// last undone, or the latest branch.
+func (ebuf *EditBuffer) SelectBranch(n int) error {
+	if n < 0 || n >= len(ebuf.children) {
+		return ErrorIndexOutofbound
+	}
+	ebuf.branch = n
+	return nil
+}
+
+// Seq return the sequence no. of this change, changes are numbered
+// in the order they were made, starting from 0 for the original
+// buffer.
+func (ebuf *EditBuffer) Seq() int64 {
+	return ebuf.seq
+}
+
+// Timestamp return the time when this change was made.
+func (ebuf *EditBuffer) Timestamp() time.Time {
+	return ebuf.time
+}
+
+// EarlierChange move back by n changes in the order they were
+// made, irrespective of the branch they were made in, like "g-" in
+// vim. Return the change with sequence no. Seq()-n.
+func (ebuf *EditBuffer) EarlierChange(n int64) *EditBuffer {
+	return ebuf.tree.change(ebuf.seq-n, false).follow()
+}
+
+// LaterChange move forward by n changes in the order they were
+// made, irrespective of the branch they were made in, like "g+" in
+// vim. Return the change with sequence no. Seq()+n.
+func (ebuf *EditBuffer) LaterChange(n int64) *EditBuffer {
+	return ebuf.tree.change(ebuf.seq+n, true).follow()
+}
+
+// EarlierTime go back in time by d, like ":earlier 10m" in vim.
+// Return the latest change made at or before Timestamp()-d.
+func (ebuf *EditBuffer) EarlierTime(d time.Duration) *EditBuffer {
+	change := ebuf.tree.changeAt(ebuf.time.Add(-d))
+	if change.seq > ebuf.seq {
+		return ebuf
+	}
+	return change.follow()
+}
+
+// LaterTime go forward in time by d, like ":later 10m" in vim.
+// Return the latest change made at or before Timestamp()+d.
+func (ebuf *EditBuffer) LaterTime(d time.Duration) *EditBuffer {
+	change := ebuf.tree.changeAt(ebuf.time.Add(d))
+	if change.seq < ebuf.seq {
+		return ebuf
+	}
+	return change.follow()
+}
+
+// index of this change among its parent's children.
+func (ebuf *EditBuffer) index() int {
+	for i, child := range ebuf.parent.children {
+		if child == ebuf {
+			return i
+		}
+	}
+	panic("impossible situation")
+}
+
+// latest return the latest change in the sub-tree of this change.
+func (ebuf *EditBuffer) latest() *EditBuffer {
+	latest := ebuf
+	ebuf.walk(func(x *EditBuffer) {
+		if x.seq > latest.seq {
+			latest = x
+		}
+	})
+	return latest
+}
+
+// walk every change in the sub-tree of this change.
+func (ebuf *EditBuffer) walk(fn func(*EditBuffer)) {
+	fn(ebuf)
+	for _, child := range ebuf.children {
+		child.walk(fn)
+	}
+}
+
+// follow select branches from the root to this change, so that
+// RedoChange from any of its ancestors leads here.
+func (ebuf *EditBuffer) follow() *EditBuffer {
+	for x := ebuf; x.parent != nil; x = x.parent {
+		x.parent.branch = x.index()
+	}
+	return ebuf
+}
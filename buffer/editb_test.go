@@ -1,7 +1,13 @@
-// build +ignore
-
 package buffer
 
+import "os"
+import "bytes"
+import "reflect"
+import "time"
+import "io/ioutil"
+import "encoding/gob"
+import "math/rand"
+import "strings"
 import "testing"
 import "fmt"
 
@@ -19,3 +25,950 @@ func TestEditBufferLines(t *testing.T) {
 	//ebuf := makeEbuf(``)
 	//fmt.Println(ebuf.Lines(-1, 1))
 }
+
+func TestEditBufferLineEndings(t *testing.T) {
+	fd, err := ioutil.TempFile("", "editbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Write([]byte("ab\r\ncd\r\nef\ngh\r\n"))
+	fd.Close()
+	defer os.Remove(fd.Name())
+
+	ebuf, err := NewEncodedFile(fd.Name(), "")
+	if err != nil {
+		t.Fatal(err)
+	} else if x := ebuf.LineEnding(); x != LineEndingCRLF {
+		t.Fatalf("expected %v, got %v", LineEndingCRLF, x)
+	} else if !ebuf.MixedLineEndings() {
+		t.Fatalf("expected mixed line endings")
+	}
+	lines := ebuf.BuildBlock(5, 1)
+	if ref := (Lines{0, 4, 4, 8, 8, 11}); !reflect.DeepEqual(lines, ref) {
+		t.Fatalf("expected %v, got %v", ref, lines)
+	}
+	if lines := ebuf.LinesAround(9, 1); !reflect.DeepEqual(lines, Lines{4, 8, 8, 11, 11, 15}) {
+		t.Fatalf("unexpected %v", lines)
+	}
+
+	// normalize as a change, dot stays on the same line.
+	ebuf.dot = 12
+	child, err := ebuf.NormalizeLineEndings()
+	if err != nil {
+		t.Fatal(err)
+	} else if x := string(child.text().Bytes()); x != "ab\r\ncd\r\nef\r\ngh\r\n" {
+		t.Fatalf("unexpected %q", x)
+	} else if child.dot != 13 || child.MixedLineEndings() {
+		t.Fatalf("unexpected dot %v mixed %v", child.dot, child.MixedLineEndings())
+	} else if child.UndoChange(1) != ebuf {
+		t.Fatalf("expected undo to the original buffer")
+	}
+
+	// save with a different line ending.
+	if err := ebuf.SetLineEnding("lf"); err != nil {
+		t.Fatal(err)
+	} else if err := ebuf.Save(""); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := ioutil.ReadFile(fd.Name())
+	if x := string(out); x != "ab\ncd\nef\ngh\n" {
+		t.Fatalf("unexpected %q", x)
+	} else if err := ebuf.SetLineEnding("lfcr"); err != ErrorInvalidNewline {
+		t.Fatalf("expected %v, got %v", ErrorInvalidNewline, err)
+	}
+}
+
+func TestEditBufferColumn(t *testing.T) {
+	text := "a\tb世界c\nxy\t\U0001F600z"
+	ebuf := NewEditBuffer(0, NewRopebuffer([]byte(text), 8), nil)
+	ebuf.Configure(map[string]interface{}{"tabstop": int64(4)})
+	columns := []int64{0, 1, 4, 5, 7, 9, 10, 0, 1, 2, 4, 6, 7}
+	for dot, ref := range columns {
+		if x := ebuf.Column(int64(dot)); x != ref {
+			t.Fatalf("%v expected %v, got %v", dot, ref, x)
+		}
+	}
+	testcases := []struct{ dot, column, ref int64 }{
+		{2, 6, 3}, {2, 5, 3}, {2, 2, 1}, {0, 100, 6}, {9, 5, 10}, {8, 0, 7},
+		{12, 100, 12},
+	}
+	for i, tcase := range testcases {
+		ebuf.dot = tcase.dot
+		if x := ebuf.MoveColumn(tcase.column); x != tcase.ref {
+			t.Fatalf("%v expected %v, got %v", i, tcase.ref, x)
+		} else if ebuf.dot != tcase.ref {
+			t.Fatalf("%v expected %v, got %v", i, tcase.ref, ebuf.dot)
+		}
+	}
+}
+
+func TestEditBufferFiles(t *testing.T) {
+	fd, err := ioutil.TempFile("", "editbfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Repeat("hello 世界\r\n", 1000)
+	fd.Write([]byte(text))
+	fd.Close()
+	defer os.Remove(fd.Name())
+	out := fd.Name() + ".out"
+	defer os.Remove(out)
+
+	// read-only file, edits after ForceWrite.
+	ebuf, err := NewReadOnlyFile(fd.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if !ebuf.IsReadonly() {
+		t.Fatalf("expected read-only buffer")
+	} else if x := string(ebuf.text().Bytes()); x != text {
+		t.Fatalf("unexpected %q", x[:20])
+	} else if x := ebuf.LineEnding(); x != LineEndingCRLF {
+		t.Fatalf("expected %v, got %v", LineEndingCRLF, x)
+	} else if _, err := ebuf.InsertText([]rune("x")); err != ErrorReadonlyBuffer {
+		t.Fatalf("expected %v, got %v", ErrorReadonlyBuffer, err)
+	}
+	change, err := ebuf.ForceWrite().InsertText([]rune("x\n"))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := change.Save(out); err != nil {
+		t.Fatal(err)
+	} else if data, _ := ioutil.ReadFile(out); string(data) != "x\r\n"+text {
+		t.Fatalf("unexpected %q", string(data[:20]))
+	} else if data, _ := ioutil.ReadFile(fd.Name()); string(data) != text {
+		t.Fatalf("unexpected %q", string(data[:20]))
+	}
+
+	// paged file, saved in place.
+	ebuf, err = NewPagedFile(fd.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if ebuf.IsReadonly() {
+		t.Fatalf("unexpected read-only buffer")
+	} else if x := ebuf.text().Length(); x != int64(len([]rune(text))) {
+		t.Fatalf("expected %v, got %v", len([]rune(text)), x)
+	}
+	ebuf.dot = ebuf.text().Length()
+	change, err = ebuf.InsertText([]rune("end"))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := change.Save(""); err != nil {
+		t.Fatal(err)
+	} else if data, _ := ioutil.ReadFile(fd.Name()); string(data) != text+"end" {
+		t.Fatalf("unexpected %q", string(data[len(data)-20:]))
+	} else if x := string(change.UndoChange(1).text().Bytes()); x != text {
+		t.Fatalf("unexpected %q", x[:20])
+	}
+}
+
+func TestEditBufferSaveInPlace(t *testing.T) {
+	chunk, budget := PagedBufferChunk, PagedBufferBudget
+	PagedBufferChunk, PagedBufferBudget = 4*1024, 16*1024
+	defer func() { PagedBufferChunk, PagedBufferBudget = chunk, budget }()
+
+	fd, err := ioutil.TempFile("", "editbsave")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fd.Name())
+	text := strings.Repeat("hello 世界, a line of text\n", 16*1024)
+	fd.Write([]byte(text))
+	fd.Close()
+	header := strings.Repeat("header\n", 100)
+
+	// paged file, chunks are evicted and reloaded after save.
+	ebuf, err := NewPagedFile(fd.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, err := ebuf.InsertText([]rune(header))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := change.Save(""); err != nil {
+		t.Fatal(err)
+	}
+	if x := string(change.text().Bytes()); x != header+text {
+		t.Fatalf("unexpected saved change of %v bytes", len(x))
+	} else if x := string(ebuf.text().Bytes()); x != text {
+		t.Fatalf("unexpected parent change of %v bytes", len(x))
+	} else if data, _ := ioutil.ReadFile(fd.Name()); string(data) != header+text {
+		t.Fatalf("unexpected file of %v bytes", len(data))
+	}
+	reread, err := NewPagedFile(fd.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if x := string(reread.text().Bytes()); x != header+text {
+		t.Fatalf("unexpected reread of %v bytes", len(x))
+	}
+
+	// mapped file, saved shorter than the mapping.
+	ebuf, err = NewReadOnlyFile(fd.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	change = ebuf.ForceWrite()
+	change.SetSelections(Selection{0, change.text().Length() - 10})
+	if change, err = change.DeleteText(1); err != nil {
+		t.Fatal(err)
+	} else if err := change.Save(""); err != nil {
+		t.Fatal(err)
+	}
+	ref := []rune(header + text)
+	if x := string(ebuf.text().Bytes()); x != string(ref) {
+		t.Fatalf("unexpected parent change of %v bytes", len(x))
+	} else if x := string(change.text().Bytes()); x != string(ref[len(ref)-10:]) {
+		t.Fatalf("unexpected %q", x)
+	} else if data, _ := ioutil.ReadFile(fd.Name()); string(data) != x {
+		t.Fatalf("unexpected %q", data)
+	}
+	reread, err = NewReadOnlyFile(fd.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if x := string(reread.text().Bytes()); x != string(ref[len(ref)-10:]) {
+		t.Fatalf("unexpected %q", x)
+	}
+}
+
+func TestEditBufferSearch(t *testing.T) {
+	text := "fee fi fo fum\nfee fi"
+	ebuf := NewEditBuffer(6, NewRopebuffer([]byte(text), 8), nil)
+	re := MustCompileRegexp(`f[a-z]+`)
+	if loc := ebuf.SearchNext(re); !reflect.DeepEqual(loc, []int64{7, 9}) {
+		t.Fatalf("expected %v, got %v", []int64{7, 9}, loc)
+	} else if loc := ebuf.SearchPrev(re); !reflect.DeepEqual(loc, []int64{4, 6}) {
+		t.Fatalf("expected %v, got %v", []int64{4, 6}, loc)
+	}
+	ebuf.dot = 4 // at the start of a match.
+	if loc := ebuf.SearchNext(re); !reflect.DeepEqual(loc, []int64{7, 9}) {
+		t.Fatalf("expected %v, got %v", []int64{7, 9}, loc)
+	} else if loc := ebuf.SearchPrev(re); !reflect.DeepEqual(loc, []int64{0, 3}) {
+		t.Fatalf("expected %v, got %v", []int64{0, 3}, loc)
+	}
+	ebuf.dot = 18
+	if loc := ebuf.SearchNext(re); loc != nil {
+		t.Fatalf("unexpected %v", loc)
+	} else if loc := ebuf.SearchPrev(MustCompileRegexp(`(?m)^f`)); !reflect.DeepEqual(loc, []int64{14, 15}) {
+		t.Fatalf("expected %v, got %v", []int64{14, 15}, loc)
+	}
+}
+
+func TestEditBufferSubstitute(t *testing.T) {
+	text := "let x = 1; let y = 2;\nlet z = x + y;"
+	ebuf := NewEditBuffer(0, NewRopebuffer([]byte(text), 8), nil)
+	child, ranges, err := ebuf.Substitute(`let (\w)`, `var $1`, 0, 21, -1)
+	if err != nil {
+		t.Fatal(err)
+	} else if x := string(child.text().Bytes()); x != "var x = 1; var y = 2;\nlet z = x + y;" {
+		t.Fatalf("unexpected %q", x)
+	} else if !reflect.DeepEqual(ranges, []int64{0, 5, 11, 16}) {
+		t.Fatalf("unexpected %v", ranges)
+	} else if child.dot != 11 {
+		t.Fatalf("expected %v, got %v", 11, child.dot)
+	} else if child.UndoChange(1) != ebuf || child.RedoChange(1) != child {
+		t.Fatalf("expected a single change")
+	} else if x := string(ebuf.text().Bytes()); x != text {
+		t.Fatalf("unexpected %q", x)
+	}
+	// no match, no change.
+	if x, ranges, err := ebuf.Substitute(`const`, `let`, 0, 10, -1); err != nil {
+		t.Fatal(err)
+	} else if x != ebuf || ranges != nil || len(ebuf.children) != 1 {
+		t.Fatalf("unexpected change")
+	}
+	if _, _, err := ebuf.Substitute(`(`, ``, 0, 10, -1); err == nil {
+		t.Fatalf("expected error")
+	}
+	ronly := NewReadOnlyBuffer(0, ebuf.text())
+	if _, _, err := ronly.Substitute(`let`, `var`, 0, 10, -1); err != ErrorReadonlyBuffer {
+		t.Fatalf("expected %v, got %v", ErrorReadonlyBuffer, err)
+	}
+}
+
+func TestEditBufferSelections(t *testing.T) {
+	text := "one\ntwo\nthree"
+	ebuf := NewEditBuffer(0, NewRopebuffer([]byte(text), 8), nil)
+	if x := ebuf.Selections(); !reflect.DeepEqual(x, Selections{{0, 0}}) {
+		t.Fatalf("unexpected %v", x)
+	}
+	if err := ebuf.SetSelections(Selection{8, 8}, Selection{4, 4}); err != nil {
+		t.Fatal(err)
+	} else if err := ebuf.AddSelection(Selection{0, 0}); err != nil {
+		t.Fatal(err)
+	} else if err := ebuf.AddSelection(Selection{0, 14}); err != ErrorIndexOutofbound {
+		t.Fatalf("expected %v, got %v", ErrorIndexOutofbound, err)
+	}
+	before := Selections{{0, 0}, {4, 4}, {8, 8}}
+	if x := ebuf.Selections(); !reflect.DeepEqual(x, before) {
+		t.Fatalf("expected %v, got %v", before, x)
+	} else if ebuf.dot != 0 {
+		t.Fatalf("expected %v, got %v", 0, ebuf.dot)
+	}
+
+	child, err := ebuf.InsertText([]rune("- "))
+	if err != nil {
+		t.Fatal(err)
+	} else if x := string(child.text().Bytes()); x != "- one\n- two\n- three" {
+		t.Fatalf("unexpected %q", x)
+	}
+	after := Selections{{2, 2}, {8, 8}, {14, 14}}
+	if x := child.Selections(); !reflect.DeepEqual(x, after) {
+		t.Fatalf("expected %v, got %v", after, x)
+	}
+	child, err = child.DeleteText(-2)
+	if err != nil {
+		t.Fatal(err)
+	} else if x := string(child.text().Bytes()); x != text {
+		t.Fatalf("unexpected %q", x)
+	} else if x := child.Selections(); !reflect.DeepEqual(x, before) {
+		t.Fatalf("expected %v, got %v", before, x)
+	}
+
+	// undo and redo restore the selections along with the text.
+	undo := child.UndoChange(1)
+	if x := undo.Selections(); !reflect.DeepEqual(x, after) {
+		t.Fatalf("expected %v, got %v", after, x)
+	} else if x := undo.UndoChange(1); x != ebuf {
+		t.Fatalf("unexpected undo")
+	} else if x := ebuf.RedoChange(2).Selections(); !reflect.DeepEqual(x, before) {
+		t.Fatalf("expected %v, got %v", before, x)
+	}
+
+	// moving dot collapse the selections.
+	ebuf.MoveDot(1)
+	if x := ebuf.Selections(); !reflect.DeepEqual(x, Selections{{1, 1}}) {
+		t.Fatalf("unexpected %v", x)
+	}
+	ronly := NewReadOnlyBuffer(0, ebuf.text())
+	if _, err := ronly.InsertText([]rune("x")); err != ErrorReadonlyBuffer {
+		t.Fatalf("expected %v, got %v", ErrorReadonlyBuffer, err)
+	}
+}
+
+func TestEditBufferMarks(t *testing.T) {
+	text := "alpha beta gamma"
+	ebuf := NewEditBuffer(0, NewRopebuffer([]byte(text), 8), nil)
+	if err := ebuf.SetMark("b", 6, GravityLeft); err != nil {
+		t.Fatal(err)
+	} else if err := ebuf.SetMark("x", 17, GravityLeft); err != ErrorIndexOutofbound {
+		t.Fatalf("expected %v, got %v", ErrorIndexOutofbound, err)
+	}
+	right, _ := ebuf.NewAnchor(6, GravityRight)
+	gamma, _ := ebuf.NewAnchor(11, GravityLeft)
+	check := func(change *EditBuffer, b, r, g int64) {
+		t.Helper()
+		if x, ok := change.Mark("b"); !ok || x != b {
+			t.Fatalf("expected %v, got %v", b, x)
+		} else if x := change.Position(right); x != r {
+			t.Fatalf("expected %v, got %v", r, x)
+		} else if x := change.Position(gamma); x != g {
+			t.Fatalf("expected %v, got %v", g, x)
+		}
+	}
+
+	c1, err := ebuf.InsertText([]rune("x "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(c1, 8, 8, 13)
+	c1.SetSelections(Selection{8, 13})
+	c2, err := c1.DeleteText(0)
+	if err != nil {
+		t.Fatal(err)
+	} else if x := string(c2.text().Bytes()); x != "x alpha gamma" {
+		t.Fatalf("unexpected %q", x)
+	}
+	check(c2, 8, 8, 8)
+	// undo restore marks collapsed by the delete.
+	check(c2.UndoChange(1), 8, 8, 13)
+	check(c2.UndoChange(2), 6, 6, 11)
+	check(ebuf.RedoChange(2), 8, 8, 8)
+
+	// mark set in a later change is seen by earlier changes.
+	if err := c2.SetMark("g", 8, GravityRight); err != nil {
+		t.Fatal(err)
+	} else if x, _ := ebuf.Mark("g"); x != 11 {
+		t.Fatalf("expected %v, got %v", 11, x)
+	} else if x, _ := c1.Mark("g"); x != 13 {
+		t.Fatalf("expected %v, got %v", 13, x)
+	}
+
+	// insert at the mark, gravity decide the side.
+	ebuf.SetSelections(Selection{6, 6})
+	c3, _ := ebuf.InsertText([]rune("new "))
+	check(c3, 6, 10, 15)
+	// changes appended as a new buffer.
+	c4, _ := ebuf.AppendChange(0, NewRopebuffer([]byte("alpha BETA gamma"), 8))
+	check(c4, 6, 10, 11)
+
+	if x := ebuf.MarkNames(); !reflect.DeepEqual(x, []string{"b", "g"}) {
+		t.Fatalf("unexpected %v", x)
+	}
+	c4.DeleteMark("g")
+	if _, ok := c2.Mark("g"); ok {
+		t.Fatalf("unexpected mark")
+	}
+	if err := c3.MoveAnchor(gamma, 0); err != nil {
+		t.Fatal(err)
+	} else if x := c2.Position(gamma); x != 0 {
+		t.Fatalf("expected %v, got %v", 0, x)
+	}
+	other := NewEditBuffer(0, NewRopebuffer([]byte(text), 8), nil)
+	if x := other.Position(gamma); x != -1 {
+		t.Fatalf("expected %v, got %v", -1, x)
+	}
+
+	// every change is resolved from its parent.
+	end, _ := ebuf.NewAnchor(16, GravityLeft)
+	change := ebuf
+	for i := int64(1); i <= 100; i++ {
+		change, _ = change.InsertText([]rune("y"))
+		if x := change.Position(end); x != 16+i {
+			t.Fatalf("expected %v, got %v", 16+i, x)
+		} else if n := int64(len(end.resolved)); n != i+1 {
+			t.Fatalf("expected %v, got %v", i+1, n)
+		}
+	}
+	if x := ebuf.RedoChange(50).Position(end); x != 66 {
+		t.Fatalf("expected %v, got %v", 66, x)
+	}
+	// edits that do not fit the text are not clamped.
+	change.edits = []textEdit{{0, 0, []rune("zzzz"), []rune{}}}
+	ebuf.SetMark("e", 16, GravityLeft)
+	if x, ok := change.Mark("e"); ok || x != -1 {
+		t.Fatalf("expected %v, got %v", -1, x)
+	}
+}
+
+func TestEditBufferChangeTree(t *testing.T) {
+	ebuf := NewEditBuffer(0, NewRopebuffer([]byte("abc"), 8), nil)
+	text := func(change *EditBuffer) string {
+		return string(change.text().Bytes())
+	}
+	// seq 1, 2 in first branch, 3, 4 in second branch.
+	c1, _ := ebuf.InsertText([]rune("1"))
+	c2, _ := c1.InsertText([]rune("2"))
+	c3, _ := ebuf.InsertText([]rune("x"))
+	c4, _ := c3.InsertText([]rune("y"))
+	changes := []*EditBuffer{ebuf, c1, c2, c3, c4}
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, change := range changes {
+		if x := change.Seq(); x != int64(i) {
+			t.Fatalf("expected %v, got %v", i, x)
+		}
+		change.time = base.Add(time.Duration(i) * time.Minute)
+	}
+	if x := ebuf.Branches(); !reflect.DeepEqual(x, []*EditBuffer{c1, c3}) {
+		t.Fatalf("unexpected branches %v", len(x))
+	} else if x := text(ebuf.RedoChange(2)); x != "xyabc" {
+		t.Fatalf("unexpected %q", x)
+	}
+	// redo follow the selected branch.
+	if err := ebuf.SelectBranch(0); err != nil {
+		t.Fatal(err)
+	} else if x := text(ebuf.RedoChange(2)); x != "12abc" {
+		t.Fatalf("unexpected %q", x)
+	} else if err := ebuf.SelectBranch(2); err != ErrorIndexOutofbound {
+		t.Fatalf("expected %v, got %v", ErrorIndexOutofbound, err)
+	}
+	// redo come back to where undo started.
+	ebuf.SelectBranch(0)
+	if x := c4.UndoChange(2).RedoChange(2); x != c4 {
+		t.Fatalf("expected %v, got %v", c4.Seq(), x.Seq())
+	}
+
+	// chronological order across branches.
+	if x := c3.EarlierChange(1); x != c2 {
+		t.Fatalf("expected %v, got %v", c2.Seq(), x.Seq())
+	} else if x := c2.LaterChange(1); x != c3 {
+		t.Fatalf("expected %v, got %v", c3.Seq(), x.Seq())
+	} else if x := c2.LaterChange(10); x != c4 {
+		t.Fatalf("expected %v, got %v", c4.Seq(), x.Seq())
+	} else if x := c2.EarlierChange(10); x != ebuf {
+		t.Fatalf("expected %v, got %v", ebuf.Seq(), x.Seq())
+	} else if x := ebuf.RedoChange(2); x != c4 {
+		t.Fatalf("expected %v, got %v", c4.Seq(), x.Seq())
+	}
+
+	// time travel.
+	testcases := []struct {
+		from    *EditBuffer
+		earlier bool
+		d       time.Duration
+		ref     *EditBuffer
+	}{
+		{c4, true, 2 * time.Minute, c2},
+		{c4, true, 90 * time.Second, c2},
+		{c4, true, time.Hour, ebuf},
+		{c1, false, 2 * time.Minute, c3},
+		{c1, false, 150 * time.Second, c3},
+		{c1, false, time.Hour, c4},
+		{c2, false, 0, c2},
+	}
+	for i, tcase := range testcases {
+		x := tcase.from.LaterTime(tcase.d)
+		if tcase.earlier {
+			x = tcase.from.EarlierTime(tcase.d)
+		}
+		if x != tcase.ref {
+			t.Fatalf("%v expected %v, got %v", i, tcase.ref.Seq(), x.Seq())
+		}
+	}
+	if x := ebuf.RedoChange(1); x != c1 {
+		t.Fatalf("expected %v, got %v", c1.Seq(), x.Seq())
+	}
+}
+
+func TestEditBufferDeltas(t *testing.T) {
+	seed := int64(20)
+	rnd := rand.New(rand.NewSource(seed))
+	text := strings.Repeat("hello world\n", 100)
+	ebuf := NewEditBuffer(0, NewLinearBuffer([]byte(text)), nil)
+
+	// a change is a checkpoint once changeCheckpoint changes are
+	// chained to the last checkpoint on its path, refer newChange.
+	// Every edit here churns a single rune, hence churn never exceeds
+	// chain, which stays well below the buffer length, and the other
+	// rule does not apply. Undo forks branches from ancestors, whose
+	// chain is carried over, so checkpoints are counted on the tree
+	// rather than as no. of changes / changeCheckpoint.
+	chains, expected := map[*EditBuffer]int64{ebuf: 0}, int64(1)
+
+	// 10k edits, with a few undos to grow branches.
+	refs := map[*EditBuffer]string{ebuf: text}
+	change, maxlen := ebuf, int64(len(text))
+	for i := 0; i < 10000; i++ {
+		rCur := rnd.Int63n(change.text().Length() + 1)
+		change.SetSelections(Selection{rCur, rCur})
+		parent := change
+		switch rnd.Intn(10) {
+		case 0:
+			change = change.UndoChange(rnd.Int63n(4))
+			continue
+		case 1, 2, 3:
+			change, _ = change.DeleteText(1)
+		default:
+			change, _ = change.InsertText([]rune("x"))
+		}
+		if change != parent {
+			if chains[change] = chains[parent] + 1; chains[change] >= changeCheckpoint {
+				chains[change], expected = 0, expected+1
+			}
+		}
+		if l := change.text().Length(); l > maxlen {
+			maxlen = l
+		}
+		if rnd.Intn(50) == 0 {
+			refs[change] = string(change.text().Bytes())
+		}
+	}
+
+	stats, _ := ebuf.Stats()
+	checkpoints, cached := stats["checkpoints"].(int64), stats["cached"].(int64)
+	n := stats["changes"].(int64)
+	if checkpoints != expected {
+		t.Fatalf("seed %v, expected %v checkpoints, got %v", seed, expected, checkpoints)
+	} else if cached > changeCacheSize {
+		t.Fatalf("seed %v, too many cached %v", seed, cached)
+	} else if x := stats["bufferRunes"].(int64); x > (checkpoints+cached)*maxlen {
+		t.Fatalf("seed %v, unexpected bufferRunes %v", seed, x)
+	}
+	// every change is rebuilt from its deltas.
+	for change, ref := range refs {
+		if x := string(change.text().Bytes()); x != ref {
+			t.Fatalf("seed %v, change %v expected %q, got %q", seed, change.Seq(), ref, x)
+		}
+	}
+	for seq := int64(0); seq <= n-1; seq += 7 {
+		change := ebuf.tree.change(seq, false)
+		if change.parent == nil {
+			continue
+		}
+		parent := string(change.parent.text().Bytes())
+		undo := applyEdits(change.text(), change.undoEdits())
+		if x := string(undo.Bytes()); x != parent {
+			t.Fatalf("seed %v, change %v expected %q, got %q", seed, seq, parent, x)
+		}
+	}
+}
+
+func TestEditBufferPrune(t *testing.T) {
+	typeText := func(change *EditBuffer, text string) *EditBuffer {
+		for _, r := range text {
+			change, _ = change.InsertText([]rune{r})
+		}
+		return change
+	}
+
+	// limit no. of changes, squashing the oldest.
+	ebuf := NewEditBuffer(0, NewRopebuffer([]byte("> "), 8), nil)
+	ebuf.SetMark("end", 2, GravityRight)
+	ebuf.SetRetention(10, 0, 0)
+	ebuf.MoveDot(2)
+	change := typeText(ebuf, "abcdefghijklmnopqrstuvwxyz")
+	stats, _ := change.Stats()
+	if x := stats["changes"].(int64); x != 10 {
+		t.Fatalf("expected %v, got %v", 10, x)
+	} else if x := stats["discarded"].(int64); x != 17 {
+		t.Fatalf("expected %v, got %v", 17, x)
+	}
+	root := change.UndoChange(100)
+	if x := string(root.text().Bytes()); x != "> abcdefghijklmnopq" {
+		t.Fatalf("unexpected %q", x)
+	} else if err := root.CanUndo(); err != ErrorOldestChange {
+		t.Fatalf("expected %v, got %v", ErrorOldestChange, err)
+	} else if err := change.CanUndo(); err != nil {
+		t.Fatal(err)
+	} else if err := change.CanRedo(); err != ErrorLatestChange {
+		t.Fatalf("expected %v, got %v", ErrorLatestChange, err)
+	} else if x, _ := root.Mark("end"); x != 19 {
+		t.Fatalf("expected %v, got %v", 19, x)
+	} else if x, _ := change.Mark("end"); x != 28 {
+		t.Fatalf("expected %v, got %v", 28, x)
+	} else if x := root.EarlierChange(5); x != root {
+		t.Fatalf("expected %v, got %v", root.Seq(), x.Seq())
+	} else if x := root.LaterChange(100); x != change {
+		t.Fatalf("expected %v, got %v", change.Seq(), x.Seq())
+	}
+
+	// old branches are dropped before squashing the path.
+	ebuf = NewEditBuffer(0, NewRopebuffer([]byte(""), 8), nil)
+	old := typeText(ebuf, "old")
+	old.SetMark("old", 3, GravityLeft)
+	change = typeText(ebuf, "new")
+	ebuf.SetRetention(4, 0, 0)
+	if x, err := change.Prune(); err != nil {
+		t.Fatal(err)
+	} else if x["branches"].(int64) != 1 || x["squashed"].(int64) != 0 {
+		t.Fatalf("unexpected %v", x)
+	} else if x["changes"].(int64) != 3 {
+		t.Fatalf("unexpected %v", x)
+	} else if x := len(ebuf.Branches()); x != 1 {
+		t.Fatalf("expected %v, got %v", 1, x)
+	} else if x, _ := change.Mark("old"); x != 0 {
+		t.Fatalf("expected %v, got %v", 0, x)
+	} else if x := change.UndoChange(3); x != ebuf {
+		t.Fatalf("unexpected undo")
+	} else if x, _ := change.Prune(); x["changes"].(int64) != 0 {
+		t.Fatalf("unexpected %v", x)
+	}
+
+	// byte budget.
+	ebuf = NewEditBuffer(0, NewRopebuffer([]byte("0123456789"), 8), nil)
+	ebuf.SetRetention(0, 100, 0)
+	change = typeText(ebuf, strings.Repeat("x", 50))
+	if stats, _ := change.Stats(); stats["bytes"].(int64) > 100 {
+		t.Fatalf("unexpected %v", stats)
+	} else if stats["changes"].(int64) != 26 || stats["discarded"].(int64) != 25 {
+		t.Fatalf("unexpected %v", stats)
+	} else if x := string(change.text().Bytes()); x != strings.Repeat("x", 50)+"0123456789" {
+		t.Fatalf("unexpected %q", x)
+	}
+
+	// random edits, across branches, with a small limit.
+	seed := rand.Int63()
+	rnd := rand.New(rand.NewSource(seed))
+	ebuf = NewEditBuffer(0, NewLinearBuffer([]byte("hello world")), nil)
+	ebuf.SetRetention(50, 0, 0)
+	refs, change := make(map[*EditBuffer]string), ebuf
+	for i := 0; i < 1000; i++ {
+		rCur := rnd.Int63n(change.text().Length() + 1)
+		change.SetSelections(Selection{rCur, rCur})
+		switch rnd.Intn(10) {
+		case 0:
+			change = change.UndoChange(rnd.Int63n(10))
+		case 1:
+			change = change.EarlierChange(rnd.Int63n(10))
+		case 2, 3:
+			change, _ = change.DeleteText(1)
+		default:
+			change, _ = change.InsertText([]rune("x"))
+		}
+		refs[change] = string(change.text().Bytes())
+	}
+	bytes := int64(0)
+	for _, x := range change.tree.changes {
+		bytes += x.memory()
+		if x.text(); x.tree != change.tree {
+			t.Fatalf("seed %v, unexpected tree", seed)
+		}
+	}
+	if stats, _ := change.Stats(); stats["bytes"].(int64) != bytes {
+		t.Fatalf("seed %v, expected %v, got %v", seed, bytes, stats["bytes"])
+	} else if stats["changes"].(int64) > 50 {
+		t.Fatalf("seed %v, unexpected %v", seed, stats)
+	}
+	for _, x := range change.tree.changes {
+		if ref, ok := refs[x]; ok && string(x.text().Bytes()) != ref {
+			t.Fatalf("seed %v, change %v expected %q", seed, x.Seq(), ref)
+		}
+	}
+
+	// age limit.
+	ebuf = NewEditBuffer(0, NewRopebuffer([]byte(""), 8), nil)
+	change = typeText(ebuf, "abcdef")
+	now := time.Now()
+	for x := change; x != nil; x = x.parent {
+		x.time = now.Add(time.Duration(x.Seq()-6) * time.Hour).Add(-time.Minute)
+	}
+	ebuf.SetRetention(0, 0, 150*time.Minute)
+	if x, _ := change.Prune(); x["squashed"].(int64) != 3 {
+		t.Fatalf("unexpected %v", x)
+	} else if x := string(change.UndoChange(10).text().Bytes()); x != "abc" {
+		t.Fatalf("unexpected %q", x)
+	}
+
+	// pruned changes are detached, and cannot be changed.
+	ebuf = NewEditBuffer(0, NewRopebuffer([]byte("> "), 8), nil)
+	ebuf.SetRetention(3, 0, 0)
+	change = typeText(ebuf, "abcdef")
+	stats, _ = change.Stats()
+	if _, err := ebuf.InsertText([]rune("y")); err != ErrorPrunedChange {
+		t.Fatalf("expected %v, got %v", ErrorPrunedChange, err)
+	} else if _, err := ebuf.Prune(); err != ErrorPrunedChange {
+		t.Fatalf("expected %v, got %v", ErrorPrunedChange, err)
+	} else if err := ebuf.CanRedo(); err != ErrorLatestChange {
+		t.Fatalf("expected %v, got %v", ErrorLatestChange, err)
+	} else if x, _ := change.Stats(); !reflect.DeepEqual(x, stats) {
+		t.Fatalf("expected %v, got %v", stats, x)
+	} else if x := int64(len(ebuf.tree.changes)); x != 3 {
+		t.Fatalf("expected %v, got %v", 3, x)
+	}
+	if change, err := change.InsertText([]rune("g")); err != nil {
+		t.Fatal(err)
+	} else if x := string(change.UndoChange(10).text().Bytes()); x != "abcde> " {
+		t.Fatalf("unexpected %q", x)
+	}
+}
+
+func TestEditBufferTransaction(t *testing.T) {
+	text := func(change *EditBuffer) string {
+		return string(change.text().Bytes())
+	}
+	ebuf := NewEditBuffer(0, NewRopebuffer([]byte("hello world"), 8), nil)
+	ebuf.SetMark("w", 6, GravityLeft)
+	if _, err := ebuf.Commit(); err != ErrorTransaction {
+		t.Fatalf("expected %v, got %v", ErrorTransaction, err)
+	}
+
+	ebuf.BeginTransaction()
+	change, _ := ebuf.InsertText([]rune("say "))
+	change.BeginTransaction() // nested
+	change.SetSelections(Selection{9, 9})
+	change, _ = change.DeleteText(1)
+	change, _ = change.InsertText([]rune(", "))
+	change, err := change.Commit()
+	if err != nil {
+		t.Fatal(err)
+	} else if x := text(change); x != "say hello, world" {
+		t.Fatalf("unexpected %q", x)
+	} else if x := text(change.UndoChange(1)); x != "say hello world" {
+		t.Fatalf("unexpected %q", x)
+	}
+	change.SetSelections(Selection{16, 16})
+	change, _ = change.InsertText([]rune("!"))
+	if change, err = change.Commit(); err != nil {
+		t.Fatal(err)
+	} else if x := text(change); x != "say hello, world!" {
+		t.Fatalf("unexpected %q", x)
+	} else if x := change.UndoChange(1); x != ebuf {
+		t.Fatalf("expected a single change")
+	} else if len(ebuf.Branches()) != 1 || change.Seq() != 6 {
+		t.Fatalf("unexpected change-tree")
+	} else if stats, _ := ebuf.Stats(); stats["changes"].(int64) != 2 {
+		t.Fatalf("unexpected %v", stats)
+	} else if x, _ := change.Mark("w"); x != 11 {
+		t.Fatalf("expected %v, got %v", 11, x)
+	} else if x := ebuf.RedoChange(1); x != change {
+		t.Fatalf("unexpected redo")
+	}
+
+	// abort discard the changes.
+	change.BeginTransaction()
+	x, _ := change.InsertText([]rune("x"))
+	x, _ = x.InsertText([]rune("y"))
+	if y, err := x.UndoChange(2).Abort(); err != nil {
+		t.Fatal(err)
+	} else if y != change || len(change.Branches()) != 0 {
+		t.Fatalf("unexpected abort")
+	}
+	// change outside the transaction.
+	change.BeginTransaction()
+	if _, err := ebuf.Commit(); err != ErrorTransaction {
+		t.Fatalf("expected %v, got %v", ErrorTransaction, err)
+	} else if x, err := change.Commit(); err != nil || x != change {
+		t.Fatalf("unexpected commit %v", err)
+	}
+}
+
+func TestEditBufferGrouping(t *testing.T) {
+	text := func(change *EditBuffer) string {
+		return string(change.text().Bytes())
+	}
+	typeText := func(change *EditBuffer, text string) *EditBuffer {
+		for _, r := range text {
+			change, _ = change.InsertText([]rune{r})
+		}
+		return change
+	}
+	ebuf := NewEditBuffer(0, NewRopebuffer([]byte(""), 8), nil)
+	ebuf.Configure(map[string]interface{}{"undogroup": "1h"})
+	change := typeText(ebuf, "hello world")
+	if x := text(change.UndoChange(1)); x != "hello " {
+		t.Fatalf("unexpected %q", x)
+	} else if x := change.UndoChange(2); x != ebuf {
+		t.Fatalf("unexpected %q", text(x))
+	}
+	// cursor jump.
+	change.MoveDot(-5)
+	change = typeText(change, "big ")
+	if x := text(change); x != "hello big world" {
+		t.Fatalf("unexpected %q", x)
+	} else if x := text(change.UndoChange(1)); x != "hello world" {
+		t.Fatalf("unexpected %q", x)
+	}
+	// pause.
+	change.time = change.time.Add(-2 * time.Hour)
+	change = typeText(change, "! ")
+	if x := text(change.UndoChange(1)); x != "hello big world" {
+		t.Fatalf("unexpected %q", x)
+	} else if stats, _ := change.Stats(); stats["changes"].(int64) != 5 {
+		t.Fatalf("unexpected %v", stats)
+	}
+	// grouping disabled.
+	change.SetGrouping(0)
+	change = typeText(change, "ab")
+	if x := text(change.UndoChange(1)); x != "hello big ! aworld" {
+		t.Fatalf("unexpected %q", x)
+	}
+}
+
+func TestEditBufferHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "editbhistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := dir + "/hello.txt"
+	ioutil.WriteFile(filename, []byte("ab\r\ncd\nef\r\n"), 0644)
+
+	text := func(change *EditBuffer) string {
+		return string(change.text().Bytes())
+	}
+	setts := map[string]interface{}{"undodir": dir + "/undo"}
+	open := func() *EditBuffer {
+		ebuf, err := NewEncodedFile(filename, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ebuf.Configure(setts)
+	}
+
+	// branches, marks and enough changes for a checkpoint.
+	ebuf := open()
+	if x, err := ebuf.ReadHistory(""); err != nil || x != ebuf {
+		t.Fatalf("unexpected %v", err)
+	}
+	c1, _ := ebuf.InsertText([]rune("1"))
+	c2, _ := ebuf.InsertText([]rune("x"))
+	c2.SetMark("a", 4, GravityLeft)
+	texts, change := []string{}, c2
+	for i := 0; i < changeCheckpoint+10; i++ {
+		change.MoveDot(1)
+		change, _ = change.InsertText([]rune{rune('a' + i%26)})
+		texts = append(texts, text(change))
+	}
+	change = change.UndoChange(5)
+	if err := change.Save(""); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := open().ReadHistory("")
+	if err != nil {
+		t.Fatal(err)
+	} else if x, ref := text(loaded), text(change); x != ref {
+		t.Fatalf("expected %q, got %q", ref, x)
+	} else if loaded.Seq() != change.Seq() || loaded.dot != change.dot {
+		t.Fatalf("expected %v, got %v", change.Seq(), loaded.Seq())
+	} else if !loaded.Timestamp().Equal(change.Timestamp()) {
+		t.Fatalf("expected %v, got %v", change.Timestamp(), loaded.Timestamp())
+	} else if !loaded.MixedLineEndings() {
+		t.Fatalf("expected mixed line endings")
+	}
+	for i := len(texts) - 1; i >= 0; i-- {
+		if x := text(loaded.tree.change(c2.Seq()+int64(i)+1, false)); x != texts[i] {
+			t.Fatalf("%v expected %q, got %q", i, texts[i], x)
+		}
+	}
+	if x := text(loaded.RedoChange(5)); x != texts[len(texts)-1] {
+		t.Fatalf("unexpected %q", x)
+	} else if x := loaded.UndoChange(1000); x.Seq() != 0 || text(x) != text(ebuf) {
+		t.Fatalf("unexpected %v %q", x.Seq(), text(x))
+	} else if branches := x.Branches(); len(branches) != 2 {
+		t.Fatalf("unexpected %v", len(branches))
+	} else if text(branches[0]) != text(c1) || x.branch != 1 {
+		t.Fatalf("unexpected %q %v", text(branches[0]), x.branch)
+	} else if pos, ok := loaded.tree.change(c2.Seq(), false).Mark("a"); !ok || pos != 4 {
+		t.Fatalf("unexpected %v", pos)
+	}
+	stats, _ := loaded.Stats()
+	if x := stats["changes"].(int64); x != int64(len(texts)+3) {
+		t.Fatalf("expected %v, got %v", len(texts)+3, x)
+	} else if x := stats["checkpoints"].(int64); x < 3 {
+		t.Fatalf("unexpected %v", stats)
+	}
+
+	// file changed outside the editor.
+	ioutil.WriteFile(filename, []byte("changed\n"), 0644)
+	ebuf = open()
+	if x, err := ebuf.ReadHistory(""); err != ErrorStaleHistory {
+		t.Fatalf("expected %v, got %v", ErrorStaleHistory, err)
+	} else if x != ebuf || text(x) != "changed\n" {
+		t.Fatalf("unexpected %q", text(x))
+	} else if err := x.CanUndo(); err != ErrorOldestChange {
+		t.Fatalf("expected %v, got %v", ErrorOldestChange, err)
+	}
+	if _, err := NewEditBuffer(0, ebuf.text(), nil).ReadHistory(""); err != ErrorUndoFile {
+		t.Fatalf("expected %v, got %v", ErrorUndoFile, err)
+	}
+
+	// edits and marks that don't fit the text are stale as well.
+	change, _ = ebuf.InsertText([]rune("hello "))
+	change.SetMark("b", 2, GravityLeft)
+	if err := change.Save(""); err != nil {
+		t.Fatal(err)
+	}
+	undofile, _ := UndoFile(dir+"/undo", filename)
+	data, _ := ioutil.ReadFile(undofile)
+	tampers := []func(hist *history){
+		func(hist *history) { hist.Changes[1].Edits[0].Start = 100 },
+		func(hist *history) { hist.Changes[1].Edits[0].End = 100 },
+		func(hist *history) { hist.Changes[1].Edits[0].Old = []rune("x") },
+		func(hist *history) { // unsorted edits.
+			edit := historyEdit{Start: 5, End: 5, Text: []rune("x"), Old: []rune{}}
+			edits := hist.Changes[1].Edits
+			hist.Changes[1].Edits = append([]historyEdit{edit}, edits...)
+		},
+		func(hist *history) { hist.Marks["b"] = historyMark{Seq: 1, Cursor: 100} },
+	}
+	for i, tamper := range tampers {
+		var hist history
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&hist); err != nil {
+			t.Fatal(err)
+		}
+		tamper(&hist)
+		var buf bytes.Buffer
+		gob.NewEncoder(&buf).Encode(&hist)
+		ioutil.WriteFile(undofile, buf.Bytes(), 0600)
+		ebuf = open()
+		if x, err := ebuf.ReadHistory(""); err != ErrorStaleHistory {
+			t.Fatalf("%v expected %v, got %v", i, ErrorStaleHistory, err)
+		} else if x != ebuf || text(x) != "hello changed\n" {
+			t.Fatalf("%v unexpected %q", i, text(x))
+		}
+	}
+	ioutil.WriteFile(undofile, data, 0600)
+	if x, err := open().ReadHistory(""); err != nil {
+		t.Fatal(err)
+	} else if pos, ok := x.Mark("b"); !ok || pos != 2 || text(x) != "hello changed\n" {
+		t.Fatalf("unexpected %v %q", pos, text(x))
+	}
+}
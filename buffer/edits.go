@@ -0,0 +1,235 @@
+package buffer
+
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// textEdit replace runes [start, end) with text, old is the
+// replaced text, when recorded, refer recordEdits.
+type textEdit struct {
+	start, end int64
+	text       []rune
+	old        []rune
+}
+
+// recordEdits remember the text replaced by edits in buffer, so
+// that edits can be reversed without buffer.
+func recordEdits(buffer Buffer, edits []textEdit) []textEdit {
+	for i, edit := range edits {
+		edits[i].old = []rune{}
+		if edit.end > edit.start {
+			edits[i].old = buffer.Slice(edit.start, edit.end-edit.start).Runes()
+		}
+	}
+	return edits
+}
+
+// deltaRunesIn return the no. of runes held by edits.
+func deltaRunesIn(edits []textEdit) (n int64) {
+	for _, edit := range edits {
+		n += int64(len(edit.text) + len(edit.old))
+	}
+	return n
+}
+
+// deltaLength return the change in length of buffer, by edits.
+func deltaLength(edits []textEdit) (n int64) {
+	for _, edit := range edits {
+		n += int64(len(edit.text)) - (edit.end - edit.start)
+	}
+	return n
+}
+
+// applyEdits to buffer, edits are sorted by start and don't
+// overlap, return the new buffer.
+func applyEdits(buffer Buffer, edits []textEdit) Buffer {
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		if edit.end > edit.start {
+			buffer = buffer.Delete(edit.start, edit.end-edit.start)
+		}
+		if len(edit.text) > 0 {
+			buffer = buffer.Insert(edit.start, edit.text)
+		}
+	}
+	return buffer
+}
+
+// validEdits check that edits are sorted by start, don't overlap,
+// fall within length runes and record the text they replace, which
+// is required by applyEdits and reverseEdits.
+func validEdits(edits []textEdit, length int64) bool {
+	end := int64(0)
+	for _, edit := range edits {
+		if edit.start < end || edit.end < edit.start || edit.end > length {
+			return false
+		} else if int64(len(edit.old)) != edit.end-edit.start {
+			return false
+		}
+		end = edit.end
+	}
+	return true
+}
+
+// editRanges return {start, end} pairs of the new text, for each
+// edit, after applying edits.
+func editRanges(edits []textEdit) []int64 {
+	ranges, shift := make([]int64, 0, 2*len(edits)), int64(0)
+	for _, edit := range edits {
+		n := int64(len(edit.text))
+		ranges = append(ranges, edit.start+shift, edit.start+shift+n)
+		shift += n - (edit.end - edit.start)
+	}
+	return ranges
+}
+
+// shiftCursor return the position of rCur after edits, sorted by
+// start and without overlap. Cursor inside a replaced text, or at
+// the position of an insert, move to the start of the new text, or
+// to its end if right is true.
+func shiftCursor(rCur int64, edits []textEdit, right bool) int64 {
+	shift := int64(0)
+	for _, edit := range edits {
+		n := int64(len(edit.text))
+		switch {
+		case rCur > edit.end || (rCur == edit.end && edit.end > edit.start):
+			shift += n - (edit.end - edit.start)
+		case rCur < edit.start:
+			return rCur + shift
+		case right:
+			return edit.start + n + shift
+		default:
+			return edit.start + shift
+		}
+	}
+	return rCur + shift
+}
+
+// reverseEdits return the edits that undo recorded edits, in the
+// coordinates of the new buffer.
+func reverseEdits(edits []textEdit) []textEdit {
+	undo, shift := make([]textEdit, 0, len(edits)), int64(0)
+	for _, edit := range edits {
+		n := int64(len(edit.text))
+		start := edit.start + shift
+		undo = append(undo, textEdit{start, start + n, edit.old, edit.text})
+		shift += n - (edit.end - edit.start)
+	}
+	return undo
+}
+
+// diffEdits return a single recorded edit that change old into
+// new, by skipping the common prefix and suffix.
+func diffEdits(old, new Buffer) []textEdit {
+	lo, ln := old.Length(), new.Length()
+	prefix := commonRunes(old.StreamFrom(0), new.StreamFrom(0))
+	if prefix == lo && prefix == ln {
+		return []textEdit{}
+	}
+	suffix := commonRunes(old.BackStreamFrom(lo), new.BackStreamFrom(ln))
+	if suffix > lo-prefix {
+		suffix = lo - prefix
+	}
+	if suffix > ln-prefix {
+		suffix = ln - prefix
+	}
+	text := []rune{}
+	if ln-suffix > prefix {
+		text = new.Slice(prefix, ln-suffix-prefix).Runes()
+	}
+	edits := []textEdit{{prefix, lo - suffix, text, nil}}
+	return recordEdits(old, edits)
+}
+
+// commonRunes return the no. of runes read alike from r1 and r2,
+// both readers are closed.
+func commonRunes(r1, r2 RuneReader) (n int64) {
+	if r1 == nil || r2 == nil {
+		return 0
+	}
+	defer r1.Close()
+	defer r2.Close()
+	for {
+		x, _, err1 := r1.ReadRune()
+		y, _, err2 := r2.ReadRune()
+		if err1 != nil || err2 != nil || x != y {
+			return n
+		}
+		n++
+	}
+}
+
+// segment of text, either kept from the original text, [start,
+// end), or new text.
+type segment struct {
+	start, end int64
+	text       []rune
+}
+
+func (seg segment) length() int64 {
+	if seg.text != nil {
+		return int64(len(seg.text))
+	}
+	return seg.end - seg.start
+}
+
+// composeEdits return edits, on a text of length runes, that have
+// the same effect as applying each list of edits in order. Edits
+// are not recorded, refer recordEdits.
+func composeEdits(length int64, lists ...[]textEdit) []textEdit {
+	segs := []segment{{0, length, nil}}
+	for _, edits := range lists {
+		for k := len(edits) - 1; k >= 0; k-- {
+			edit := edits[k]
+			var i, j int
+			segs, i = splitSegments(segs, edit.start)
+			segs, j = splitSegments(segs, edit.end)
+			tail := append([]segment{}, segs[j:]...)
+			if segs = segs[:i]; len(edit.text) > 0 {
+				segs = append(segs, segment{text: edit.text})
+			}
+			segs = append(segs, tail...)
+		}
+	}
+
+	edits, pos, text := make([]textEdit, 0), int64(0), []rune{}
+	for _, seg := range segs {
+		if seg.text != nil {
+			text = append(text, seg.text...)
+			continue
+		}
+		if seg.start > pos || len(text) > 0 {
+			edits = append(edits, textEdit{pos, seg.start, text, nil})
+		}
+		pos, text = seg.end, []rune{}
+	}
+	if pos < length || len(text) > 0 {
+		edits = append(edits, textEdit{pos, length, text, nil})
+	}
+	return edits
+}
+
+// splitSegments at pos, return segments and the index of segment
+// starting at pos.
+func splitSegments(segs []segment, pos int64) ([]segment, int) {
+	off := int64(0)
+	for i, seg := range segs {
+		n := seg.length()
+		if pos == off {
+			return segs, i
+		} else if pos < off+n {
+			k := pos - off
+			left := segment{seg.start, seg.start + k, nil}
+			right := segment{seg.start + k, seg.end, nil}
+			if seg.text != nil {
+				left = segment{text: seg.text[:k:k]}
+				right = segment{text: seg.text[k:]}
+			}
+			segs = append(segs[:i+1], append([]segment{right}, segs[i+1:]...)...)
+			segs[i] = left
+			return segs, i + 1
+		}
+		off += n
+	}
+	return segs, len(segs)
+}
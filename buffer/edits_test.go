@@ -0,0 +1,123 @@
+package buffer
+
+import "math/rand"
+import "reflect"
+import "testing"
+
+func TestShiftCursor(t *testing.T) {
+	edits := []textEdit{
+		{2, 2, []rune("xy"), nil},  // insert
+		{4, 6, nil, nil},           // delete
+		{8, 9, []rune("abc"), nil}, // replace
+	}
+	testcases := []struct {
+		rCur, left, right int64
+	}{
+		{0, 0, 0}, {2, 2, 4}, {3, 5, 5}, {4, 6, 6}, {5, 6, 6}, {6, 6, 6},
+		{7, 7, 7}, {8, 8, 11}, {9, 11, 11}, {10, 12, 12},
+	}
+	for _, tcase := range testcases {
+		if x := shiftCursor(tcase.rCur, edits, false); x != tcase.left {
+			t.Fatalf("%v expected %v, got %v", tcase.rCur, tcase.left, x)
+		} else if x := shiftCursor(tcase.rCur, edits, true); x != tcase.right {
+			t.Fatalf("%v expected %v, got %v", tcase.rCur, tcase.right, x)
+		}
+	}
+}
+
+func TestReverseEdits(t *testing.T) {
+	text := "the quick brown fox"
+	edits := []textEdit{
+		{0, 3, []rune("a"), nil},
+		{4, 4, []rune("very "), nil},
+		{10, 16, nil, nil},
+		{19, 19, []rune("!"), nil},
+	}
+	rb := NewRopebuffer([]byte(text), 4)
+	edits = recordEdits(rb, edits)
+	if x := string(edits[2].old); x != "brown " {
+		t.Fatalf("unexpected %q", x)
+	} else if x := deltaRunesIn(edits); x != 16 {
+		t.Fatalf("expected %v, got %v", 16, x)
+	}
+	buffer := applyEdits(rb, edits)
+	if x := string(buffer.Bytes()); x != "a very quick fox!" {
+		t.Fatalf("unexpected %q", x)
+	}
+	undo := reverseEdits(edits)
+	if x := string(applyEdits(buffer, undo).Bytes()); x != text {
+		t.Fatalf("expected %q, got %q", text, x)
+	}
+	ref := []int64{0, 1, 2, 7, 13, 13, 16, 17}
+	if x := editRanges(edits); !reflect.DeepEqual(x, ref) {
+		t.Fatalf("expected %v, got %v", ref, x)
+	}
+	// cursors round trip, except inside deleted text.
+	for rCur := int64(0); rCur <= rb.Length(); rCur++ {
+		x := shiftCursor(shiftCursor(rCur, edits, false), undo, false)
+		if (rCur < 1 || rCur > 2) && (rCur < 11 || rCur > 16) && x != rCur {
+			t.Fatalf("expected %v, got %v", rCur, x)
+		}
+	}
+}
+
+func TestDiffEdits(t *testing.T) {
+	testcases := []struct {
+		old, new string
+		edits    []textEdit
+	}{
+		{"hello world", "hello world", []textEdit{}},
+		{"hello world", "hello there world", []textEdit{{6, 6, []rune("there "), []rune{}}}},
+		{"hello world", "hello", []textEdit{{5, 11, []rune{}, []rune(" world")}}},
+		{"aaaa", "aa", []textEdit{{2, 4, []rune{}, []rune("aa")}}},
+		{"中文字", "中国字", []textEdit{{1, 2, []rune("国"), []rune("文")}}},
+		{"", "new", []textEdit{{0, 0, []rune("new"), []rune{}}}},
+		{"old", "", []textEdit{{0, 3, []rune{}, []rune("old")}}},
+	}
+	for _, tcase := range testcases {
+		old := NewRopebuffer([]byte(tcase.old), 4)
+		new := NewRopebuffer([]byte(tcase.new), 4)
+		edits := diffEdits(old, new)
+		if !reflect.DeepEqual(edits, tcase.edits) {
+			t.Fatalf("%q expected %v, got %v", tcase.new, tcase.edits, edits)
+		} else if x := string(applyEdits(old, edits).Bytes()); x != tcase.new {
+			t.Fatalf("expected %q, got %q", tcase.new, x)
+		}
+	}
+}
+
+func TestComposeEdits(t *testing.T) {
+	seed := rand.Int63()
+	rnd := rand.New(rand.NewSource(seed))
+	for i := 0; i < 200; i++ {
+		rb := NewRopebuffer([]byte("the quick brown fox"), 4)
+		buffer, lists := Buffer(rb), make([][]textEdit, 0)
+		for j := rnd.Intn(6); j >= 0; j-- {
+			edits, pos := make([]textEdit, 0), int64(0)
+			for pos < buffer.Length() && rnd.Intn(3) > 0 {
+				start := pos + rnd.Int63n(buffer.Length()-pos+1)
+				end := start + rnd.Int63n(buffer.Length()-start+1)
+				text := []rune("xyz"[:rnd.Intn(4)])
+				edits = append(edits, textEdit{start, end, text, nil})
+				pos = end + 1
+			}
+			buffer = applyEdits(buffer, edits)
+			lists = append(lists, edits)
+		}
+		edits := composeEdits(rb.Length(), lists...)
+		ref, x := string(buffer.Bytes()), string(applyEdits(rb, edits).Bytes())
+		if x != ref {
+			t.Fatalf("seed %v, expected %q, got %q", seed, ref, x)
+		}
+		for k := 1; k < len(edits); k++ {
+			if edits[k].start <= edits[k-1].end {
+				t.Fatalf("seed %v, overlapping edits %v", seed, edits)
+			}
+		}
+	}
+	edits := composeEdits(5, []textEdit{{1, 1, []rune("ab"), nil}}, []textEdit{{2, 3, nil, nil}})
+	ref := []textEdit{{1, 1, []rune("a"), nil}}
+	if !reflect.DeepEqual(edits, ref) {
+		t.Fatalf("expected %v, got %v", ref, edits)
+	}
+}
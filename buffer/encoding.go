@@ -0,0 +1,288 @@
+package buffer
+
+import "os"
+import "bytes"
+import "strings"
+import "io/ioutil"
+import "unicode/utf8"
+import "path/filepath"
+import "fmt"
+
+import "golang.org/x/text/encoding"
+import "golang.org/x/text/encoding/charmap"
+import "golang.org/x/text/encoding/japanese"
+import "golang.org/x/text/encoding/unicode"
+
+var _ = fmt.Sprintf("dummy")
+
+// DefaultEncoding is used for new files and for files that don't
+// look like any other encoding.
+const DefaultEncoding = "utf-8"
+
+// Encoding describes how a file is converted to and from the
+// utf8 text held in buffers.
+type Encoding struct {
+	Name  string            // canonical name, like "utf-16le".
+	BOM   bool              // file starts with a byte order mark.
+	bom   []byte            // byte order mark for the encoding.
+	codec encoding.Encoding // nil for utf-8.
+}
+
+// encodings registered by canonical name and aliases.
+var encodings = map[string]*Encoding{}
+
+func init() {
+	RegisterEncoding("utf-8", nil, []byte{0xef, 0xbb, 0xbf}, "utf8")
+	RegisterEncoding(
+		"utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+		[]byte{0xff, 0xfe}, "utf16le")
+	RegisterEncoding(
+		"utf-16be", unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+		[]byte{0xfe, 0xff}, "utf16be")
+	RegisterEncoding(
+		"shift-jis", japanese.ShiftJIS, nil, "shift_jis", "sjis", "cp932")
+	RegisterEncoding(
+		"latin-1", charmap.ISO8859_1, nil, "latin1", "iso-8859-1")
+}
+
+// RegisterEncoding add an encoding, identified by name and its
+// aliases, to the list of encodings that can be used to load and
+// save files. A nil codec means utf-8, bom is the byte order mark
+// for the encoding, nil if it has none.
+func RegisterEncoding(
+	name string, codec encoding.Encoding, bom []byte, aliases ...string) {
+
+	enc := &Encoding{Name: name, bom: bom, codec: codec}
+	for _, alias := range append([]string{name}, aliases...) {
+		encodings[strings.ToLower(alias)] = enc
+	}
+}
+
+// LookupEncoding return the encoding registered as name, a "-bom"
+// suffix to name selects the byte order mark on save.
+func LookupEncoding(name string) (*Encoding, error) {
+	name, bom := strings.ToLower(name), false
+	if strings.HasSuffix(name, "-bom") {
+		name, bom = strings.TrimSuffix(name, "-bom"), true
+	}
+	enc, ok := encodings[name]
+	if !ok || (bom && enc.bom == nil) {
+		return nil, ErrorUnknownEncoding
+	}
+	x := *enc
+	x.BOM = bom
+	return &x, nil
+}
+
+// DetectEncoding guess the encoding for file content bs, byte
+// order mark takes precedence, otherwise bs is checked for utf-16
+// by the position of NUL bytes, for utf-8 and for Shift-JIS,
+// falling back to latin-1. Binary content is read as utf-8, where
+// invalid bytes are held as raw-runes.
+func DetectEncoding(bs []byte) *Encoding {
+	for _, name := range []string{"utf-8", "utf-16le", "utf-16be"} {
+		enc, _ := LookupEncoding(name)
+		if bytes.HasPrefix(bs, enc.bom) {
+			enc.BOM = true
+			return enc
+		}
+	}
+	var name string
+	switch utf16 := guessUTF16(bs); {
+	case utf16 != "":
+		name = utf16
+	case utf8.Valid(bs), isBinary(bs):
+		name = DefaultEncoding
+	case isShiftJIS(bs):
+		name = "shift-jis"
+	default:
+		name = "latin-1"
+	}
+	enc, _ := LookupEncoding(name)
+	return enc
+}
+
+// String implement fmt.Stringer interface.
+func (enc *Encoding) String() string {
+	if enc.BOM {
+		return enc.Name + "-bom"
+	}
+	return enc.Name
+}
+
+// Decode file content bs to utf8 text, stripping the byte order
+// mark if enc.BOM is set.
+func (enc *Encoding) Decode(bs []byte) ([]byte, error) {
+	if enc.BOM {
+		bs = bytes.TrimPrefix(bs, enc.bom)
+	}
+	if enc.codec == nil {
+		return bs, nil
+	}
+	return enc.codec.NewDecoder().Bytes(bs)
+}
+
+// Encode utf8 text to file content, prefixed with byte order mark
+// if enc.BOM is set. Return ErrorUnrepresentable if text has
+// characters not supported by the encoding, refer Unrepresentable.
+func (enc *Encoding) Encode(text []byte) ([]byte, error) {
+	var out []byte
+	if enc.codec == nil {
+		out = text
+	} else if !utf8.Valid(text) { // raw-runes are only held by utf-8.
+		return nil, ErrorUnrepresentable
+	} else if x, err := enc.codec.NewEncoder().Bytes(text); err != nil {
+		return nil, ErrorUnrepresentable
+	} else {
+		out = x
+	}
+	if enc.BOM {
+		return append(append([]byte{}, enc.bom...), out...), nil
+	}
+	return out, nil
+}
+
+// Unrepresentable return the rune offset of first character in
+// text that cannot be encoded, -1 if the whole text can be encoded.
+func (enc *Encoding) Unrepresentable(text []byte) int64 {
+	if enc.codec == nil {
+		return -1
+	}
+	encoder := enc.codec.NewEncoder()
+	for rCur, off := int64(0), 0; off < len(text); rCur++ {
+		r, size := decodeRune(text[off:])
+		if _, ok := RawByte(r); ok {
+			return rCur
+		} else if _, err := encoder.Bytes(text[off : off+size]); err != nil {
+			return rCur
+		}
+		off += size
+	}
+	return -1
+}
+
+//-----------
+// file APIs
+//-----------
+
+// ReadFileEncoded read filename and return its content as utf8
+// text, along with the encoding it was read with. If name is empty
+// the encoding is detected from file content.
+func ReadFileEncoded(filename, name string) ([]byte, *Encoding, error) {
+	bs, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	var enc *Encoding
+	if name == "" {
+		enc = DetectEncoding(bs)
+	} else if enc, err = LookupEncoding(name); err != nil {
+		return nil, nil, err
+	}
+	text, err := enc.Decode(bs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return text, enc, nil
+}
+
+// WriteFileEncoded write utf8 text to filename in encoding enc,
+// file is left untouched if text cannot be encoded. Text is written
+// to a temporary file and renamed over filename, so buffers that
+// read the old file lazily, like MmapBuffer and PagedBuffer, remain
+// valid after the file is saved.
+func WriteFileEncoded(filename string, text []byte, enc *Encoding) error {
+	out, err := enc.Encode(text)
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(filename); err == nil {
+		mode = fi.Mode()
+	}
+	if path, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = path
+	}
+	dir, base := filepath.Dir(filename), filepath.Base(filename)
+	fd, err := ioutil.TempFile(dir, base)
+	if err != nil {
+		return err
+	}
+	if _, err = fd.Write(out); err == nil {
+		err = fd.Sync()
+	}
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(fd.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(fd.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(fd.Name())
+	}
+	return err
+}
+
+//---------------
+// local function
+//---------------
+
+// guessUTF16 look for ascii text encoded in utf-16, where every
+// other byte is NUL.
+func guessUTF16(bs []byte) string {
+	if len(bs) > 4096 {
+		bs = bs[:4096]
+	}
+	if len(bs) < 2 || len(bs)%2 != 0 {
+		return ""
+	}
+	even, odd := 0, 0
+	for i := 0; i < len(bs); i += 2 {
+		if bs[i] == 0 {
+			even++
+		}
+		if bs[i+1] == 0 {
+			odd++
+		}
+	}
+	half := len(bs) / 2
+	if odd*2 > half && even*8 < odd {
+		return "utf-16le"
+	} else if even*2 > half && odd*8 < even {
+		return "utf-16be"
+	}
+	return ""
+}
+
+// isBinary check for NUL and control bytes that don't show up in
+// text files.
+func isBinary(bs []byte) bool {
+	if len(bs) > 4096 {
+		bs = bs[:4096]
+	}
+	for _, b := range bs {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' &&
+			b != 0x1b {
+			return true
+		}
+	}
+	return false
+}
+
+// isShiftJIS check whether bs is a valid Shift-JIS text with kana,
+// without kana latin-1 text can pass as a valid Shift-JIS.
+func isShiftJIS(bs []byte) bool {
+	text, err := japanese.ShiftJIS.NewDecoder().Bytes(bs)
+	if err != nil || bytes.ContainsRune(text, utf8.RuneError) {
+		return false
+	}
+	for _, r := range string(text) {
+		if r >= 0x3041 && r <= 0x30ff { // hiragana and katakana
+			return true
+		}
+	}
+	return false
+}
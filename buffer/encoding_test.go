@@ -0,0 +1,147 @@
+package buffer
+
+import "os"
+import "bytes"
+import "io/ioutil"
+import "testing"
+
+import "golang.org/x/text/encoding/charmap"
+
+func TestDetectEncoding(t *testing.T) {
+	testcases := []struct {
+		bs   string
+		name string
+		text string
+	}{
+		{"hello 世界", "utf-8", "hello 世界"},
+		{"", "utf-8", ""},
+		{"\xef\xbb\xbfhello", "utf-8-bom", "hello"},
+		{"\xff\xfeh\x00i\x00", "utf-16le-bom", "hi"},
+		{"\xfe\xff\x00h\x00i", "utf-16be-bom", "hi"},
+		{"h\x00e\x00l\x00l\x00o\x00", "utf-16le", "hello"},
+		{"\x00h\x00e\x00l\x00l\x00o", "utf-16be", "hello"},
+		{"caf\xe9 cr\xe8me br\xfbl\xe9e", "latin-1", "café crème brûlée"},
+		{"\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\x90\xa2\x8aE", "shift-jis",
+			"こんにちは世界"},
+		{"\x00\x01\x02\xff\xfe\x80", "utf-8", "\x00\x01\x02\xff\xfe\x80"},
+	}
+	for _, tcase := range testcases {
+		enc := DetectEncoding([]byte(tcase.bs))
+		if x := enc.String(); x != tcase.name {
+			t.Fatalf("%q expected %v, got %v", tcase.bs, tcase.name, x)
+		}
+		text, err := enc.Decode([]byte(tcase.bs))
+		if err != nil {
+			t.Fatal(err)
+		} else if string(text) != tcase.text {
+			t.Fatalf("expected %q, got %q", tcase.text, text)
+		}
+		out, err := enc.Encode(text)
+		if err != nil {
+			t.Fatal(err)
+		} else if string(out) != tcase.bs {
+			t.Fatalf("expected %q, got %q", tcase.bs, out)
+		}
+	}
+}
+
+func TestLookupEncoding(t *testing.T) {
+	for _, name := range []string{"UTF-8", "utf8", "SJIS", "iso-8859-1"} {
+		if _, err := LookupEncoding(name); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+	}
+	if enc, err := LookupEncoding("utf-16be-bom"); err != nil {
+		t.Fatal(err)
+	} else if !enc.BOM || enc.Name != "utf-16be" {
+		t.Fatalf("unexpected %v", enc)
+	}
+	if _, err := LookupEncoding("latin-1-bom"); err != ErrorUnknownEncoding {
+		t.Fatalf("expected %v, got %v", ErrorUnknownEncoding, err)
+	} else if _, err := LookupEncoding("ebcdic"); err != ErrorUnknownEncoding {
+		t.Fatalf("expected %v, got %v", ErrorUnknownEncoding, err)
+	}
+
+	RegisterEncoding("windows-1252", charmap.Windows1252, nil, "cp1252")
+	enc, err := LookupEncoding("cp1252")
+	if err != nil {
+		t.Fatal(err)
+	} else if out, err := enc.Encode([]byte("€5")); err != nil {
+		t.Fatal(err)
+	} else if string(out) != "\x805" {
+		t.Fatalf("expected %q, got %q", "\x805", out)
+	}
+}
+
+func TestUnrepresentable(t *testing.T) {
+	text := []byte("café 中國")
+	enc, _ := LookupEncoding("latin-1")
+	if _, err := enc.Encode(text); err != ErrorUnrepresentable {
+		t.Fatalf("expected %v, got %v", ErrorUnrepresentable, err)
+	} else if x := enc.Unrepresentable(text); x != 5 {
+		t.Fatalf("expected %v, got %v", 5, x)
+	}
+	enc, _ = LookupEncoding("shift-jis")
+	text = []byte("日本語🙂")
+	if _, err := enc.Encode(text); err != ErrorUnrepresentable {
+		t.Fatalf("expected %v, got %v", ErrorUnrepresentable, err)
+	} else if x := enc.Unrepresentable(text); x != 3 {
+		t.Fatalf("expected %v, got %v", 3, x)
+	}
+	// raw-runes can only be saved in utf-8.
+	text = []byte("ok\xff")
+	if _, err := enc.Encode(text); err != ErrorUnrepresentable {
+		t.Fatalf("expected %v, got %v", ErrorUnrepresentable, err)
+	} else if x := enc.Unrepresentable(text); x != 2 {
+		t.Fatalf("expected %v, got %v", 2, x)
+	}
+	enc, _ = LookupEncoding("utf-8")
+	if x := enc.Unrepresentable(text); x != -1 {
+		t.Fatalf("expected %v, got %v", -1, x)
+	}
+}
+
+func TestFileEncoded(t *testing.T) {
+	data := []byte("\xff\xfe" + "h\x00\xe9\x00\n\x00-N")
+	fd, err := ioutil.TempFile("", "encodingtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Write(data)
+	fd.Close()
+	defer os.Remove(fd.Name())
+
+	text, enc, err := ReadFileEncoded(fd.Name(), "")
+	if err != nil {
+		t.Fatal(err)
+	} else if x := string(text); x != "hé\n中" {
+		t.Fatalf("expected %q, got %q", "hé\n中", x)
+	} else if x := enc.String(); x != "utf-16le-bom" {
+		t.Fatalf("expected %v, got %v", "utf-16le-bom", x)
+	}
+	// edit and save back in the same encoding.
+	rb := NewRopebuffer(text, RopeBufferCapacity).Insert(1, []rune("i"))
+	if err := WriteFileEncoded(fd.Name(), rb.Bytes(), enc); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := ioutil.ReadFile(fd.Name())
+	if ref := "\xff\xfe" + "h\x00i\x00\xe9\x00\n\x00-N"; string(out) != ref {
+		t.Fatalf("expected %q, got %q", ref, out)
+	}
+	// file is untouched when text cannot be encoded.
+	latin1, _ := LookupEncoding("latin-1")
+	if err := WriteFileEncoded(fd.Name(), rb.Bytes(), latin1); err != ErrorUnrepresentable {
+		t.Fatalf("expected %v, got %v", ErrorUnrepresentable, err)
+	} else if x, _ := ioutil.ReadFile(fd.Name()); !bytes.Equal(x, out) {
+		t.Fatalf("expected %q, got %q", out, x)
+	}
+	// explicit override.
+	if text, _, err := ReadFileEncoded(fd.Name(), "latin-1"); err != nil {
+		t.Fatal(err)
+	} else if x := string(text); x != "ÿþh\x00i\x00é\x00\n\x00-N" {
+		t.Fatalf("unexpected %q", x)
+	}
+	if _, _, err := ReadFileEncoded(fd.Name(), "ebcdic"); err != ErrorUnknownEncoding {
+		t.Fatalf("expected %v, got %v", ErrorUnknownEncoding, err)
+	}
+}
@@ -0,0 +1,361 @@
+package buffer
+
+import "fmt"
+import "io"
+
+var _ = fmt.Sprintf("dummy")
+
+// GapBufferCapacity is the default size of the gap, in runes, when
+// a gap-buffer is created or when the gap has to grow.
+var GapBufferCapacity = int64(1024)
+
+// GapBuffer implements Buffer as an array of runes with a gap
+// positioned at the point of editing. Inserts and deletes that are
+// localised around the gap are cheap, moving the gap to a new
+// cursor position costs a copy of the runes in between.
+type GapBuffer struct {
+	Text     []rune // text-before-gap, gap, text-after-gap.
+	GapStart int64  // offset of first rune in the gap.
+	GapEnd   int64  // offset of first rune after the gap.
+	Cap      int64  // size of the gap to create when growing.
+	// statistics
+	moves int64 // no. of times the gap was moved.
+	moved int64 // no. of runes copied while moving the gap.
+	grows int64 // no. of times the gap was re-allocated.
+}
+
+// NewGapBuffer returns a new buffer, initialized with text, by
+// copying it locally. Capacity decides the size of the gap in
+// runes, when capacity is <= 0 GapBufferCapacity is used.
+func NewGapBuffer(bs []byte, capacity int64) Buffer {
+	if capacity <= 0 {
+		capacity = GapBufferCapacity
+	}
+	return newGapBuffer(bytes2Runes(bs), capacity)
+}
+
+// newGapBuffer copy runes into a new gap-buffer with the gap
+// positioned at the end of text.
+func newGapBuffer(rs []rune, capacity int64) *GapBuffer {
+	l := int64(len(rs))
+	text := make([]rune, l+capacity)
+	copy(text, rs)
+	return &GapBuffer{Text: text, GapStart: l, GapEnd: l + capacity, Cap: capacity}
+}
+
+//----------
+// rune APIs
+//----------
+
+// Length implement Buffer{} interface.
+func (gb *GapBuffer) Length() int64 {
+	return int64(len(gb.Text)) - gb.gapSize()
+}
+
+// Slice implement Buffer{} interface.
+func (gb *GapBuffer) Slice(rCur, rn int64) Buffer {
+	endCur := rCur + rn
+	if !gb.isValidCursor(rCur) {
+		return nil
+	} else if endCur < rCur {
+		return nil
+	} else if !gb.isValidCursor(endCur) {
+		endCur = gb.Length()
+	}
+	return newGapBuffer(gb.runes(rCur, endCur), gb.Cap)
+}
+
+// Runes implement Buffer{} interface.
+func (gb *GapBuffer) Runes() []rune {
+	if gb.Length() == 0 {
+		return nil
+	}
+	return gb.runes(0, gb.Length())
+}
+
+// Concat implement Buffer{} interface.
+func (gb *GapBuffer) Concat(right Buffer) Buffer {
+	rgb := right.(*GapBuffer)
+	if gb == nil {
+		return rgb
+	} else if rgb == nil {
+		return gb
+	}
+	ln, rn := gb.Length(), rgb.Length()
+	rs := make([]rune, ln+rn)
+	gb.copyTo(rs, 0, ln)
+	rgb.copyTo(rs[ln:], 0, rn)
+	return newGapBuffer(rs, gb.Cap)
+}
+
+// Split implement Buffer{} interface.
+func (gb *GapBuffer) Split(rCur int64) (left, right Buffer) {
+	if gb == nil {
+		return left, right
+	}
+	l := gb.Length()
+	if rCur >= l {
+		return gb, nil
+	} else if rCur == 0 {
+		return nil, gb
+	}
+	left = gb.Slice(0, rCur)
+	right = gb.Slice(rCur, l-rCur)
+	return
+}
+
+// Insert implement Buffer{} interface. The gap in the new buffer
+// is positioned right after the inserted text, so that subsequent
+// InsertIn() at the same point are cheap.
+func (gb *GapBuffer) Insert(rCur int64, text []rune) Buffer {
+	if text == nil {
+		return gb
+	} else if gb == nil {
+		panic(ErrorBufferNil)
+	} else if !gb.isValidCursor(rCur) {
+		panic(ErrorIndexOutofbound)
+	}
+	l, insLen := gb.Length(), int64(len(text))
+	rs := make([]rune, l+insLen+gb.Cap)
+	gb.copyTo(rs, 0, rCur)
+	copy(rs[rCur:], text)
+	newStart := rCur + insLen
+	newEnd := newStart + gb.Cap
+	gb.copyTo(rs[newEnd:], rCur, l)
+	return &GapBuffer{Text: rs, GapStart: newStart, GapEnd: newEnd, Cap: gb.Cap}
+}
+
+// Delete implement Buffer{} interface. The gap in the new buffer
+// is positioned at rCur.
+func (gb *GapBuffer) Delete(rCur, rn int64) Buffer {
+	if rn == 0 {
+		return gb
+	} else if gb == nil {
+		panic(ErrorBufferNil)
+	} else if !gb.isValidCursor(rCur) || !gb.isValidCursor(rCur+rn) {
+		panic(ErrorIndexOutofbound)
+	}
+	l := gb.Length()
+	rs := make([]rune, l-rn+gb.Cap)
+	gb.copyTo(rs, 0, rCur)
+	newEnd := rCur + gb.Cap
+	gb.copyTo(rs[newEnd:], rCur+rn, l)
+	return &GapBuffer{Text: rs, GapStart: rCur, GapEnd: newEnd, Cap: gb.Cap}
+}
+
+// InsertIn implement Buffer{} interface.
+func (gb *GapBuffer) InsertIn(rCur int64, text []rune) Buffer {
+	if text == nil {
+		return gb
+	} else if gb == nil {
+		panic(ErrorBufferNil)
+	} else if !gb.isValidCursor(rCur) {
+		panic(ErrorIndexOutofbound)
+	}
+	insLen := int64(len(text))
+	gb.moveGap(rCur)
+	if gb.gapSize() < insLen {
+		gb.growGap(insLen)
+	}
+	copy(gb.Text[gb.GapStart:], text)
+	gb.GapStart += insLen
+	return gb
+}
+
+// DeleteIn implement Buffer{} interface.
+func (gb *GapBuffer) DeleteIn(rCur, rn int64) Buffer {
+	if rn == 0 {
+		return gb
+	} else if gb == nil {
+		panic(ErrorBufferNil)
+	} else if !gb.isValidCursor(rCur) || !gb.isValidCursor(rCur+rn) {
+		panic(ErrorIndexOutofbound)
+	}
+	gb.moveGap(rCur)
+	gb.GapEnd += rn
+	return gb
+}
+
+//------------
+// search APIs
+//------------
+
+// StreamFrom implement Buffer interface{}.
+func (gb *GapBuffer) StreamFrom(rCur int64) RuneReader {
+	if !gb.isValidCursor(rCur) {
+		return nil
+	}
+	ln := gb.Length()
+	return iterator(func(finish bool) (r rune, size int, err error) {
+		if rCur >= ln || finish {
+			rCur = ln
+			return r, size, io.EOF
+		}
+		r = gb.runeAt(rCur)
+		rCur++
+		return r, runeLen(r), nil
+	})
+}
+
+// StreamCount implement Buffer interface{}.
+func (gb *GapBuffer) StreamCount(rCur, count int64) RuneReader {
+	if !gb.isValidCursor(rCur) {
+		return nil
+	}
+	ln := gb.Length()
+	return iterator(func(finish bool) (r rune, size int, err error) {
+		if rCur >= ln || count <= 0 || finish {
+			count = 0
+			return r, size, io.EOF
+		}
+		r = gb.runeAt(rCur)
+		count--
+		rCur++
+		return r, runeLen(r), nil
+	})
+}
+
+// BackStreamFrom implement Buffer interface{}.
+func (gb *GapBuffer) BackStreamFrom(rCur int64) RuneReader {
+	if !gb.isValidCursor(rCur) {
+		return nil
+	}
+	return iterator(func(finish bool) (r rune, size int, err error) {
+		rCur--
+		if rCur < 0 || finish {
+			rCur = 0
+			return r, size, io.EOF
+		}
+		r = gb.runeAt(rCur)
+		return r, runeLen(r), nil
+	})
+}
+
+// BackStreamCount implement Buffer interface{}.
+func (gb *GapBuffer) BackStreamCount(rCur, count int64) RuneReader {
+	if !gb.isValidCursor(rCur) {
+		return nil
+	}
+	return iterator(func(finish bool) (r rune, size int, err error) {
+		rCur--
+		if rCur < 0 || count <= 0 || finish {
+			count = 0
+			return r, size, io.EOF
+		}
+		r = gb.runeAt(rCur)
+		count--
+		return r, runeLen(r), nil
+	})
+}
+
+//----------
+// byte APIs
+//----------
+
+// Size implement Buffer{} interface.
+func (gb *GapBuffer) Size() int64 {
+	return int64(len(gb.Bytes()))
+}
+
+// Bytes implement Buffer{} interface.
+func (gb *GapBuffer) Bytes() []byte {
+	return runes2Bytes(gb.runes(0, gb.Length()))
+}
+
+// Stats implement Buffer{} interface.
+func (gb *GapBuffer) Stats() (Statistics, error) {
+	if gb == nil {
+		return nil, ErrorBufferNil
+	}
+	stats := Statistics{
+		"length":   gb.Length(),         // no. of runes in buffer
+		"capacity": int64(len(gb.Text)), // no. of runes allocated
+		"gapStart": gb.GapStart,         // offset of gap
+		"gapSize":  gb.gapSize(),        // no. of free runes in gap
+		"gapMoves": gb.moves,            // no. of times gap was moved
+		"gapMoved": gb.moved,            // no. of runes copied moving gap
+		"gapGrows": gb.grows,            // no. of times gap was grown
+	}
+	return stats, nil
+}
+
+//---------------
+// local function
+//---------------
+
+func (gb *GapBuffer) isValidCursor(rCur int64) bool {
+	return 0 <= rCur && rCur <= gb.Length()
+}
+
+func (gb *GapBuffer) gapSize() int64 {
+	return gb.GapEnd - gb.GapStart
+}
+
+// runeAt return the rune at logical offset rCur.
+func (gb *GapBuffer) runeAt(rCur int64) rune {
+	if rCur < gb.GapStart {
+		return gb.Text[rCur]
+	}
+	return gb.Text[rCur+gb.gapSize()]
+}
+
+// runes return a copy of runes between logical offsets
+// [from, till).
+func (gb *GapBuffer) runes(from, till int64) []rune {
+	rs := make([]rune, till-from)
+	gb.copyTo(rs, from, till)
+	return rs
+}
+
+// copyTo copy runes between logical offsets [from, till) into rs.
+func (gb *GapBuffer) copyTo(rs []rune, from, till int64) {
+	if from >= till {
+		return
+	}
+	n := int64(0)
+	if from < gb.GapStart {
+		end := till
+		if end > gb.GapStart {
+			end = gb.GapStart
+		}
+		n = int64(copy(rs, gb.Text[from:end]))
+		from = end
+	}
+	if from < till {
+		gap := gb.gapSize()
+		copy(rs[n:], gb.Text[from+gap:till+gap])
+	}
+}
+
+// moveGap such that gap starts at logical offset rCur.
+func (gb *GapBuffer) moveGap(rCur int64) {
+	if rCur == gb.GapStart {
+		return
+	}
+	gap := gb.gapSize()
+	if rCur < gb.GapStart { // move text before gap to after gap.
+		n := gb.GapStart - rCur
+		copy(gb.Text[gb.GapEnd-n:gb.GapEnd], gb.Text[rCur:gb.GapStart])
+		gb.moved += n
+	} else { // move text after gap to before gap.
+		n := rCur - gb.GapStart
+		copy(gb.Text[gb.GapStart:rCur], gb.Text[gb.GapEnd:gb.GapEnd+n])
+		gb.moved += n
+	}
+	gb.GapStart, gb.GapEnd = rCur, rCur+gap
+	gb.moves++
+}
+
+// growGap atleast by n runes, gap is grown by multiples of Cap.
+func (gb *GapBuffer) growGap(n int64) {
+	grow := gb.Cap
+	for grow < n {
+		grow += gb.Cap
+	}
+	l := int64(len(gb.Text))
+	rs := make([]rune, l+grow)
+	copy(rs, gb.Text[:gb.GapStart])
+	copy(rs[gb.GapEnd+grow:], gb.Text[gb.GapEnd:])
+	gb.Text, gb.GapEnd = rs, gb.GapEnd+grow
+	gb.grows++
+}
@@ -0,0 +1,411 @@
+package buffer
+
+import "testing"
+import "io"
+import "fmt"
+import "math/rand"
+
+var _ = fmt.Sprintf("dummy")
+
+func TestGapLength(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	if l := gb.Length(); l != 51 {
+		t.Fatalf("expected 51 got %v\n", l)
+	}
+	gb = NewGapBuffer([]byte(""), 8)
+	if l := gb.Length(); l != 0 {
+		t.Fatalf("expected 0 got %v\n", l)
+	}
+}
+
+func TestGapSliceRunes(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	gb.InsertIn(10, []rune("abc")).DeleteIn(10, 3) // move the gap
+	rs := gb.Runes()
+	if gb.Slice(-1, 0) != nil {
+		t.Fatalf("expected nil")
+	} else if gb.Slice(52, 0) != nil {
+		t.Fatalf("expected nil")
+	} else if string(gb.Slice(0, 52).Runes()) != testChinese {
+		t.Fatalf("expected full string %v", len(gb.Slice(0, 52).Runes()))
+	} else if string(gb.Slice(0, gb.Length()).Runes()) != testChinese {
+		t.Fatalf("expected full string")
+	}
+	for i := int64(0); i < gb.Length(); i++ {
+		for n := int64(0); i+n <= gb.Length(); n++ {
+			if x, y := string(rs[i:i+n]), string(gb.Slice(i, n).Runes()); x != y {
+				t.Fatalf("expected %q, got %q", x, y)
+			}
+		}
+	}
+}
+
+func TestGapConcat(t *testing.T) {
+	testcases := [][]string{
+		[]string{testChinese, testChinese},
+		[]string{``, testChinese},
+		[]string{testChinese, ``},
+		[]string{``, ``},
+	}
+	for _, testcase := range testcases {
+		s1, s2 := testcase[0], testcase[1]
+		gb1 := NewGapBuffer([]byte(s1), 8)
+		gb2 := NewGapBuffer([]byte(s2), 8)
+		gb := gb1.Concat(gb2)
+		if string(gb.Runes()) != (s1 + s2) {
+			t.Fatalf("expected concat of s1 and s2")
+		}
+	}
+}
+
+func TestGapSplit(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	testcases := [][]interface{}{
+		[]interface{}{int64(-1), nil, nil},
+		[]interface{}{int64(0), nil, string(gb.Runes())},
+		[]interface{}{int64(10), string(gb.Runes()[:10]), string(gb.Runes()[10:])},
+		[]interface{}{gb.Length(), string(gb.Runes()), nil},
+		[]interface{}{gb.Length() + 1, string(gb.Runes()), nil},
+	}
+	for _, tc := range testcases {
+		rCur, l, r := tc[0].(int64), tc[1], tc[2]
+		lgb, rgb := gb.Split(int64(rCur))
+		t.Logf("rCur: %v\n", rCur)
+		if l == nil && lgb != nil {
+			t.Fatalf("expected %v != %v", l, lgb)
+		} else if l != nil && (l.(string) != string(lgb.Runes())) {
+			t.Fatalf("expected %v != %v", l, lgb)
+		}
+		if r == nil && rgb != nil {
+			t.Fatalf("expected %v != %v", r, rgb)
+		} else if r != nil && (r.(string) != string(rgb.Runes())) {
+			t.Fatalf("expected %v != %v", r, rgb)
+		}
+	}
+}
+
+func TestGapInsert(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	testRunes := []rune(testChinese)
+	testcases := [][]interface{}{
+		[]interface{}{0, testChinese, testChinese + testChinese},
+		[]interface{}{
+			1, testChinese,
+			string(testRunes[0]) + testChinese + string(testRunes[1:])},
+		[]interface{}{len(testRunes), testChinese, testChinese + testChinese},
+	}
+	for _, tc := range testcases {
+		rCur, text, ref := int64(tc[0].(int)), tc[1].(string), tc[2].(string)
+		newgb := gb.Insert(rCur, []rune(text))
+		t.Logf("rCur: %v\n", rCur)
+		if s := string(newgb.Runes()); s != ref {
+			t.Fatalf("expected %v != %v", ref, s)
+		} else if s := string(gb.Runes()); s != testChinese {
+			t.Fatalf("persistence failed, expected %v != %v", testChinese, s)
+		}
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != ErrorIndexOutofbound {
+				t.Fatalf("expected panic ErrorIndexOutofbound, got %v", r)
+			}
+		}()
+		gb.Insert(gb.Length()+1, []rune("a"))
+	}()
+}
+
+func TestGapDelete(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	lb := NewLinearBuffer([]byte(testChinese))
+	for gb.Length() > 0 {
+		rCur := rand.Int63n(gb.Length())
+		rn := rand.Int63n(gb.Length() - rCur + 1)
+		ref := string(gb.Runes())
+		newgb, newlb := gb.Delete(rCur, rn), lb.Delete(rCur, rn)
+		if x, y := string(newlb.Runes()), string(newgb.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		} else if s := string(gb.Runes()); s != ref {
+			t.Fatalf("persistence failed, expected %q, got %q", ref, s)
+		}
+		gb, lb = newgb, newlb
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != ErrorIndexOutofbound {
+				t.Fatalf("expected panic ErrorIndexOutofbound, got %v", r)
+			}
+		}()
+		gb.Delete(0, 1)
+	}()
+}
+
+func TestGapInsertIn(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 2)
+	lb := NewLinearBuffer([]byte(testChinese))
+	for i := 0; i < 100; i++ {
+		rCur := rand.Int63n(gb.Length() + 1)
+		text := []rune(`中國;pinyin`)[:rand.Intn(9)]
+		if x := gb.InsertIn(rCur, text); x != gb {
+			t.Fatalf("expected same reference")
+		}
+		lb = lb.Insert(rCur, text)
+		if x, y := string(lb.Runes()), string(gb.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+	stats, _ := gb.Stats()
+	if stats["gapGrows"].(int64) == 0 {
+		t.Fatalf("expected gap to grow")
+	}
+}
+
+func TestGapDeleteIn(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	lb := NewLinearBuffer([]byte(testChinese))
+	for gb.Length() > 0 {
+		rCur := rand.Int63n(gb.Length())
+		rn := rand.Int63n(gb.Length() - rCur + 1)
+		if x := gb.DeleteIn(rCur, rn); x != gb {
+			t.Fatalf("expected same reference")
+		}
+		lb = lb.Delete(rCur, rn)
+		if x, y := string(lb.Runes()), string(gb.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+}
+
+func TestGapStreamFrom(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	gb.InsertIn(20, []rune("abc")).DeleteIn(20, 3) // move the gap
+	ref := []rune(testChinese)
+	if gb.StreamFrom(-1) != nil {
+		t.Fatalf("expected nil")
+	}
+	for i := int64(0); i <= gb.Length(); i++ {
+		runes, size := readAllRunes(gb.StreamFrom(i))
+		if x, y := string(ref[i:]), string(runes); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		} else if size != len(x) {
+			t.Fatalf("expected %v, got %v", len(x), size)
+		}
+	}
+}
+
+func TestGapStreamCount(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	gb.InsertIn(20, []rune("abc")).DeleteIn(20, 3) // move the gap
+	ref := []rune(testChinese)
+	for i := int64(0); i <= gb.Length(); i++ {
+		n := rand.Int63n(gb.Length() - i + 1)
+		runes, _ := readAllRunes(gb.StreamCount(i, n))
+		if x, y := string(ref[i:i+n]), string(runes); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+}
+
+func TestGapBackStreamFrom(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	gb.InsertIn(20, []rune("abc")).DeleteIn(20, 3) // move the gap
+	ref := []rune(testChinese)
+	for i := int64(0); i <= gb.Length(); i++ {
+		runes, _ := readAllRunes(gb.BackStreamFrom(i))
+		if x, y := string(ref[:i]), string(reverseRunes(runes)); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+}
+
+func TestGapBackStreamCount(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	gb.InsertIn(20, []rune("abc")).DeleteIn(20, 3) // move the gap
+	ref := []rune(testChinese)
+	for i := int64(0); i <= gb.Length(); i++ {
+		n := rand.Int63n(i + 1)
+		runes, _ := readAllRunes(gb.BackStreamCount(i, n))
+		if x, y := string(ref[i-n:i]), string(reverseRunes(runes)); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+}
+
+func TestGapBytes(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	gb.InsertIn(20, []rune("abc")).DeleteIn(20, 3) // move the gap
+	if s := string(gb.Bytes()); s != testChinese {
+		t.Fatalf("expected %q, got %q", testChinese, s)
+	} else if size := gb.Size(); size != int64(len(testChinese)) {
+		t.Fatalf("expected %v, got %v", len(testChinese), size)
+	}
+}
+
+func TestGapStats(t *testing.T) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	gb.InsertIn(10, []rune("a")).InsertIn(11, []rune("b")).InsertIn(1, nil)
+	stats, err := gb.Stats()
+	if err != nil {
+		t.Fatal(err)
+	} else if x := stats["gapMoves"].(int64); x != 1 {
+		t.Fatalf("expected %v, got %v", 1, x)
+	} else if x := stats["gapSize"].(int64); x != 6 {
+		t.Fatalf("expected %v, got %v", 6, x)
+	} else if x := stats["length"].(int64); x != 53 {
+		t.Fatalf("expected %v, got %v", 53, x)
+	}
+}
+
+func BenchmarkGapLength(b *testing.B) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	for i := 0; i < b.N; i++ {
+		gb.Length()
+	}
+}
+
+func BenchmarkGapSlice(b *testing.B) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	l := gb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gb.Slice(0, int64(i)%l)
+	}
+}
+
+func BenchmarkGapRunes(b *testing.B) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	for i := 0; i < b.N; i++ {
+		gb.Runes()
+	}
+	b.SetBytes(int64(len(testChinese)))
+}
+
+func BenchmarkGapConcat(b *testing.B) {
+	gb1 := NewGapBuffer([]byte(testChinese), 8)
+	gb2 := NewGapBuffer([]byte(testChinese), 8)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gb1.Concat(gb2)
+	}
+	b.SetBytes(int64(len(testChinese) * 2))
+}
+
+func BenchmarkGapSplit(b *testing.B) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	l := gb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gb.Split(int64(i) % l)
+	}
+	b.SetBytes(int64(len(testChinese)))
+}
+
+func BenchmarkGapInsert(b *testing.B) {
+	// insert small text into a small buffer.
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	itext := []rune(`中國;pinyin`)
+	l := gb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gb.Insert(int64(i)%l, itext)
+	}
+	b.SetBytes(int64(len(string(itext))))
+}
+
+func BenchmarkGapInsert2M(b *testing.B) {
+	// insert small text into a large buffer.
+	gb := NewGapBuffer(sampleData, 0)
+	itext := []rune(`中國;pinyin`)
+	l := gb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gb.Insert(int64(i)%l, itext)
+	}
+	b.SetBytes(int64(len(string(itext))))
+}
+
+func BenchmarkGapDelete(b *testing.B) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gb.Delete(int64(i)%10, 10)
+	}
+}
+
+func BenchmarkGapDelete2M(b *testing.B) {
+	gb := NewGapBuffer(sampleData, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gb.Delete(int64(i)%1000, 10)
+	}
+}
+
+func BenchmarkGapInsIn(b *testing.B) {
+	// insert small text, at the same spot, into a small buffer.
+	gb := NewGapBuffer([]byte(testChinese), 0)
+	itext := []rune(`中國;pinyin`)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gb.InsertIn(10, itext)
+		gb.DeleteIn(10, int64(len(itext)))
+	}
+	b.SetBytes(int64(len(string(itext))))
+}
+
+func BenchmarkGapInsIn2M(b *testing.B) {
+	// type text, one rune at a time, into a large buffer.
+	gb := NewGapBuffer(sampleData, 0)
+	itext := []rune(`中國;pinyin`)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gb.InsertIn(1000+int64(i), itext[i%len(itext):i%len(itext)+1])
+	}
+}
+
+func BenchmarkGapDelIn2M(b *testing.B) {
+	gb := NewGapBuffer(sampleData, 0)
+	itext := []rune(`中國;pinyin`)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gb.DeleteIn(1000, 10)
+		gb.InsertIn(1000, itext)
+	}
+	b.SetBytes(int64(len(string(itext))))
+}
+
+func BenchmarkGapJump2M(b *testing.B) {
+	// alternate the editing point to force moving the gap.
+	gb := NewGapBuffer(sampleData, 0)
+	l := gb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rCur := (int64(i) * 4096) % l
+		gb.InsertIn(rCur, []rune("a"))
+		gb.DeleteIn(rCur, 1)
+	}
+}
+
+func BenchmarkGapStrmFrm(b *testing.B) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	for i := 0; i < b.N; i++ {
+		var err error
+		reader := gb.StreamFrom(0)
+		for err != io.EOF {
+			_, _, err = reader.ReadRune()
+		}
+	}
+	b.SetBytes(int64(len(testChinese)))
+}
+
+func BenchmarkGapBStrmFrm(b *testing.B) {
+	gb := NewGapBuffer([]byte(testChinese), 8)
+	l := gb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		reader := gb.BackStreamFrom(l)
+		for err != io.EOF {
+			_, _, err = reader.ReadRune()
+		}
+	}
+	b.SetBytes(int64(len(testChinese)))
+}
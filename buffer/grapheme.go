@@ -0,0 +1,266 @@
+package buffer
+
+import "io"
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+//go:generate go run ../tools/ucdtables -table grapheme -out grapheme_tables.go
+
+// graphemeProp is Grapheme_Cluster_Break property of a code point,
+// as defined by UAX #29.
+type graphemeProp byte
+
+const (
+	gbAny graphemeProp = iota
+	gbCR
+	gbLF
+	gbControl
+	gbExtend
+	gbZWJ
+	gbRegional
+	gbPrepend
+	gbSpacingMark
+	gbL
+	gbV
+	gbT
+	gbLV
+	gbLVT
+	gbPictographic // Extended_Pictographic
+	gbConsonant    // Indic_Conjunct_Break=Consonant
+)
+
+type graphemeRange struct {
+	lo, hi rune
+	prop   graphemeProp
+}
+
+// GraphemeReader segments runes from RuneReader into extended
+// grapheme clusters, as defined by UAX #29. If reader streams
+// in backward direction, clusters are read from the end of text
+// to the beginning.
+type GraphemeReader struct {
+	reader   RuneReader
+	backward bool
+	runes    []rune // runes read ahead of next cluster, in read order.
+	err      error
+}
+
+// NewGraphemeReader return a new GraphemeReader for reader, if
+// backward is true reader is expected to stream runes in backward
+// direction, like from Buffer.BackStreamFrom().
+func NewGraphemeReader(reader RuneReader, backward bool) *GraphemeReader {
+	return &GraphemeReader{reader: reader, backward: backward}
+}
+
+// ReadGrapheme return next grapheme cluster as runes in text
+// order, irrespective of the direction. Return io.EOF after
+// the last cluster.
+func (gr *GraphemeReader) ReadGrapheme() ([]rune, error) {
+	if !gr.fill(1) {
+		return nil, io.EOF
+	}
+	n := 1
+	for gr.fill(n + 1) {
+		if gr.backward && gr.breakBackward(n) {
+			break
+		} else if !gr.backward && gr.breakForward(n) {
+			break
+		}
+		n++
+	}
+	cluster := make([]rune, n)
+	copy(cluster, gr.runes[:n])
+	gr.runes = gr.runes[:copy(gr.runes, gr.runes[n:])]
+	if gr.backward {
+		return reverseRunes(cluster), nil
+	}
+	return cluster, nil
+}
+
+// Close the reader and its underlying RuneReader.
+func (gr *GraphemeReader) Close() {
+	gr.reader.Close()
+}
+
+// breakForward check for a boundary between runes[n-1] and runes[n],
+// where runes[:n] is the current cluster.
+func (gr *GraphemeReader) breakForward(n int) bool {
+	left := func(k int) (rune, bool) {
+		if k < n {
+			return gr.runes[n-1-k], true
+		}
+		return 0, false
+	}
+	return graphemeBoundary(left, gr.runes[n])
+}
+
+// breakBackward check for a boundary between runes[n] and runes[n-1],
+// where runes[:n] is the current cluster in reverse order.
+func (gr *GraphemeReader) breakBackward(n int) bool {
+	left := func(k int) (rune, bool) {
+		if gr.fill(n + k + 1) {
+			return gr.runes[n+k], true
+		}
+		return 0, false
+	}
+	return graphemeBoundary(left, gr.runes[n-1])
+}
+
+// fill read ahead until there are atleast n runes, return false if
+// reader has no more runes.
+func (gr *GraphemeReader) fill(n int) bool {
+	for len(gr.runes) < n {
+		if gr.err != nil {
+			return false
+		}
+		r, _, err := gr.reader.ReadRune()
+		if err != nil {
+			gr.err = err
+			return false
+		}
+		gr.runes = append(gr.runes, r)
+	}
+	return true
+}
+
+//---------------
+// buffer motions
+//---------------
+
+// NextGrapheme return the cursor after the grapheme cluster
+// starting at rCur, return rCur if it is at the end of buffer.
+func NextGrapheme(buffer Buffer, rCur int64) int64 {
+	reader := buffer.StreamFrom(rCur)
+	if reader == nil {
+		return rCur
+	}
+	defer reader.Close()
+	cluster, _ := NewGraphemeReader(reader, false).ReadGrapheme()
+	return rCur + int64(len(cluster))
+}
+
+// PrevGrapheme return the cursor at the start of grapheme cluster
+// ending at rCur, return rCur if it is at the start of buffer.
+func PrevGrapheme(buffer Buffer, rCur int64) int64 {
+	reader := buffer.BackStreamFrom(rCur)
+	if reader == nil {
+		return rCur
+	}
+	defer reader.Close()
+	cluster, _ := NewGraphemeReader(reader, true).ReadGrapheme()
+	return rCur - int64(len(cluster))
+}
+
+//---------------
+// local function
+//---------------
+
+// graphemeBoundary apply UAX #29 rules to check for boundary
+// between left(0) and right, left(k) return the k-th rune before
+// the boundary and false at the start of text.
+func graphemeBoundary(left func(k int) (rune, bool), right rune) bool {
+	l, ok := left(0)
+	if !ok {
+		return true // GB1
+	}
+	lp, rp := graphemePropOf(l), graphemePropOf(right)
+	switch {
+	case lp == gbCR && rp == gbLF: // GB3
+		return false
+	case lp == gbCR || lp == gbLF || lp == gbControl: // GB4
+		return true
+	case rp == gbCR || rp == gbLF || rp == gbControl: // GB5
+		return true
+	case lp == gbL && (rp == gbL || rp == gbV || rp == gbLV || rp == gbLVT):
+		return false // GB6
+	case (lp == gbLV || lp == gbV) && (rp == gbV || rp == gbT): // GB7
+		return false
+	case (lp == gbLVT || lp == gbT) && rp == gbT: // GB8
+		return false
+	case rp == gbExtend || rp == gbZWJ: // GB9
+		return false
+	case rp == gbSpacingMark: // GB9a
+		return false
+	case lp == gbPrepend: // GB9b
+		return false
+	case rp == gbConsonant && incbConjunct(left): // GB9c
+		return false
+	case lp == gbZWJ && rp == gbPictographic && pictographicZWJ(left):
+		return false // GB11
+	case lp == gbRegional && rp == gbRegional: // GB12, GB13
+		k := 0 // count regional indicators before the boundary.
+		for r, ok := left(0); ok && graphemePropOf(r) == gbRegional; r, ok = left(k) {
+			k++
+		}
+		return k%2 == 0
+	}
+	return true // GB999
+}
+
+// incbConjunct match Consonant [Extend Linker]* Linker [Extend Linker]*
+// before the boundary.
+func incbConjunct(left func(k int) (rune, bool)) bool {
+	linker := false
+	for k := 0; ; k++ {
+		r, ok := left(k)
+		if !ok {
+			return false
+		} else if incbLinker(r) {
+			linker = true
+		} else if !incbExtend(r) {
+			return linker && graphemePropOf(r) == gbConsonant
+		}
+	}
+}
+
+// pictographicZWJ match Extended_Pictographic Extend* ZWJ before
+// the boundary.
+func pictographicZWJ(left func(k int) (rune, bool)) bool {
+	for k := 1; ; k++ {
+		r, ok := left(k)
+		if !ok {
+			return false
+		} else if prop := graphemePropOf(r); prop != gbExtend {
+			return prop == gbPictographic
+		}
+	}
+}
+
+func graphemePropOf(r rune) graphemeProp {
+	if r >= 0x20 && r < 0x7f {
+		return gbAny
+	} else if r >= 0xd800 && r <= 0xdfff { // surrogates and raw-runes.
+		return gbControl
+	}
+	return lookupGrapheme(graphemeTable, r, gbAny)
+}
+
+func incbLinker(r rune) bool {
+	switch r {
+	case 0x094d, 0x09cd, 0x0acd, 0x0b4d, 0x0c4d, 0x0d4d:
+		return true
+	}
+	return false
+}
+
+func incbExtend(r rune) bool {
+	return lookupGrapheme(incbExtendTable, r, gbAny) == gbExtend
+}
+
+func lookupGrapheme(
+	table []graphemeRange, r rune, def graphemeProp) graphemeProp {
+
+	lo, hi := 0, len(table)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if rng := table[mid]; r < rng.lo {
+			hi = mid
+		} else if r > rng.hi {
+			lo = mid + 1
+		} else {
+			return rng.prop
+		}
+	}
+	return def
+}
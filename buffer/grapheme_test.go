@@ -0,0 +1,152 @@
+package buffer
+
+import "io"
+import "bufio"
+import "os"
+import "strconv"
+import "strings"
+import "testing"
+
+func TestGraphemeBreakTest(t *testing.T) {
+	tests := loadGraphemeBreakTest(t, "testdata/GraphemeBreakTest.txt")
+	if len(tests) < 1000 {
+		t.Fatalf("expected atleast 1000 test cases, got %v", len(tests))
+	}
+	for _, clusters := range tests {
+		text := []rune{}
+		for _, cluster := range clusters {
+			text = append(text, cluster...)
+		}
+		bs := []byte(string(text))
+		buffers := []Buffer{NewLinearBuffer(bs), NewRopebuffer(bs, 8)}
+		for _, buf := range buffers {
+			l := buf.Length()
+			// forward.
+			gr := NewGraphemeReader(buf.StreamFrom(0), false)
+			for i, ref := range clusters {
+				cluster, err := gr.ReadGrapheme()
+				if err != nil {
+					t.Fatalf("%q: %v", string(text), err)
+				} else if x, y := string(ref), string(cluster); x != y {
+					t.Fatalf("%q: cluster %v expected %q, got %q", string(text), i, x, y)
+				}
+			}
+			if _, err := gr.ReadGrapheme(); err != io.EOF {
+				t.Fatalf("%q: expected %v, got %v", string(text), io.EOF, err)
+			}
+			// backward.
+			gr = NewGraphemeReader(buf.BackStreamFrom(l), true)
+			for i := len(clusters) - 1; i >= 0; i-- {
+				cluster, err := gr.ReadGrapheme()
+				if err != nil {
+					t.Fatalf("%q: %v", string(text), err)
+				} else if x, y := string(clusters[i]), string(cluster); x != y {
+					t.Fatalf("%q: cluster %v expected %q, got %q", string(text), i, x, y)
+				}
+			}
+			if _, err := gr.ReadGrapheme(); err != io.EOF {
+				t.Fatalf("%q: expected %v, got %v", string(text), io.EOF, err)
+			}
+			// motions from every boundary.
+			rCur := int64(0)
+			for _, cluster := range clusters {
+				next := NextGrapheme(buf, rCur)
+				if x := rCur + int64(len(cluster)); next != x {
+					t.Fatalf("%q: expected %v, got %v", string(text), x, next)
+				} else if prev := PrevGrapheme(buf, next); prev != rCur {
+					t.Fatalf("%q: expected %v, got %v", string(text), rCur, prev)
+				}
+				rCur = next
+			}
+		}
+	}
+}
+
+func TestGraphemeMotion(t *testing.T) {
+	// e with combining acute, family with zero width joiners,
+	// flags and a hangul syllable in conjoining jamo.
+	text := "e\u0301\U0001F468\u200d\U0001F469\u200d\U0001F467" +
+		"\U0001F1EE\U0001F1F3\U0001F1EF\U0001F1F5\u1100\u1161\u11a8\r\nz"
+	stops := []int64{0, 2, 7, 9, 11, 14, 16, 17}
+	buf := NewRopebuffer([]byte(text), 4)
+	for i := 0; i+1 < len(stops); i++ {
+		if x := NextGrapheme(buf, stops[i]); x != stops[i+1] {
+			t.Fatalf("expected %v, got %v", stops[i+1], x)
+		} else if x := PrevGrapheme(buf, stops[i+1]); x != stops[i] {
+			t.Fatalf("expected %v, got %v", stops[i], x)
+		}
+	}
+	if x := NextGrapheme(buf, 17); x != 17 {
+		t.Fatalf("expected %v, got %v", 17, x)
+	} else if x := PrevGrapheme(buf, 0); x != 0 {
+		t.Fatalf("expected %v, got %v", 0, x)
+	} else if x := NextGrapheme(buf, 18); x != 18 {
+		t.Fatalf("expected %v, got %v", 18, x)
+	}
+}
+
+func BenchmarkGraphemeRead(b *testing.B) {
+	rb := NewRopebuffer(sampleData, testRopeBufferCapacity)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gr := NewGraphemeReader(rb.StreamFrom(0), false)
+		for _, err := gr.ReadGrapheme(); err == nil; {
+			_, err = gr.ReadGrapheme()
+		}
+	}
+	b.SetBytes(rb.Size())
+}
+
+func BenchmarkGraphemePrev(b *testing.B) {
+	rb := NewRopebuffer(sampleData, testRopeBufferCapacity)
+	l := rb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PrevGrapheme(rb, int64(i*1009)%l)
+	}
+}
+
+// loadGraphemeBreakTest parse test cases from GraphemeBreakTest.txt,
+// returning the expected clusters for each case.
+func loadGraphemeBreakTest(t *testing.T, filename string) [][][]rune {
+	fd, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	tests := [][][]rune{}
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		clusters, cluster := [][]rune{}, []rune{}
+		for _, field := range fields {
+			switch field {
+			case "÷":
+				if len(cluster) > 0 {
+					clusters = append(clusters, cluster)
+				}
+				cluster = []rune{}
+			case "×":
+			default:
+				r, err := strconv.ParseUint(field, 16, 32)
+				if err != nil {
+					t.Fatalf("%q: %v", line, err)
+				}
+				cluster = append(cluster, rune(r))
+			}
+		}
+		tests = append(tests, clusters)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return tests
+}
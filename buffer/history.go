@@ -0,0 +1,305 @@
+package buffer
+
+import "os"
+import "time"
+import "bytes"
+import "strings"
+import "net/url"
+import "io/ioutil"
+import "crypto/sha256"
+import "encoding/gob"
+import "path/filepath"
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// historyVersion is the format of undo files, files written in any
+// other format are treated as stale.
+const historyVersion = 1
+
+// history is the content of an undo file. Changes are stored as
+// edits only, text of the saved change is the file itself, and the
+// rest of the change-tree is rebuilt from it.
+type history struct {
+	Version int
+	Hash    []byte // sha256 of the saved file, before encoding.
+	Seq     int64  // sequence no. of the latest change.
+	Saved   int64  // sequence no. of the change saved to file.
+	Mixed   bool   // saved change had mixed line endings.
+	// edits that converted line endings, when the saved change was
+	// written to file.
+	Convert []historyEdit
+	Changes []historyChange // ordered by sequence no.
+	Marks   map[string]historyMark
+}
+
+type historyChange struct {
+	Seq    int64
+	Parent int64 // -1 for root.
+	Time   time.Time
+	Dot    int64
+	Sels   Selections
+	Branch int
+	Edits  []historyEdit
+}
+
+type historyEdit struct {
+	Start, End int64
+	Text, Old  []rune
+}
+
+type historyMark struct {
+	Seq     int64
+	Cursor  int64
+	Gravity Gravity
+}
+
+// UndoFile return the undo file for filename under dir, absolute
+// path of filename is escaped into a single file name.
+func UndoFile(dir, filename string) (string, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	name := url.PathEscape(filepath.ToSlash(path))
+	name = strings.Replace(name, ":", "%3A", -1)
+	return filepath.Join(dir, name+".undo"), nil
+}
+
+// WriteHistory write the change-tree to undofile, an empty undofile
+// writes to the "undodir" setting, refer UndoFile. Every change is
+// written, along with its dot, selections, timestamp and branch,
+// and named marks. This change is expected to be saved to its file,
+// the undo file is valid only as long as the file is not changed
+// outside the editor, refer ReadHistory.
+func (ebuf *EditBuffer) WriteHistory(undofile string) error {
+	buffer, _ := ConvertLineEndings(ebuf.text(), ebuf.newline)
+	return ebuf.writeHistory(undofile, buffer)
+}
+
+// ReadHistory load the change-tree written by WriteHistory, an
+// empty undofile reads from the "undodir" setting, refer UndoFile.
+// This edit buffer is expected to be freshly loaded from file, and
+// its change-tree is replaced with the loaded one. Return the
+// change that was saved to the file, and this edit buffer as is if
+// undofile does not exist. If the file was changed since the
+// history was written, the history is stale and is not loaded,
+// return ErrorStaleHistory.
+func (ebuf *EditBuffer) ReadHistory(undofile string) (*EditBuffer, error) {
+	undofile, err := ebuf.undoFile(undofile)
+	if err != nil {
+		return ebuf, err
+	}
+	data, err := ioutil.ReadFile(undofile)
+	if os.IsNotExist(err) {
+		return ebuf, nil
+	} else if err != nil {
+		return ebuf, err
+	}
+	var hist history
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&hist); err != nil {
+		return ebuf, err
+	}
+	hash := sha256.Sum256(ebuf.text().Bytes())
+	if hist.Version != historyVersion || !bytes.Equal(hist.Hash, hash[:]) {
+		return ebuf, ErrorStaleHistory
+	}
+	return ebuf.restoreHistory(&hist)
+}
+
+func (ebuf *EditBuffer) undoFile(undofile string) (string, error) {
+	if undofile != "" {
+		return undofile, nil
+	} else if ebuf.tree.undodir == "" || ebuf.filename == "" {
+		return "", ErrorUndoFile
+	}
+	return UndoFile(ebuf.tree.undodir, ebuf.filename)
+}
+
+// writeHistory with saved as the text written to file, undofile
+// is replaced atomically.
+func (ebuf *EditBuffer) writeHistory(undofile string, saved Buffer) error {
+	undofile, err := ebuf.undoFile(undofile)
+	if err != nil {
+		return err
+	}
+	tree, hash := ebuf.tree, sha256.Sum256(saved.Bytes())
+	hist := history{
+		Version: historyVersion,
+		Hash:    hash[:],
+		Seq:     tree.seq,
+		Saved:   ebuf.seq,
+		Mixed:   ebuf.mixed,
+		Convert: toHistoryEdits(diffEdits(ebuf.text(), saved)),
+		Changes: make([]historyChange, 0, len(tree.changes)),
+		Marks:   make(map[string]historyMark),
+	}
+	for _, change := range tree.changes {
+		parent := int64(-1)
+		if change.parent != nil {
+			parent = change.parent.seq
+		}
+		hist.Changes = append(hist.Changes, historyChange{
+			Seq:    change.seq,
+			Parent: parent,
+			Time:   change.time,
+			Dot:    change.dot,
+			Sels:   change.sels,
+			Branch: change.branch,
+			Edits:  toHistoryEdits(change.edits),
+		})
+	}
+	for name, anchor := range tree.marks {
+		hist.Marks[name] = historyMark{
+			Seq: anchor.change.seq, Cursor: anchor.rCur, Gravity: anchor.gravity,
+		}
+	}
+
+	dir := filepath.Dir(undofile)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	fd, err := ioutil.TempFile(dir, filepath.Base(undofile))
+	if err != nil {
+		return err
+	}
+	if err = gob.NewEncoder(fd).Encode(&hist); err == nil {
+		err = fd.Close()
+	} else {
+		fd.Close()
+	}
+	if err == nil {
+		err = os.Rename(fd.Name(), undofile)
+	}
+	if err != nil {
+		os.Remove(fd.Name())
+	}
+	return err
+}
+
+// restoreHistory rebuild the change-tree from hist, text of this
+// edit buffer being the saved text. Checkpoints are placed like
+// they are when changes are made, refer newChange. Edits that don't
+// fit the text they apply to make the history stale.
+func (ebuf *EditBuffer) restoreHistory(hist *history) (*EditBuffer, error) {
+	tree := &changeTree{
+		seq:        hist.Seq,
+		changes:    make([]*EditBuffer, 0, len(hist.Changes)),
+		marks:      make(map[string]*Anchor),
+		anchors:    make(map[*Anchor]bool),
+		pause:      ebuf.tree.pause,
+		undodir:    ebuf.tree.undodir,
+		maxChanges: ebuf.tree.maxChanges,
+		maxBytes:   ebuf.tree.maxBytes,
+		maxAge:     ebuf.tree.maxAge,
+	}
+	changes := make(map[int64]*EditBuffer)
+	for _, hc := range hist.Changes {
+		change := &EditBuffer{
+			dot:      hc.Dot,
+			sels:     hc.Sels,
+			ronly:    ebuf.ronly,
+			children: make([]*EditBuffer, 0),
+			branch:   hc.Branch,
+			tree:     tree,
+			seq:      hc.Seq,
+			time:     hc.Time,
+			edits:    fromHistoryEdits(hc.Edits),
+			tabstop:  ebuf.tabstop,
+			newline:  ebuf.newline,
+			mixed:    hist.Mixed,
+			filename: ebuf.filename,
+			encoding: ebuf.encoding,
+		}
+		if hc.Parent >= 0 {
+			parent, ok := changes[hc.Parent]
+			if !ok {
+				return ebuf, ErrorStaleHistory
+			}
+			change.parent = parent
+			parent.children = append(parent.children, change)
+		} else if len(tree.changes) > 0 {
+			return ebuf, ErrorStaleHistory
+		}
+		changes[change.seq] = change
+		tree.changes = append(tree.changes, change)
+	}
+	saved, ok := changes[hist.Saved]
+	if !ok || len(tree.changes) == 0 {
+		return ebuf, ErrorStaleHistory
+	}
+	for _, change := range tree.changes {
+		if change.branch >= len(change.children) {
+			change.branch = 0
+		}
+	}
+
+	// saved text, and root text by undoing the path to saved.
+	convert := fromHistoryEdits(hist.Convert)
+	if !validEdits(convert, ebuf.text().Length()-deltaLength(convert)) {
+		return ebuf, ErrorStaleHistory
+	}
+	saved.buffer = applyEdits(ebuf.text(), reverseEdits(convert))
+	buffer := saved.buffer
+	for x := saved; x.parent != nil; x = x.parent {
+		if !validEdits(x.edits, buffer.Length()-deltaLength(x.edits)) {
+			return ebuf, ErrorStaleHistory
+		}
+		buffer = applyEdits(buffer, x.undoEdits())
+	}
+	root := tree.changes[0]
+	root.buffer, root.checkpoint = buffer, true
+
+	lengths := map[*EditBuffer]int64{root: buffer.Length()}
+	for _, change := range tree.changes[1:] {
+		parent := change.parent
+		if !validEdits(change.edits, lengths[parent]) {
+			return ebuf, ErrorStaleHistory
+		}
+		lengths[change] = lengths[parent] + deltaLength(change.edits)
+		change.chain = parent.chain + 1
+		change.churn = parent.churn + deltaRunesIn(change.edits)
+		if change == saved {
+			change.checkpoint = true
+		} else if change.chain >= changeCheckpoint ||
+			change.churn > lengths[change] {
+
+			change.buffer = applyEdits(parent.text(), change.edits)
+			change.checkpoint = true
+		}
+		if change.checkpoint {
+			change.chain, change.churn = 0, 0
+		}
+		tree.bytes += change.memory()
+	}
+	for name, hm := range hist.Marks {
+		change, ok := changes[hm.Seq]
+		if !ok {
+			continue
+		} else if hm.Cursor < 0 || hm.Cursor > lengths[change] {
+			return ebuf, ErrorStaleHistory
+		}
+		anchor := &Anchor{change: change, rCur: hm.Cursor, gravity: hm.Gravity}
+		tree.marks[name], tree.anchors[anchor] = anchor, true
+	}
+	tree.prune(saved, time.Now())
+	return saved, nil
+}
+
+func toHistoryEdits(edits []textEdit) []historyEdit {
+	hedits := make([]historyEdit, 0, len(edits))
+	for _, edit := range edits {
+		hedits = append(hedits, historyEdit{
+			Start: edit.start, End: edit.end, Text: edit.text, Old: edit.old,
+		})
+	}
+	return hedits
+}
+
+func fromHistoryEdits(hedits []historyEdit) []textEdit {
+	edits := make([]textEdit, 0, len(hedits))
+	for _, he := range hedits {
+		edits = append(edits, textEdit{he.Start, he.End, he.Text, he.Old})
+	}
+	return edits
+}
@@ -2,7 +2,6 @@ package buffer
 
 import "fmt"
 import "io"
-import "unicode/utf8"
 
 var _ = fmt.Sprintf("dummy")
 
@@ -32,9 +31,9 @@ func (lb *LinearBuffer) Slice(rCur, rn int64) Buffer {
 	endCur := rCur + rn
 	if !lb.isValidCursor(rCur) {
 		return nil
-	} else if ok := lb.isValidCursor(endCur); !ok && endCur < rCur {
+	} else if endCur < rCur {
 		return nil
-	} else if !ok {
+	} else if !lb.isValidCursor(endCur) {
 		endCur = lb.Length()
 	}
 	rs := make([]rune, endCur-rCur)
@@ -68,10 +67,11 @@ func (lb *LinearBuffer) Concat(right Buffer) Buffer {
 
 // Split implement Buffer{} interface.
 func (lb *LinearBuffer) Split(rCur int64) (left, right Buffer) {
-	l := lb.Length()
-	if lb == nil {
+	if lb == nil || rCur < 0 {
 		return left, right
-	} else if rCur >= l {
+	}
+	l := lb.Length()
+	if rCur >= l {
 		return lb, nil
 	} else if rCur == 0 {
 		return nil, lb
@@ -104,7 +104,7 @@ func (lb *LinearBuffer) Delete(rCur, rn int64) Buffer {
 		return lb
 	} else if lb == nil {
 		panic(ErrorBufferNil)
-	} else if !lb.isValidCursor(rCur) {
+	} else if !lb.isValidCursor(rCur) || !lb.isValidCursor(rCur+rn) {
 		panic(ErrorIndexOutofbound)
 	}
 	rs := make([]rune, int64(len(lb.Text))-rn)
@@ -123,7 +123,8 @@ func (lb *LinearBuffer) InsertIn(rCur int64, text []rune) Buffer {
 		panic(ErrorIndexOutofbound)
 	}
 	l := int64(len(text))
-	lb.Text = append(lb.Text, lb.Text[rCur:]...)
+	lb.Text = append(lb.Text, text...)
+	copy(lb.Text[rCur+l:], lb.Text[rCur:])
 	copy(lb.Text[rCur:rCur+l], text)
 	return lb
 }
@@ -134,7 +135,7 @@ func (lb *LinearBuffer) DeleteIn(rCur, rn int64) Buffer {
 		return lb
 	} else if lb == nil {
 		panic(ErrorBufferNil)
-	} else if !lb.isValidCursor(rCur) {
+	} else if !lb.isValidCursor(rCur) || !lb.isValidCursor(rCur+rn) {
 		panic(ErrorIndexOutofbound)
 	}
 	l := int64(len(lb.Text))
@@ -155,11 +156,12 @@ func (lb *LinearBuffer) StreamFrom(rCur int64) RuneReader {
 	ln := int64(len(lb.Text))
 	return iterator(func(finish bool) (r rune, size int, err error) {
 		if rCur >= ln || finish {
+			rCur = ln
 			return r, size, io.EOF
 		}
 		r = lb.Text[rCur]
 		rCur++
-		return r, utf8.RuneLen(r), nil
+		return r, runeLen(r), nil
 	})
 }
 
@@ -171,12 +173,13 @@ func (lb *LinearBuffer) StreamCount(rCur, count int64) RuneReader {
 	ln := int64(len(lb.Text))
 	return iterator(func(finish bool) (r rune, size int, err error) {
 		if rCur >= ln || count <= 0 || finish {
+			count = 0
 			return r, size, io.EOF
 		}
 		r = lb.Text[rCur]
 		count--
 		rCur++
-		return r, utf8.RuneLen(r), nil
+		return r, runeLen(r), nil
 	})
 }
 
@@ -187,11 +190,12 @@ func (lb *LinearBuffer) BackStreamFrom(rCur int64) RuneReader {
 	}
 	return iterator(func(finish bool) (r rune, size int, err error) {
 		rCur--
-		if rCur <= 0 || finish {
+		if rCur < 0 || finish {
+			rCur = 0
 			return r, size, io.EOF
 		}
 		r = lb.Text[rCur]
-		return r, utf8.RuneLen(r), nil
+		return r, runeLen(r), nil
 	})
 }
 
@@ -202,12 +206,13 @@ func (lb *LinearBuffer) BackStreamCount(rCur, count int64) RuneReader {
 	}
 	return iterator(func(finish bool) (r rune, size int, err error) {
 		rCur--
-		if rCur <= 0 || count <= 0 || finish {
+		if rCur < 0 || count <= 0 || finish {
+			count = 0
 			return r, size, io.EOF
 		}
 		r = lb.Text[rCur]
 		count--
-		return r, utf8.RuneLen(r), nil
+		return r, runeLen(r), nil
 	})
 }
 
@@ -226,8 +231,15 @@ func (lb *LinearBuffer) Bytes() []byte {
 }
 
 // Stats implement Buffer{} interface.
-func (lb *LinearBuffer) Stats() (stats Statistics, err error) {
-	return
+func (lb *LinearBuffer) Stats() (Statistics, error) {
+	if lb == nil {
+		return nil, ErrorBufferNil
+	}
+	stats := Statistics{
+		"length":   lb.Length(),         // no. of runes in buffer
+		"capacity": int64(cap(lb.Text)), // no. of runes allocated
+	}
+	return stats, nil
 }
 
 //---------------
@@ -1,5 +1,3 @@
-// +build ignore
-
 package buffer
 
 import "fmt"
@@ -7,16 +5,16 @@ import "fmt"
 // Lines index pairs within the input buffer,
 // eg lines[2*n:2*n+1] identifies the indexes of the
 // nth line starting from lines[2*n] and ending
-// before lines[2*n+1], all offsets at byte level.
+// before lines[2*n+1], all offsets at rune level.
 //
 // First-line:
-//      [{0, x}, ...], where x < buflen
+//      [{0, x}, ...], where x <= buflen
 // Last-line:
-//      [..., {x, buflen+1}], where x < buflen
+//      [..., {x, buflen}], where x <= buflen
 // Empty-buffer:
 //      [{0, 0}]
 // Empty-line:
-//      [..., {x, x+nl} ...], where nl == len(newline)
+//      [..., {x, x+nl} ...], where nl is 2 for "\r\n", else 1
 type Lines []int64
 
 // LinesIterator will iterate on blocks of lines
@@ -24,7 +22,7 @@ type Lines []int64
 type LinesIterator func() Lines
 
 // LineIterator will iterate on each consecutive line
-// within a block, all offsets at byte level.
+// within a block, all offsets at rune level.
 type LineIterator func() (start, end int64)
 
 // return an iterator on block of consecutive-lines.
@@ -56,13 +54,11 @@ func (lines Lines) blocksFrom(bCur int64) LinesIterator {
 	return LinesIterator(func() Lines {
 		for {
 			block := iterBlock()
-			if bCur < 0 {
+			if bCur < 0 || block == nil {
+				return block
+			} else if block.containsCursor(bCur) || block.afterCursor(bCur) {
+				bCur = -1 // let us iterate on each block from now on.
 				return block
-			} else if block != nil {
-				if block.containsCursor(bCur) || block.afterCursor(bCur) {
-					bCur = -1 // let us iterate on each block from now on.
-					return block
-				}
 			}
 			// NOTE: continue to find the next block containing bCur.
 		}
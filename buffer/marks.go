@@ -0,0 +1,89 @@
+package buffer
+
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// Gravity decide where an anchor goes when text is inserted at
+// its position, or when text around it is replaced.
+type Gravity byte
+
+const (
+	// GravityLeft keep the anchor before the inserted text.
+	GravityLeft Gravity = iota
+	// GravityRight move the anchor after the inserted text.
+	GravityRight
+)
+
+// Anchor is a position pinned to text, rather than to an offset.
+// It is set in one change of a change-tree and resolved in any
+// other change of the same tree, by following the edits between
+// the two changes, refer EditBuffer.Position. Text deleted around
+// an anchor collapse it to where the text was, and undoing the
+// delete restore it.
+type Anchor struct {
+	change  *EditBuffer // change the anchor was set in.
+	rCur    int64       // position within change.
+	gravity Gravity
+	// positions resolved so far, by change, so that a change is
+	// resolved by following the edits from its nearest resolved
+	// ancestor.
+	resolved map[*EditBuffer]int64
+}
+
+// Gravity of anchor.
+func (anchor *Anchor) Gravity() Gravity {
+	return anchor.gravity
+}
+
+// resolve anchor's position in change, -1 if change is not in the
+// same tree as the anchor, or if the edits between them move the
+// anchor outside change's text.
+func (anchor *Anchor) resolve(change *EditBuffer) int64 {
+	if anchor.resolved == nil {
+		anchor.resolved = map[*EditBuffer]int64{anchor.change: anchor.rCur}
+	}
+	if rCur, ok := anchor.resolved[change]; ok {
+		return rCur
+	}
+	right := anchor.gravity == GravityRight
+
+	down, rCur, ok := make([]*EditBuffer, 0), int64(0), false
+	for x := change; x != nil && !ok; x = x.parent {
+		if rCur, ok = anchor.resolved[x]; !ok {
+			down = append(down, x)
+		}
+	}
+	if !ok { // change is above the anchor, or on another branch.
+		ancestors := make(map[*EditBuffer]bool)
+		for x := anchor.change; x != nil; x = x.parent {
+			ancestors[x] = true
+		}
+		i := 0
+		for ; i < len(down) && !ancestors[down[i]]; i++ {
+		}
+		if i == len(down) {
+			return -1
+		}
+		lca := down[i]
+		down, rCur = down[:i], anchor.rCur
+		for x := anchor.change; x != lca; x = x.parent {
+			rCur = shiftCursor(rCur, x.undoEdits(), right)
+			anchor.resolved[x.parent] = rCur
+		}
+	}
+	for i := len(down) - 1; i >= 0; i-- {
+		rCur = shiftCursor(rCur, down[i].changeEdits(), right)
+		anchor.resolved[down[i]] = rCur
+	}
+	if rCur < 0 || rCur > change.text().Length() {
+		anchor.resolved = nil
+		return -1
+	}
+	return rCur
+}
+
+// reset anchor to rCur in change, forgetting resolved positions.
+func (anchor *Anchor) reset(change *EditBuffer, rCur int64) {
+	anchor.change, anchor.rCur, anchor.resolved = change, rCur, nil
+}
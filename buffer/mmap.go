@@ -0,0 +1,377 @@
+package buffer
+
+import "fmt"
+import "io"
+import "os"
+import "sort"
+import "unicode/utf8"
+
+var _ = fmt.Sprintf("dummy")
+
+// MmapBufferCheckpoint is the distance, in bytes, between two
+// checkpoints in the rune index of a memory mapped file.
+var MmapBufferCheckpoint = int64(64 * 1024)
+
+// MmapBuffer implements a read-only Buffer over a memory mapped
+// file. Opening a file does not read it, runes are decoded from the
+// mapped bytes on demand and rune offsets are mapped to byte offsets
+// using a checkpoint index that is built lazily and shared by all
+// buffers sliced from the same file.
+//
+// Insert and Delete return a PieceTable that refers to the mapped
+// bytes as its original text and holds the changes in its own
+// store. InsertIn and DeleteIn panic with ErrorReadonlyBuffer.
+type MmapBuffer struct {
+	index *mmapIndex
+	off   int64 // byte offset of the buffer within mapped file.
+	end   int64 // byte offset of the end of buffer.
+	rOff  int64 // rune offset of the buffer within mapped file.
+	rLen  int64 // no. of runes in buffer, -1 if not yet counted.
+}
+
+// mmapIndex maps rune offsets to byte offsets for a mapped file.
+// Checkpoints are rune aligned, bOffs[0] and rOffs[0] are zero.
+type mmapIndex struct {
+	data  []byte
+	step  int64
+	bOffs []int64 // byte offset of checkpoints.
+	rOffs []int64 // rune offset of checkpoints.
+}
+
+// NewMmapBuffer returns a read-only buffer by memory mapping
+// filename. Mapped bytes are not validated for utf8 encoding,
+// invalid bytes are read as raw-runes, refer RuneRawBase.
+func NewMmapBuffer(filename string) (*MmapBuffer, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data, err := mmapFile(fd, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	index := &mmapIndex{
+		data: data, step: MmapBufferCheckpoint,
+		bOffs: []int64{0}, rOffs: []int64{0},
+	}
+	return &MmapBuffer{index: index, end: int64(len(data)), rLen: -1}, nil
+}
+
+// Close unmaps the file. Buffers sliced from this buffer, and the
+// overlays created by Insert and Delete, shall not be used there
+// after.
+func (mb *MmapBuffer) Close() error {
+	if mb.index.data == nil {
+		return nil
+	}
+	data := mb.index.data
+	mb.index.data = nil
+	return munmapFile(data)
+}
+
+//----------
+// rune APIs
+//----------
+
+// Length implement Buffer{} interface. First call on a buffer
+// would scan the mapped bytes upto the end of buffer.
+func (mb *MmapBuffer) Length() int64 {
+	if mb.rLen < 0 {
+		mb.rLen = mb.index.runeOffset(mb.end) - mb.rOff
+	}
+	return mb.rLen
+}
+
+// Slice implement Buffer{} interface.
+func (mb *MmapBuffer) Slice(rCur, rn int64) Buffer {
+	if rn < 0 {
+		return nil
+	}
+	bCur, ok := mb.byteOffset(rCur)
+	if !ok {
+		return nil
+	}
+	newmb := &MmapBuffer{index: mb.index, off: bCur, rOff: mb.rOff + rCur}
+	if bEnd, ok := mb.byteOffset(rCur + rn); ok {
+		newmb.end, newmb.rLen = bEnd, rn
+	} else {
+		newmb.end, newmb.rLen = mb.end, -1
+	}
+	return newmb
+}
+
+// Runes implement Buffer{} interface.
+func (mb *MmapBuffer) Runes() []rune {
+	if mb.end == mb.off {
+		return nil
+	}
+	rs := make([]rune, 0, mb.Length())
+	for bs := mb.bytes(); len(bs) > 0; {
+		r, size := decodeRune(bs)
+		rs, bs = append(rs, r), bs[size:]
+	}
+	return rs
+}
+
+// Concat implement Buffer{} interface. Adjacent slices of the same
+// file are concatenated without copying, else an overlay is
+// returned.
+func (mb *MmapBuffer) Concat(right Buffer) Buffer {
+	if rmb, ok := right.(*MmapBuffer); ok {
+		if rmb.index == mb.index && rmb.off == mb.end {
+			newmb := &MmapBuffer{
+				index: mb.index, off: mb.off, end: rmb.end, rOff: mb.rOff,
+				rLen: -1,
+			}
+			if mb.rLen >= 0 && rmb.rLen >= 0 {
+				newmb.rLen = mb.rLen + rmb.rLen
+			}
+			return newmb
+		}
+		return mb.overlay().Concat(rmb.overlay())
+	}
+	return mb.overlay().Concat(right)
+}
+
+// Split implement Buffer{} interface.
+func (mb *MmapBuffer) Split(rCur int64) (left, right Buffer) {
+	if mb == nil || rCur < 0 {
+		return left, right
+	}
+	bCur, ok := mb.byteOffset(rCur)
+	if !ok || bCur == mb.end {
+		return mb, nil
+	} else if rCur == 0 {
+		return nil, mb
+	}
+	left = mb.Slice(0, rCur)
+	right = &MmapBuffer{
+		index: mb.index, off: bCur, end: mb.end, rOff: mb.rOff + rCur,
+		rLen: -1,
+	}
+	return left, right
+}
+
+// Insert implement Buffer{} interface, return an overlay
+// containing the inserted text.
+func (mb *MmapBuffer) Insert(rCur int64, text []rune) Buffer {
+	if text == nil {
+		return mb
+	} else if mb == nil {
+		panic(ErrorBufferNil)
+	}
+	return mb.overlay().Insert(rCur, text)
+}
+
+// Delete implement Buffer{} interface, return an overlay without
+// the deleted text.
+func (mb *MmapBuffer) Delete(rCur, rn int64) Buffer {
+	if rn == 0 {
+		return mb
+	} else if mb == nil {
+		panic(ErrorBufferNil)
+	}
+	return mb.overlay().Delete(rCur, rn)
+}
+
+// InsertIn implement Buffer{} interface, mapped file cannot be
+// changed in place.
+func (mb *MmapBuffer) InsertIn(rCur int64, text []rune) Buffer {
+	panic(ErrorReadonlyBuffer)
+}
+
+// DeleteIn implement Buffer{} interface, mapped file cannot be
+// changed in place.
+func (mb *MmapBuffer) DeleteIn(rCur, rn int64) Buffer {
+	panic(ErrorReadonlyBuffer)
+}
+
+//------------
+// search APIs
+//------------
+
+// StreamFrom implement Buffer interface{}.
+func (mb *MmapBuffer) StreamFrom(rCur int64) RuneReader {
+	bCur, ok := mb.byteOffset(rCur)
+	if !ok {
+		return nil
+	}
+	data, end := mb.index.data, mb.end
+	return iterator(func(finish bool) (r rune, size int, err error) {
+		if bCur >= end || finish {
+			bCur = end
+			return r, size, io.EOF
+		}
+		r, size = decodeRune(data[bCur:end])
+		bCur += int64(size)
+		return r, size, nil
+	})
+}
+
+// StreamCount implement Buffer interface{}.
+func (mb *MmapBuffer) StreamCount(rCur, count int64) RuneReader {
+	reader := mb.StreamFrom(rCur)
+	if reader == nil {
+		return nil
+	}
+	return iterator(func(finish bool) (r rune, size int, err error) {
+		if count <= 0 || finish {
+			count = 0
+			return r, size, io.EOF
+		}
+		count--
+		return reader.ReadRune()
+	})
+}
+
+// BackStreamFrom implement Buffer interface{}.
+func (mb *MmapBuffer) BackStreamFrom(rCur int64) RuneReader {
+	bCur, ok := mb.byteOffset(rCur)
+	if !ok {
+		return nil
+	}
+	data, off := mb.index.data, mb.off
+	return iterator(func(finish bool) (r rune, size int, err error) {
+		if bCur <= off || finish {
+			bCur = off
+			return r, size, io.EOF
+		}
+		r, size = decodeLastRune(data[off:bCur])
+		bCur -= int64(size)
+		return r, size, nil
+	})
+}
+
+// BackStreamCount implement Buffer interface{}.
+func (mb *MmapBuffer) BackStreamCount(rCur, count int64) RuneReader {
+	reader := mb.BackStreamFrom(rCur)
+	if reader == nil {
+		return nil
+	}
+	return iterator(func(finish bool) (r rune, size int, err error) {
+		if count <= 0 || finish {
+			count = 0
+			return r, size, io.EOF
+		}
+		count--
+		return reader.ReadRune()
+	})
+}
+
+//----------
+// byte APIs
+//----------
+
+// Size implement Buffer{} interface.
+func (mb *MmapBuffer) Size() int64 {
+	return mb.end - mb.off
+}
+
+// Bytes implement Buffer{} interface, return a copy of the mapped
+// bytes.
+func (mb *MmapBuffer) Bytes() []byte {
+	bs := make([]byte, mb.end-mb.off)
+	copy(bs, mb.bytes())
+	return bs
+}
+
+// Stats implement Buffer{} interface.
+func (mb *MmapBuffer) Stats() (Statistics, error) {
+	if mb == nil {
+		return nil, ErrorBufferNil
+	}
+	n := len(mb.index.bOffs)
+	stats := Statistics{
+		"size":        mb.Size(),                 // no. of bytes in buffer
+		"offset":      mb.off,                    // byte offset in file
+		"mapped":      int64(len(mb.index.data)), // no. of bytes mapped
+		"checkpoints": int64(n),                  // no. of checkpoints
+		"indexed":     mb.index.bOffs[n-1],       // no. of bytes indexed
+	}
+	return stats, nil
+}
+
+//---------------
+// local function
+//---------------
+
+func (mb *MmapBuffer) bytes() []byte {
+	return mb.index.data[mb.off:mb.end]
+}
+
+// byteOffset return the absolute byte offset for rune offset rCur
+// and whether rCur is a valid cursor. Indexing is done only upto
+// rCur.
+func (mb *MmapBuffer) byteOffset(rCur int64) (int64, bool) {
+	if rCur < 0 {
+		return 0, false
+	} else if rCur == 0 {
+		return mb.off, true
+	} else if mb.rLen >= 0 && rCur > mb.rLen {
+		return 0, false
+	}
+	bCur, ok := mb.index.byteOffset(mb.rOff + rCur)
+	if !ok || bCur > mb.end {
+		return 0, false
+	}
+	return bCur, true
+}
+
+// overlay return a piece-table over this buffer.
+func (mb *MmapBuffer) overlay() *PieceTable {
+	return newPieceOverlay(mb.index.data, mb.off, mb.Size(), mb.Length())
+}
+
+// extend the index by one checkpoint, return false if the whole
+// file is already indexed.
+func (index *mmapIndex) extend() bool {
+	n, l := len(index.bOffs)-1, int64(len(index.data))
+	bCur, rCur := index.bOffs[n], index.rOffs[n]
+	if bCur >= l {
+		return false
+	}
+	end := bCur + index.step
+	if end > l {
+		end = l
+	}
+	end = alignRune(index.data, end)
+	rn := int64(utf8.RuneCount(index.data[bCur:end]))
+	index.bOffs = append(index.bOffs, end)
+	index.rOffs = append(index.rOffs, rCur+rn)
+	return true
+}
+
+// byteOffset return the byte offset for rune offset rCur, and
+// false if rCur is beyond the end of file.
+func (index *mmapIndex) byteOffset(rCur int64) (int64, bool) {
+	for index.rOffs[len(index.rOffs)-1] < rCur {
+		if !index.extend() {
+			return 0, false
+		}
+	}
+	i := sort.Search(len(index.rOffs), func(i int) bool {
+		return index.rOffs[i] > rCur
+	}) - 1
+	bCur := index.bOffs[i]
+	return bCur + runeOffset(index.data[bCur:], rCur-index.rOffs[i]), true
+}
+
+// runeOffset return the rune offset for rune aligned byte offset
+// bCur.
+func (index *mmapIndex) runeOffset(bCur int64) int64 {
+	for index.bOffs[len(index.bOffs)-1] < bCur {
+		if !index.extend() {
+			break
+		}
+	}
+	i := sort.Search(len(index.bOffs), func(i int) bool {
+		return index.bOffs[i] > bCur
+	}) - 1
+	from := index.bOffs[i]
+	return index.rOffs[i] + int64(utf8.RuneCount(index.data[from:bCur]))
+}
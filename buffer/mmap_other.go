@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package buffer
+
+import "io/ioutil"
+import "os"
+
+// mmapFile falls back to reading the file into memory on platforms
+// without syscall.Mmap.
+func mmapFile(fd *os.File, size int64) ([]byte, error) {
+	return ioutil.ReadAll(fd)
+}
+
+func munmapFile(data []byte) error {
+	return nil
+}
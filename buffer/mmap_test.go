@@ -0,0 +1,222 @@
+package buffer
+
+import "io"
+import "os"
+import "io/ioutil"
+import "reflect"
+import "testing"
+
+func TestMmapRead(t *testing.T) {
+	// small checkpoints so that the index is exercised.
+	defer func(step int64) { MmapBufferCheckpoint = step }(MmapBufferCheckpoint)
+	MmapBufferCheckpoint = 7
+
+	mb, filename := mmapTestFile(t, []byte(testChinese))
+	defer os.Remove(filename)
+	defer mb.Close()
+
+	ref := []rune(testChinese)
+	if x, y := int64(len(testChinese)), mb.Size(); x != y {
+		t.Fatalf("expected %v, got %v", x, y)
+	} else if x, y := int64(len(ref)), mb.Length(); x != y {
+		t.Fatalf("expected %v, got %v", x, y)
+	} else if x, y := testChinese, string(mb.Runes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	} else if x, y := testChinese, string(mb.Bytes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	}
+	for rCur := int64(0); rCur <= int64(len(ref)); rCur += 3 {
+		runes, _ := readAllRunes(mb.StreamFrom(rCur))
+		if x, y := string(ref[rCur:]), string(runes); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+		runes, _ = readAllRunes(mb.BackStreamFrom(rCur))
+		if x, y := string(ref[:rCur]), string(reverseRunes(runes)); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+		runes, _ = readAllRunes(mb.StreamCount(rCur, 5))
+		if x := int64(len(runes)); x > 5 || x > int64(len(ref))-rCur {
+			t.Fatalf("unexpected %v runes", x)
+		}
+	}
+	if mb.StreamFrom(int64(len(ref))+1) != nil {
+		t.Fatalf("expected nil")
+	} else if mb.BackStreamFrom(-1) != nil {
+		t.Fatalf("expected nil")
+	}
+}
+
+func TestMmapSlice(t *testing.T) {
+	defer func(step int64) { MmapBufferCheckpoint = step }(MmapBufferCheckpoint)
+	MmapBufferCheckpoint = 7
+
+	mb, filename := mmapTestFile(t, []byte(testChinese))
+	defer os.Remove(filename)
+	defer mb.Close()
+
+	ref := []rune(testChinese)
+	l := int64(len(ref))
+	for rCur := int64(0); rCur <= l; rCur += 5 {
+		for rn := int64(0); rCur+rn <= l+2; rn += 3 {
+			slice := mb.Slice(rCur, rn)
+			end := rCur + rn
+			if end > l {
+				end = l
+			}
+			if x, y := string(ref[rCur:end]), string(slice.Runes()); x != y {
+				t.Fatalf("expected %q, got %q", x, y)
+			} else if x, y := end-rCur, slice.Length(); x != y {
+				t.Fatalf("expected %v, got %v", x, y)
+			}
+			runes, _ := readAllRunes(slice.BackStreamFrom(slice.Length()))
+			if x, y := string(ref[rCur:end]), string(reverseRunes(runes)); x != y {
+				t.Fatalf("expected %q, got %q", x, y)
+			}
+		}
+	}
+	if mb.Slice(-1, 1) != nil || mb.Slice(l+1, 1) != nil || mb.Slice(0, -1) != nil {
+		t.Fatalf("expected nil")
+	}
+	// split and concat back, without copying.
+	left, right := mb.Split(10)
+	x := left.Concat(right)
+	if _, ok := x.(*MmapBuffer); !ok {
+		t.Fatalf("unexpected type %T", x)
+	} else if y := string(x.Runes()); y != testChinese {
+		t.Fatalf("expected %q, got %q", testChinese, y)
+	}
+	if left, right := mb.Split(0); left != nil || right != mb {
+		t.Fatalf("unexpected split %v %v", left, right)
+	} else if left, right := mb.Split(l); left != mb || right != nil {
+		t.Fatalf("unexpected split %v %v", left, right)
+	}
+}
+
+func TestMmapOverlay(t *testing.T) {
+	mb, filename := mmapTestFile(t, []byte(testChinese))
+	defer os.Remove(filename)
+	defer mb.Close()
+
+	lb := NewLinearBuffer([]byte(testChinese))
+	var buf Buffer = mb
+	for i := int64(0); i < 10; i++ {
+		buf = buf.Insert(i*4, []rune("中國;"))
+		lb = lb.Insert(i*4, []rune("中國;"))
+		buf = buf.Delete(i*3, 2)
+		lb = lb.Delete(i*3, 2)
+	}
+	if _, ok := buf.(*PieceTable); !ok {
+		t.Fatalf("unexpected type %T", buf)
+	} else if x, y := string(lb.Runes()), string(buf.Runes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	} else if x := string(mb.Runes()); x != testChinese {
+		t.Fatalf("expected %q, got %q", testChinese, x)
+	}
+	// overlay on a slice.
+	slice := mb.Slice(5, 10).Insert(10, []rune("end"))
+	if x, y := string([]rune(testChinese)[5:15])+"end", string(slice.Runes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != ErrorReadonlyBuffer {
+				t.Fatalf("expected panic %v, got %v", ErrorReadonlyBuffer, r)
+			}
+		}()
+		mb.InsertIn(0, []rune("x"))
+	}()
+}
+
+func TestMmapBinary(t *testing.T) {
+	data := []byte("caf\xe9 \xe4\xb8 ok\xff")
+	mb, filename := mmapTestFile(t, data)
+	defer os.Remove(filename)
+	defer mb.Close()
+
+	ref := []rune{'c', 'a', 'f', RawRune(0xe9), ' ', RawRune(0xe4), RawRune(0xb8)}
+	ref = append(ref, ' ', 'o', 'k', RawRune(0xff))
+	runes, _ := readAllRunes(mb.StreamFrom(0))
+	if x := mb.Length(); x != int64(len(ref)) {
+		t.Fatalf("expected %v, got %v", len(ref), x)
+	} else if x := mb.Runes(); !reflect.DeepEqual(x, ref) {
+		t.Fatalf("expected %q, got %q", ref, x)
+	} else if !reflect.DeepEqual(runes, ref) {
+		t.Fatalf("expected %q, got %q", ref, runes)
+	} else if x := mb.Slice(3, 4).Runes(); !reflect.DeepEqual(x, ref[3:7]) {
+		t.Fatalf("expected %q, got %q", ref[3:7], x)
+	}
+}
+
+func TestMmapEmpty(t *testing.T) {
+	mb, filename := mmapTestFile(t, []byte{})
+	defer os.Remove(filename)
+	defer mb.Close()
+
+	if x := mb.Length(); x != 0 {
+		t.Fatalf("expected %v, got %v", 0, x)
+	} else if x := mb.Runes(); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	}
+	if _, _, err := mb.StreamFrom(0).ReadRune(); err != io.EOF {
+		t.Fatalf("expected %v, got %v", io.EOF, err)
+	}
+	if x := string(mb.Insert(0, []rune("hello")).Runes()); x != "hello" {
+		t.Fatalf("expected %q, got %q", "hello", x)
+	}
+	if _, err := NewMmapBuffer(filename + ".missing"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func BenchmarkMmapOpen(b *testing.B) {
+	mb, filename := mmapTestFile(b, sampleData)
+	mb.Close()
+	defer os.Remove(filename)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mb, _ := NewMmapBuffer(filename)
+		mb.StreamFrom(0).ReadRune()
+		mb.Close()
+	}
+}
+
+func BenchmarkMmapStrmFrm(b *testing.B) {
+	mb, filename := mmapTestFile(b, sampleData)
+	defer os.Remove(filename)
+	defer mb.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		reader := mb.StreamFrom(0)
+		for err != io.EOF {
+			_, _, err = reader.ReadRune()
+		}
+	}
+	b.SetBytes(mb.Size())
+}
+
+func BenchmarkMmapSlice(b *testing.B) {
+	mb, filename := mmapTestFile(b, sampleData)
+	defer os.Remove(filename)
+	defer mb.Close()
+	l := mb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mb.Slice(int64(i*1009)%l, 100)
+	}
+}
+
+func mmapTestFile(tb testing.TB, data []byte) (*MmapBuffer, string) {
+	fd, err := ioutil.TempFile("", "mmaptest")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	fd.Write(data)
+	fd.Close()
+	mb, err := NewMmapBuffer(fd.Name())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return mb, fd.Name()
+}
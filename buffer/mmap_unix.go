@@ -0,0 +1,23 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package buffer
+
+import "os"
+import "syscall"
+
+// mmapFile map size bytes of fd as read-only shared memory.
+func mmapFile(fd *os.File, size int64) ([]byte, error) {
+	if size == 0 { // cannot map an empty file.
+		return []byte{}, nil
+	}
+	prot, flags := syscall.PROT_READ, syscall.MAP_SHARED
+	return syscall.Mmap(int(fd.Fd()), 0, int(size), prot, flags)
+}
+
+func munmapFile(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,251 @@
+package buffer
+
+import "strings"
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// LineEnding is the sequence of runes that terminate a line.
+type LineEnding byte
+
+const (
+	// LineEndingLF unix style "\n".
+	LineEndingLF LineEnding = iota
+	// LineEndingCRLF dos/windows style "\r\n".
+	LineEndingCRLF
+	// LineEndingCR classic mac style "\r".
+	LineEndingCR
+)
+
+// DefaultLineEnding is used for new files and for files without
+// any line ending.
+const DefaultLineEnding = LineEndingLF
+
+// LineEndingSample is the number of runes scanned to detect the
+// line ending of files that are not loaded in full.
+const LineEndingSample = int64(1024 * 1024)
+
+// ParseLineEnding return the line ending by its name, one of
+// "lf", "crlf", "cr", or "unix", "dos", "mac".
+func ParseLineEnding(name string) (LineEnding, error) {
+	switch strings.ToLower(name) {
+	case "lf", "unix":
+		return LineEndingLF, nil
+	case "crlf", "dos":
+		return LineEndingCRLF, nil
+	case "cr", "mac":
+		return LineEndingCR, nil
+	}
+	return DefaultLineEnding, ErrorInvalidNewline
+}
+
+// String implement fmt.Stringer interface.
+func (ending LineEnding) String() string {
+	switch ending {
+	case LineEndingCRLF:
+		return "crlf"
+	case LineEndingCR:
+		return "cr"
+	}
+	return "lf"
+}
+
+// Runes return the line ending as runes.
+func (ending LineEnding) Runes() []rune {
+	switch ending {
+	case LineEndingCRLF:
+		return []rune{'\r', '\n'}
+	case LineEndingCR:
+		return []rune{'\r'}
+	}
+	return []rune{'\n'}
+}
+
+// LineEndingCount is the number of line endings of each style.
+type LineEndingCount struct {
+	LF   int64
+	CRLF int64
+	CR   int64
+}
+
+// CountLineEndings from reader, until io.EOF.
+func CountLineEndings(reader RuneReader) (count LineEndingCount) {
+	if reader == nil {
+		return count
+	}
+	defer reader.Close()
+	prev := rune(0)
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			break
+		}
+		switch {
+		case r == '\n' && prev == '\r':
+			count.CRLF, count.CR = count.CRLF+1, count.CR-1
+		case r == '\n':
+			count.LF++
+		case r == '\r':
+			count.CR++
+		}
+		prev = r
+	}
+	return count
+}
+
+// Dominant return the most used line ending, DefaultLineEnding
+// is preferred on a tie.
+func (count LineEndingCount) Dominant() LineEnding {
+	ending, n := DefaultLineEnding, count.LF
+	if count.CRLF > n {
+		ending, n = LineEndingCRLF, count.CRLF
+	}
+	if count.CR > n {
+		ending = LineEndingCR
+	}
+	return ending
+}
+
+// Mixed return true if more than one style of line ending is used.
+func (count LineEndingCount) Mixed() bool {
+	styles := 0
+	for _, n := range []int64{count.LF, count.CRLF, count.CR} {
+		if n > 0 {
+			styles++
+		}
+	}
+	return styles > 1
+}
+
+// ConvertLineEndings return a new buffer with every line ending
+// converted to ending, along with the number of line endings that
+// were converted. If nothing was converted, buffer is returned.
+func ConvertLineEndings(buffer Buffer, ending LineEnding) (Buffer, int64) {
+	reader := buffer.StreamFrom(0)
+	if reader == nil {
+		return buffer, 0
+	}
+	defer reader.Close()
+
+	nl, n := ending.Runes(), int64(0)
+	text := make([]rune, 0, buffer.Length())
+	for cr := false; ; {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			if cr {
+				text, n = append(text, nl...), n+converted(ending, LineEndingCR)
+			}
+			break
+		}
+		switch {
+		case r == '\n' && cr:
+			text, n = append(text, nl...), n+converted(ending, LineEndingCRLF)
+			cr = false
+			continue
+		case r == '\n':
+			text, n = append(text, nl...), n+converted(ending, LineEndingLF)
+			continue
+		case cr:
+			text, n = append(text, nl...), n+converted(ending, LineEndingCR)
+		}
+		if cr = r == '\r'; !cr {
+			text = append(text, r)
+		}
+	}
+	if n == 0 {
+		return buffer, 0
+	}
+	return buffer.Slice(0, 0).Insert(0, text), n
+}
+
+// LineStart return the cursor at the beginning of line containing
+// rCur, any of "\n", "\r\n" or "\r" end a line. Return -1 if rCur
+// is invalid.
+func LineStart(buffer Buffer, rCur int64) int64 {
+	reader := buffer.BackStreamFrom(rCur)
+	if reader == nil {
+		return -1
+	}
+	defer reader.Close()
+
+	atLF := false // rCur between "\r" and "\n" is within the line ending.
+	if next := buffer.StreamCount(rCur, 1); next != nil {
+		r, _, err := next.ReadRune()
+		atLF = err == nil && r == '\n'
+		next.Close()
+	}
+	for start := rCur; ; start-- {
+		r, _, err := reader.ReadRune()
+		if err != nil || r == '\n' {
+			return start
+		} else if r == '\r' && !(start == rCur && atLF) {
+			return start
+		}
+	}
+}
+
+// LineEnd return the cursor at the line ending, and the cursor at
+// the beginning of next line, for the line containing rCur. For
+// the last line without a line ending both are buffer's length.
+// Return -1, -1 if rCur is invalid.
+func LineEnd(buffer Buffer, rCur int64) (end, next int64) {
+	start := LineStart(buffer, rCur)
+	if start < 0 {
+		return -1, -1
+	}
+	reader := buffer.StreamFrom(start)
+	defer reader.Close()
+	for end = start; ; end++ {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return end, end
+		} else if r == '\n' {
+			return end, end + 1
+		} else if r == '\r' {
+			if r, _, err := reader.ReadRune(); err == nil && r == '\n' {
+				return end, end + 2
+			}
+			return end, end + 1
+		}
+	}
+}
+
+//---------------
+// local function
+//---------------
+
+// buildBlock return {start, end} pairs of consecutive lines around
+// rCur, width number of lines above and below the line containing
+// rCur, where end is the start of next line. If buffer ends with
+// a line ending, the last line is {length, length}.
+func buildBlock(buffer Buffer, rCur, width int64) []int64 {
+	start := LineStart(buffer, rCur)
+	if start < 0 {
+		return nil
+	}
+	starts := []int64{start}
+	for i := int64(0); i < width && starts[len(starts)-1] > 0; i++ {
+		starts = append(starts, LineStart(buffer, starts[len(starts)-1]-1))
+	}
+	block := make([]int64, 0, (width*2+1)*2)
+	for i := len(starts) - 1; i > 0; i-- {
+		block = append(block, starts[i], starts[i-1])
+	}
+	for i := int64(0); i <= width; i++ {
+		end, next := LineEnd(buffer, start)
+		block = append(block, start, next)
+		if end == next { // last line.
+			break
+		}
+		start = next
+	}
+	return block
+}
+
+// converted return 1 if line ending style differs from ending.
+func converted(ending, style LineEnding) int64 {
+	if ending != style {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+package buffer
+
+import "reflect"
+import "testing"
+
+func TestLineEndings(t *testing.T) {
+	testcases := []struct {
+		text   string
+		count  LineEndingCount
+		ending LineEnding
+		mixed  bool
+	}{
+		{"", LineEndingCount{}, LineEndingLF, false},
+		{"no newline", LineEndingCount{}, LineEndingLF, false},
+		{"a\nb\n", LineEndingCount{LF: 2}, LineEndingLF, false},
+		{"a\r\nb\r\n", LineEndingCount{CRLF: 2}, LineEndingCRLF, false},
+		{"a\rb\r", LineEndingCount{CR: 2}, LineEndingCR, false},
+		{"a\r\nb\nc\r\n", LineEndingCount{LF: 1, CRLF: 2}, LineEndingCRLF, true},
+		{"a\r\rb\n", LineEndingCount{LF: 1, CR: 2}, LineEndingCR, true},
+		{"a\n\r", LineEndingCount{LF: 1, CR: 1}, LineEndingLF, true},
+	}
+	for _, tcase := range testcases {
+		rb := NewRopebuffer([]byte(tcase.text), 8)
+		count := CountLineEndings(rb.StreamFrom(0))
+		if count != tcase.count {
+			t.Fatalf("%q expected %v, got %v", tcase.text, tcase.count, count)
+		} else if x := count.Dominant(); x != tcase.ending {
+			t.Fatalf("%q expected %v, got %v", tcase.text, tcase.ending, x)
+		} else if x := count.Mixed(); x != tcase.mixed {
+			t.Fatalf("%q expected %v, got %v", tcase.text, tcase.mixed, x)
+		}
+	}
+
+	for _, name := range []string{"lf", "crlf", "cr", "DOS"} {
+		if ending, err := ParseLineEnding(name); err != nil {
+			t.Fatal(err)
+		} else if x, _ := ParseLineEnding(ending.String()); x != ending {
+			t.Fatalf("expected %v, got %v", ending, x)
+		}
+	}
+	if _, err := ParseLineEnding("\n"); err != ErrorInvalidNewline {
+		t.Fatalf("expected %v, got %v", ErrorInvalidNewline, err)
+	}
+}
+
+func TestConvertLineEndings(t *testing.T) {
+	text := "a\r\nb\nc\rd\r\r\n\n"
+	testcases := []struct {
+		ending LineEnding
+		ref    string
+		n      int64
+	}{
+		{LineEndingLF, "a\nb\nc\nd\n\n\n", 4},
+		{LineEndingCRLF, "a\r\nb\r\nc\r\nd\r\n\r\n\r\n", 4},
+		{LineEndingCR, "a\rb\rc\rd\r\r\r", 4},
+	}
+	for _, tcase := range testcases {
+		rb := NewRopebuffer([]byte(text), 8)
+		buffer, n := ConvertLineEndings(rb, tcase.ending)
+		if x := string(buffer.Bytes()); x != tcase.ref {
+			t.Fatalf("%v expected %q, got %q", tcase.ending, tcase.ref, x)
+		} else if n != tcase.n {
+			t.Fatalf("%v expected %v, got %v", tcase.ending, tcase.n, n)
+		} else if string(rb.Bytes()) != text {
+			t.Fatalf("source buffer changed %q", rb.Bytes())
+		}
+		// converting again is a no-op.
+		if x, n := ConvertLineEndings(buffer, tcase.ending); n != 0 || x != buffer {
+			t.Fatalf("expected no conversion, got %v", n)
+		}
+	}
+	// trailing "\r" and raw-runes.
+	lb := NewLinearBuffer([]byte("\xff\r"))
+	if buffer, n := ConvertLineEndings(lb, LineEndingCRLF); n != 1 {
+		t.Fatalf("expected %v, got %v", 1, n)
+	} else if x := string(buffer.Bytes()); x != "\xff\r\n" {
+		t.Fatalf("expected %q, got %q", "\xff\r\n", x)
+	}
+}
+
+func TestLineStartEnd(t *testing.T) {
+	for _, nl := range []string{"\n", "\r\n", "\r"} {
+		text := "ab" + nl + nl + "c" + nl + "de"
+		rb := NewRopebuffer([]byte(text), 8)
+		l := int64(len(nl))
+		// {start, end, next} for each line.
+		lines := [][3]int64{
+			{0, 2, 2 + l}, {2 + l, 2 + l, 2 + 2*l}, {2 + 2*l, 3 + 2*l, 3 + 3*l},
+			{3 + 3*l, 5 + 3*l, 5 + 3*l},
+		}
+		for _, line := range lines {
+			for rCur := line[0]; rCur < line[2] || rCur == rb.Length(); rCur++ {
+				if x := LineStart(rb, rCur); x != line[0] {
+					t.Fatalf("%q %v expected %v, got %v", nl, rCur, line[0], x)
+				}
+				end, next := LineEnd(rb, rCur)
+				if end != line[1] || next != line[2] {
+					t.Fatalf("%q %v expected %v, got %v %v", nl, rCur, line, end, next)
+				}
+				if rCur == rb.Length() {
+					break
+				}
+			}
+		}
+		if x := LineStart(rb, rb.Length()+1); x != -1 {
+			t.Fatalf("expected %v, got %v", -1, x)
+		} else if x, y := LineEnd(rb, -1); x != -1 || y != -1 {
+			t.Fatalf("expected %v, got %v %v", -1, x, y)
+		}
+	}
+}
+
+func TestBuildBlock(t *testing.T) {
+	for _, nl := range []string{"\n", "\r\n", "\r"} {
+		l := int64(len(nl))
+		text := "a" + nl + "b" + nl + "c" + nl + "d" + nl
+		rb := NewRopebuffer([]byte(text), 8)
+		// starts of the lines, including the empty last line.
+		s := []int64{0, 1 + l, 2 + 2*l, 3 + 3*l, 4 + 4*l}
+		testcases := []struct {
+			rCur, width int64
+			ref         []int64
+		}{
+			{0, 0, []int64{s[0], s[1]}},
+			{0, 1, []int64{s[0], s[1], s[1], s[2]}},
+			{s[2], 1, []int64{s[1], s[2], s[2], s[3], s[3], s[4]}},
+			{s[2] + 1, 2, []int64{s[0], s[1], s[1], s[2], s[2], s[3], s[3], s[4], s[4], s[4]}},
+			{s[4], 1, []int64{s[3], s[4], s[4], s[4]}},
+		}
+		for _, tcase := range testcases {
+			block := buildBlock(rb, tcase.rCur, tcase.width)
+			if !reflect.DeepEqual(block, tcase.ref) {
+				t.Fatalf("%q %v expected %v, got %v", nl, tcase.rCur, tcase.ref, block)
+			}
+		}
+	}
+	if x := buildBlock(NewLinearBuffer(nil), 0, 2); !reflect.DeepEqual(x, []int64{0, 0}) {
+		t.Fatalf("expected %v, got %v", []int64{0, 0}, x)
+	} else if x := buildBlock(NewLinearBuffer([]byte("ab")), 1, 2); !reflect.DeepEqual(x, []int64{0, 2}) {
+		t.Fatalf("expected %v, got %v", []int64{0, 2}, x)
+	}
+}
+
+func TestColumnsNewline(t *testing.T) {
+	for _, nl := range []string{"\r\n", "\r"} {
+		rb := NewRopebuffer([]byte("ab"+nl+"\tc"), 8)
+		start := int64(2 + len(nl))
+		if x := RuneToColumn(rb, start+1, 8); x != 8 {
+			t.Fatalf("%q expected %v, got %v", nl, 8, x)
+		} else if x := ColumnToRune(rb, 0, 10, 8); x != 2 {
+			t.Fatalf("%q expected %v, got %v", nl, 2, x)
+		} else if x := ColumnToRune(rb, start, 8, 8); x != start+1 {
+			t.Fatalf("%q expected %v, got %v", nl, start+1, x)
+		}
+	}
+}
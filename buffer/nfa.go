@@ -0,0 +1,232 @@
+package buffer
+
+import "regexp/syntax"
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// nfa simulate the Thompson NFA of a compiled program, advancing
+// every thread in lock step one rune at a time, each thread carry
+// its own copy of capture offsets. Semantics follow the pike VM in
+// regexp package, including leftmost-longest.
+type nfa struct {
+	prog     *syntax.Prog
+	ncap     int
+	longest  bool
+	q0, q1   *nfaQueue
+	pool     []*nfaThread
+	matched  bool
+	matchcap []int64
+}
+
+type nfaThread struct {
+	pc  uint32
+	cap []int64
+}
+
+// nfaQueue is a sparse set of threads, ordered by priority.
+type nfaQueue struct {
+	sparse []uint32
+	dense  []nfaEntry
+}
+
+type nfaEntry struct {
+	pc uint32
+	t  *nfaThread
+}
+
+func newNFA(prog *syntax.Prog, ncap int, longest bool) *nfa {
+	n := len(prog.Inst)
+	return &nfa{
+		prog:     prog,
+		ncap:     ncap,
+		longest:  longest,
+		q0:       &nfaQueue{make([]uint32, n), make([]nfaEntry, 0, n)},
+		q1:       &nfaQueue{make([]uint32, n), make([]nfaEntry, 0, n)},
+		matchcap: make([]int64, ncap),
+	}
+}
+
+// search in for the first match at or after pos, return offsets
+// of the match and its capture groups, nil if there is no match.
+func (m *nfa) search(in *runeInput, pos int64) []int64 {
+	m.matched = false
+	for i := range m.matchcap {
+		m.matchcap[i] = -1
+	}
+	runq, nextq := m.q0, m.q1
+	r := in.at(pos)
+	flag := syntax.EmptyOpContext(in.at(pos-1), r)
+	for {
+		if len(runq.dense) == 0 && m.matched {
+			break
+		}
+		if !m.matched {
+			m.matchcap[0] = pos
+			m.add(runq, uint32(m.prog.Start), pos, m.matchcap, flag, nil)
+		}
+		r1 := in.at(pos + 1)
+		if r < 0 {
+			r1 = -1
+		}
+		flag = syntax.EmptyOpContext(r, r1)
+		m.step(runq, nextq, pos, r, flag)
+		if r < 0 {
+			break
+		}
+		pos, r = pos+1, r1
+		runq, nextq = nextq, runq
+		in.trim(m.keep(runq, pos) - 1)
+	}
+	m.free(nextq)
+	if !m.matched {
+		return nil
+	}
+	return append([]int64(nil), m.matchcap...)
+}
+
+// step every thread in runq over rune r at pos, adding the
+// surviving threads to nextq.
+func (m *nfa) step(
+	runq, nextq *nfaQueue, pos int64, r rune, flag syntax.EmptyOp) {
+
+	for j := 0; j < len(runq.dense); j++ {
+		t := runq.dense[j].t
+		if t == nil {
+			continue
+		}
+		if m.longest && m.matched && m.matchcap[0] < t.cap[0] {
+			m.pool = append(m.pool, t)
+			continue
+		}
+		inst, add := &m.prog.Inst[t.pc], false
+		switch inst.Op {
+		case syntax.InstMatch:
+			if !m.longest || !m.matched || m.matchcap[1] < pos {
+				t.cap[1] = pos
+				copy(m.matchcap, t.cap)
+			}
+			if !m.longest { // cut off lower priority threads.
+				for _, d := range runq.dense[j+1:] {
+					if d.t != nil {
+						m.pool = append(m.pool, d.t)
+					}
+				}
+				runq.dense = runq.dense[:0]
+			}
+			m.matched = true
+		default:
+			add = r >= 0 && matchRune(inst, r)
+		}
+		if add {
+			t = m.add(nextq, inst.Out, pos+1, t.cap, flag, t)
+		}
+		if t != nil {
+			m.pool = append(m.pool, t)
+		}
+	}
+	runq.dense = runq.dense[:0]
+}
+
+// add pc to q, following empty transitions, t is reused for the
+// new thread if not nil.
+func (m *nfa) add(
+	q *nfaQueue, pc uint32, pos int64, cap []int64, flag syntax.EmptyOp,
+	t *nfaThread) *nfaThread {
+
+	if pc == 0 || q.contains(pc) {
+		return t
+	}
+	j := q.add(pc)
+	switch inst := &m.prog.Inst[pc]; inst.Op {
+	case syntax.InstAlt, syntax.InstAltMatch:
+		t = m.add(q, inst.Out, pos, cap, flag, t)
+		t = m.add(q, inst.Arg, pos, cap, flag, t)
+	case syntax.InstEmptyWidth:
+		if syntax.EmptyOp(inst.Arg)&^flag == 0 {
+			t = m.add(q, inst.Out, pos, cap, flag, t)
+		}
+	case syntax.InstNop:
+		t = m.add(q, inst.Out, pos, cap, flag, t)
+	case syntax.InstCapture:
+		if int(inst.Arg) < len(cap) {
+			opos := cap[inst.Arg]
+			cap[inst.Arg] = pos
+			m.add(q, inst.Out, pos, cap, flag, nil)
+			cap[inst.Arg] = opos
+		} else {
+			t = m.add(q, inst.Out, pos, cap, flag, t)
+		}
+	case syntax.InstMatch, syntax.InstRune, syntax.InstRune1,
+		syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+		if t == nil {
+			t = m.alloc()
+		}
+		t.pc = pc
+		copy(t.cap, cap)
+		q.dense[j].t, t = t, nil
+	}
+	return t
+}
+
+// keep return the earliest offset that a match, or the search
+// following it, can refer to.
+func (m *nfa) keep(runq *nfaQueue, pos int64) int64 {
+	if m.matched && m.matchcap[1] < pos {
+		pos = m.matchcap[1]
+	}
+	for _, d := range runq.dense { // threads are ordered by start.
+		if d.t != nil {
+			if d.t.cap[0] < pos {
+				pos = d.t.cap[0]
+			}
+			break
+		}
+	}
+	return pos
+}
+
+func (m *nfa) alloc() *nfaThread {
+	if n := len(m.pool); n > 0 {
+		t := m.pool[n-1]
+		m.pool = m.pool[:n-1]
+		return t
+	}
+	return &nfaThread{cap: make([]int64, m.ncap)}
+}
+
+func (m *nfa) free(q *nfaQueue) {
+	for _, d := range q.dense {
+		if d.t != nil {
+			m.pool = append(m.pool, d.t)
+		}
+	}
+	q.dense = q.dense[:0]
+}
+
+func (q *nfaQueue) contains(pc uint32) bool {
+	j := q.sparse[pc]
+	return j < uint32(len(q.dense)) && q.dense[j].pc == pc
+}
+
+func (q *nfaQueue) add(pc uint32) uint32 {
+	j := uint32(len(q.dense))
+	q.dense = append(q.dense, nfaEntry{pc: pc})
+	q.sparse[pc] = j
+	return j
+}
+
+// matchRune check whether rune instruction inst accepts r.
+func matchRune(inst *syntax.Inst, r rune) bool {
+	switch inst.Op {
+	case syntax.InstRune:
+		return inst.MatchRune(r)
+	case syntax.InstRune1:
+		return r == inst.Rune[0]
+	case syntax.InstRuneAny:
+		return true
+	case syntax.InstRuneAnyNotNL:
+		return r != '\n'
+	}
+	return false
+}
@@ -0,0 +1,726 @@
+package buffer
+
+import "container/list"
+import "fmt"
+import "io"
+import "io/ioutil"
+import "math"
+import "os"
+import "runtime"
+import "sort"
+import "strings"
+import "sync"
+import "unicode/utf8"
+
+var _ = fmt.Sprintf("dummy")
+
+// PagedBufferChunk is the default size of a chunk, in bytes, that
+// is loaded from file in one go.
+var PagedBufferChunk = int64(64 * 1024)
+
+// PagedBufferBudget is the default memory budget, in bytes, for the
+// chunks resident in memory.
+var PagedBufferBudget = int64(64 * 1024 * 1024)
+
+// pagedLeafCapacity is the capacity of rope leaves holding a
+// resident chunk.
+const pagedLeafCapacity = int64(1024)
+
+// where a chunk's content is kept, when it is not resident.
+const (
+	chunkFile   = iota // extent in the original file.
+	chunkSpill         // extent in the spill file.
+	chunkMemory        // only in memory, dirty.
+)
+
+// PagedBuffer implements Buffer over a file that is loaded in
+// chunks on demand. Resident chunks are held as ropes and are
+// evicted in least recently used order once they cross the memory
+// budget. Chunks that are changed are dirty, they are either held
+// in memory until they are saved, or written to a spill file when
+// evicted. Chunks replaced by an edit are released from the budget,
+// those that are dirty are left to the garbage collector along with
+// the older buffers still referring to them. Rune count of a chunk is learnt when the chunk is loaded
+// for the first time, cursors are validated against the chunks
+// counted so far, hence edits load only the chunks upto the cursor.
+// Errors in reading or spilling chunks are remembered, refer Err.
+//
+// File is expected to be utf8 encoded and not modified while the
+// buffer is open. Not thread safe.
+type PagedBuffer struct {
+	store  *pageStore
+	chunks []*pageChunk
+}
+
+// pageStore is shared by all buffers derived from the same file.
+type pageStore struct {
+	file      *os.File
+	chunkSize int64
+	budget    int64
+	resident  int64      // no. of bytes in resident chunks.
+	lru       *list.List // resident chunks, most recent in front.
+	spill     bool       // whether to spill dirty chunks.
+	spillFd   *os.File
+	spillOff  int64
+	mu        sync.Mutex     // guards free, released by finalizers.
+	free      []*spillExtent // unused extents in spill file, by offset.
+	err       error          // first error in reading or spilling chunks.
+	// statistics
+	loads     int64
+	evictions int64
+	spills    int64
+}
+
+// pageChunk is a contiguous span of text, chunks are never modified
+// once created, only their residency changes.
+type pageChunk struct {
+	store *pageStore
+	where int
+	off   int64 // byte offset of extent in file or spill file.
+	size  int64 // no. of bytes.
+	rn    int64 // no. of runes, -1 if not yet known.
+	rope  *RopeBuffer
+	elem  *list.Element // nil if not counted against budget.
+	// extent in spill file shared with chunks trimmed from it.
+	extent *spillExtent
+}
+
+// spillExtent is a region of the spill file, it is reused once no
+// chunk refers to it.
+type spillExtent struct {
+	off, size int64
+}
+
+// NewPagedBuffer returns a buffer over filename, without loading
+// any part of it. Chunksize and budget are in bytes, if <= 0 the
+// defaults PagedBufferChunk and PagedBufferBudget are used. If
+// spill is true, dirty chunks are written to a temporary spill file
+// when evicted, else they stay in memory irrespective of budget.
+func NewPagedBuffer(
+	filename string, chunksize, budget int64, spill bool) (*PagedBuffer, error) {
+
+	if chunksize <= 0 {
+		chunksize = PagedBufferChunk
+	}
+	if budget <= 0 {
+		budget = PagedBufferBudget
+	}
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+	store := &pageStore{
+		file: fd, chunkSize: chunksize, budget: budget, lru: list.New(),
+		spill: spill,
+	}
+	chunks, err := store.fileChunks(info.Size())
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+	return &PagedBuffer{store: store, chunks: chunks}, nil
+}
+
+// Close the file and remove the spill file. Buffers derived from
+// this buffer shall not be used there after.
+func (pb *PagedBuffer) Close() error {
+	if fd := pb.store.spillFd; fd != nil {
+		fd.Close()
+		os.Remove(fd.Name())
+		pb.store.spillFd = nil
+	}
+	return pb.store.file.Close()
+}
+
+// Err return the first error in reading or spilling chunks, if
+// any. A chunk that failed to load reads as utf8.RuneError, hence
+// the buffer shall not be saved once Err is not nil.
+func (pb *PagedBuffer) Err() error {
+	return pb.store.err
+}
+
+//----------
+// rune APIs
+//----------
+
+// Length implement Buffer{} interface. Chunks that are not yet
+// counted are loaded.
+func (pb *PagedBuffer) Length() int64 {
+	l := int64(0)
+	for _, c := range pb.chunks {
+		l += c.count()
+	}
+	return l
+}
+
+// Slice implement Buffer{} interface.
+func (pb *PagedBuffer) Slice(rCur, rn int64) Buffer {
+	endCur := rCur + rn
+	if !pb.isValidCursor(rCur) {
+		return nil
+	} else if endCur < rCur {
+		return nil
+	} else if !pb.isValidCursor(endCur) {
+		endCur = -1
+	}
+	return pb.newBuffer(pb.slice(rCur, endCur))
+}
+
+// Runes implement Buffer{} interface.
+func (pb *PagedBuffer) Runes() []rune {
+	var acc []rune
+	for _, c := range pb.chunks {
+		acc = append(acc, c.load().Runes()...)
+	}
+	return acc
+}
+
+// Concat implement Buffer{} interface.
+func (pb *PagedBuffer) Concat(right Buffer) Buffer {
+	rpb := right.(*PagedBuffer)
+	if pb == nil {
+		return rpb
+	} else if rpb == nil {
+		return pb
+	}
+	chunks := make([]*pageChunk, 0, len(pb.chunks)+len(rpb.chunks))
+	chunks = append(chunks, pb.chunks...)
+	chunks = append(chunks, rpb.chunks...)
+	return pb.newBuffer(chunks)
+}
+
+// Split implement Buffer{} interface.
+func (pb *PagedBuffer) Split(rCur int64) (left, right Buffer) {
+	if pb == nil || rCur < 0 {
+		return left, right
+	}
+	if !pb.isValidCursor(rCur + 1) {
+		return pb, nil
+	} else if rCur == 0 {
+		return nil, pb
+	}
+	left = pb.newBuffer(pb.slice(0, rCur))
+	right = pb.newBuffer(pb.slice(rCur, -1))
+	return left, right
+}
+
+// Insert implement Buffer{} interface.
+func (pb *PagedBuffer) Insert(rCur int64, text []rune) Buffer {
+	if text == nil {
+		return pb
+	} else if pb == nil {
+		panic(ErrorBufferNil)
+	} else if !pb.isValidCursor(rCur) {
+		panic(ErrorIndexOutofbound)
+	}
+	return pb.newBuffer(pb.insert(rCur, text))
+}
+
+// Delete implement Buffer{} interface.
+func (pb *PagedBuffer) Delete(rCur, rn int64) Buffer {
+	if rn == 0 {
+		return pb
+	} else if pb == nil {
+		panic(ErrorBufferNil)
+	} else if !pb.isValidCursor(rCur) || !pb.isValidCursor(rCur+rn) {
+		panic(ErrorIndexOutofbound)
+	}
+	return pb.newBuffer(pb.delete(rCur, rn))
+}
+
+// InsertIn implement Buffer{} interface. Chunks are shared with
+// other buffers, hence the affected chunk is replaced rather than
+// modified.
+func (pb *PagedBuffer) InsertIn(rCur int64, text []rune) Buffer {
+	if text == nil {
+		return pb
+	} else if pb == nil {
+		panic(ErrorBufferNil)
+	} else if !pb.isValidCursor(rCur) {
+		panic(ErrorIndexOutofbound)
+	}
+	pb.chunks = pb.insert(rCur, text)
+	return pb
+}
+
+// DeleteIn implement Buffer{} interface.
+func (pb *PagedBuffer) DeleteIn(rCur, rn int64) Buffer {
+	if rn == 0 {
+		return pb
+	} else if pb == nil {
+		panic(ErrorBufferNil)
+	} else if !pb.isValidCursor(rCur) || !pb.isValidCursor(rCur+rn) {
+		panic(ErrorIndexOutofbound)
+	}
+	pb.chunks = pb.delete(rCur, rn)
+	return pb
+}
+
+//------------
+// search APIs
+//------------
+
+// StreamFrom implement Buffer interface{}.
+func (pb *PagedBuffer) StreamFrom(rCur int64) RuneReader {
+	return pb.StreamCount(rCur, math.MaxInt64)
+}
+
+// StreamCount implement Buffer interface{}.
+func (pb *PagedBuffer) StreamCount(rCur, count int64) RuneReader {
+	if !pb.isValidCursor(rCur) {
+		return nil
+	}
+	i, r := pb.locate(rCur)
+	chunks, cur := pb.chunks, (*RopeCursor)(nil)
+	return iterator(func(finish bool) (ch rune, size int, err error) {
+		for count > 0 && !finish && i < len(chunks) {
+			if cur == nil {
+				cur = chunks[i].load().Cursor(r)
+			}
+			if ch, size, err = cur.Next(); err == nil {
+				count--
+				return ch, size, nil
+			}
+			i, r, cur = i+1, 0, nil
+		}
+		count = 0
+		return ch, size, io.EOF
+	})
+}
+
+// BackStreamFrom implement Buffer interface{}.
+func (pb *PagedBuffer) BackStreamFrom(rCur int64) RuneReader {
+	return pb.BackStreamCount(rCur, rCur)
+}
+
+// BackStreamCount implement Buffer interface{}.
+func (pb *PagedBuffer) BackStreamCount(rCur, count int64) RuneReader {
+	if !pb.isValidCursor(rCur) {
+		return nil
+	}
+	i, r := pb.locate(rCur)
+	chunks, cur := pb.chunks, (*RopeCursor)(nil)
+	return iterator(func(finish bool) (ch rune, size int, err error) {
+		for count > 0 && !finish && i >= 0 && i < len(chunks) {
+			if cur == nil {
+				cur = chunks[i].load().Cursor(r)
+			}
+			if ch, size, err = cur.Prev(); err == nil {
+				count--
+				return ch, size, nil
+			}
+			if i--; i >= 0 {
+				r, cur = chunks[i].count(), nil
+			}
+		}
+		count = 0
+		return ch, size, io.EOF
+	})
+}
+
+//----------
+// byte APIs
+//----------
+
+// Size implement Buffer{} interface.
+func (pb *PagedBuffer) Size() int64 {
+	size := int64(0)
+	for _, c := range pb.chunks {
+		size += c.size
+	}
+	return size
+}
+
+// Bytes implement Buffer{} interface.
+func (pb *PagedBuffer) Bytes() []byte {
+	acc := make([]byte, 0, pb.Size())
+	for _, c := range pb.chunks {
+		acc = append(acc, c.load().Bytes()...)
+	}
+	return acc
+}
+
+// Stats implement Buffer{} interface.
+func (pb *PagedBuffer) Stats() (Statistics, error) {
+	if pb == nil {
+		return nil, ErrorBufferNil
+	}
+	resident, evicted, dirty, spilled := int64(0), int64(0), int64(0), int64(0)
+	for _, c := range pb.chunks {
+		if c.elem != nil {
+			resident++
+		} else {
+			evicted++
+		}
+		switch c.where {
+		case chunkMemory:
+			dirty++
+		case chunkSpill:
+			spilled++
+		}
+	}
+	store := pb.store
+	stats := Statistics{
+		"chunks":        int64(len(pb.chunks)), // no. of chunks in buffer
+		"resident":      resident,              // no. of chunks in memory
+		"evicted":       evicted,               // no. of chunks not in memory
+		"dirty":         dirty,                 // no. of chunks only in memory
+		"spilled":       spilled,               // no. of chunks in spill file
+		"residentBytes": store.resident,        // bytes in memory, all buffers
+		"budget":        store.budget,          // memory budget in bytes
+		"loads":         store.loads,           // no. of chunks loaded
+		"evictions":     store.evictions,       // no. of chunks evicted
+		"spills":        store.spills,          // no. of chunks written to spill
+	}
+	return stats, store.err
+}
+
+//---------------
+// local function
+//---------------
+
+// isValidCursor count chunks only upto rCur.
+func (pb *PagedBuffer) isValidCursor(rCur int64) bool {
+	if rCur < 0 {
+		return false
+	}
+	for _, c := range pb.chunks {
+		if rCur -= c.count(); rCur <= 0 {
+			return true
+		}
+	}
+	return rCur == 0
+}
+
+func (pb *PagedBuffer) newBuffer(chunks []*pageChunk) *PagedBuffer {
+	return &PagedBuffer{store: pb.store, chunks: chunks}
+}
+
+// locate return the index of chunk containing rCur and the rune
+// offset of rCur within the chunk. If rCur falls on a chunk
+// boundary, the chunk ending at rCur is returned.
+func (pb *PagedBuffer) locate(rCur int64) (int, int64) {
+	for i, c := range pb.chunks {
+		rn := c.count()
+		if rCur <= rn {
+			return i, rCur
+		}
+		rCur -= rn
+	}
+	return len(pb.chunks), 0
+}
+
+// slice return chunks for runes between [from, till), chunks at
+// the edges are trimmed. If till is -1 slice upto the end, without
+// counting chunks after from.
+func (pb *PagedBuffer) slice(from, till int64) []*pageChunk {
+	chunks := make([]*pageChunk, 0)
+	rCur := int64(0)
+	for _, c := range pb.chunks {
+		if till < 0 && rCur >= from {
+			chunks = append(chunks, c)
+			continue
+		}
+		rn := c.count()
+		if rCur+rn <= from {
+			rCur += rn
+			continue
+		} else if till >= 0 && rCur >= till {
+			break
+		}
+		a, z := from-rCur, till-rCur
+		if a < 0 {
+			a = 0
+		}
+		if till < 0 || z > rn {
+			z = rn
+		}
+		if a == 0 && z == rn {
+			chunks = append(chunks, c)
+		} else if a < z {
+			chunks = append(chunks, c.trim(a, z))
+		}
+		rCur += rn
+	}
+	return chunks
+}
+
+// insert return chunks after inserting text at rCur.
+func (pb *PagedBuffer) insert(rCur int64, text []rune) []*pageChunk {
+	if len(text) == 0 {
+		return pb.chunks
+	} else if len(pb.chunks) == 0 {
+		rope := newRopeRunes(text, pagedLeafCapacity)
+		return pb.store.memoryChunks(rope)
+	}
+	i, r := pb.locate(rCur)
+	if i == len(pb.chunks) {
+		i, r = i-1, pb.chunks[i-1].count()
+	}
+	rope := pb.chunks[i].load().Insert(r, text).(*RopeBuffer)
+	pb.chunks[i].release()
+	chunks := make([]*pageChunk, 0, len(pb.chunks)+1)
+	chunks = append(chunks, pb.chunks[:i]...)
+	chunks = append(chunks, pb.store.memoryChunks(rope)...)
+	chunks = append(chunks, pb.chunks[i+1:]...)
+	return chunks
+}
+
+// delete return chunks after deleting rn runes from rCur.
+func (pb *PagedBuffer) delete(rCur, rn int64) []*pageChunk {
+	left, right := pb.slice(0, rCur), pb.slice(rCur+rn, -1)
+	chunks := make([]*pageChunk, 0, len(left)+len(right))
+	chunks = append(chunks, left...)
+	chunks = append(chunks, right...)
+
+	replaced, from := make(map[*pageChunk]bool), int64(0)
+	for _, c := range pb.chunks {
+		if from > rCur+rn {
+			break
+		} else if from+c.count() >= rCur {
+			replaced[c] = true
+		}
+		from += c.count()
+	}
+	for _, c := range chunks {
+		delete(replaced, c)
+	}
+	for c := range replaced {
+		c.release()
+	}
+	return chunks
+}
+
+// fileChunks split the file into chunks at rune boundaries.
+func (store *pageStore) fileChunks(size int64) ([]*pageChunk, error) {
+	chunks := make([]*pageChunk, 0, size/store.chunkSize+1)
+	buf := make([]byte, utf8.UTFMax)
+	for off := int64(0); off < size; {
+		end := off + store.chunkSize
+		if end >= size {
+			end = size
+		} else {
+			n, err := store.file.ReadAt(buf, end)
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			for i := 0; i < n-1 && !utf8.RuneStart(buf[i]); i++ {
+				end++
+			}
+		}
+		c := &pageChunk{store: store, where: chunkFile, off: off, size: end - off}
+		c.rn = -1
+		chunks, off = append(chunks, c), end
+	}
+	return chunks, nil
+}
+
+// memoryChunks return dirty chunks for rope, large ropes are split
+// into chunks of chunkSize.
+func (store *pageStore) memoryChunks(rope *RopeBuffer) []*pageChunk {
+	if rope.Size() == 0 {
+		return []*pageChunk{}
+	} else if rope.Size() > 2*store.chunkSize {
+		rCur := rope.ByteToRune(store.chunkSize)
+		for rope.RuneToByte(rCur) < store.chunkSize {
+			rCur++
+		}
+		left, right := rope.Split(rCur)
+		chunks := store.memoryChunks(left.(*RopeBuffer))
+		return append(chunks, store.memoryChunks(right.(*RopeBuffer))...)
+	}
+	c := &pageChunk{
+		store: store, where: chunkMemory, size: rope.Size(), rn: rope.Length(),
+	}
+	store.admit(c, rope)
+	return []*pageChunk{c}
+}
+
+// admit a loaded chunk into memory and evict other chunks if
+// memory budget is exceeded.
+func (store *pageStore) admit(c *pageChunk, rope *RopeBuffer) {
+	c.rope = rope
+	c.elem = store.lru.PushFront(c)
+	store.resident += c.size
+	for e := store.lru.Back(); store.resident > store.budget && e != nil; {
+		prev := e.Prev()
+		if victim := e.Value.(*pageChunk); victim != c {
+			victim.evict()
+		}
+		e = prev
+	}
+}
+
+// count return the number of runes in chunk, loading it if not
+// yet known.
+func (c *pageChunk) count() int64 {
+	if c.rn < 0 {
+		c.load()
+	}
+	return c.rn
+}
+
+// load return the chunk's content as a rope, loading it from file
+// or spill file if it is not resident. If reading fails the error
+// is remembered and a rope of utf8.RuneError, as many as the known
+// runes in chunk, is returned without being admitted.
+func (c *pageChunk) load() *RopeBuffer {
+	store := c.store
+	if c.rope != nil && c.elem == nil {
+		store.admit(c, c.rope)
+		return c.rope
+	} else if c.rope != nil {
+		store.lru.MoveToFront(c.elem)
+		return c.rope
+	}
+	fd := store.file
+	if c.where == chunkSpill {
+		fd = store.spillFd
+	}
+	bs := make([]byte, c.size)
+	if _, err := fd.ReadAt(bs, c.off); err != nil {
+		if store.err == nil {
+			store.err = err
+		}
+		if c.rn < 0 {
+			c.rn = 0
+		}
+		bs = []byte(strings.Repeat(string(utf8.RuneError), int(c.rn)))
+		return NewRopebuffer(bs, pagedLeafCapacity)
+	}
+	rope := NewRopebuffer(bs, pagedLeafCapacity)
+	c.rn = rope.Length()
+	store.loads++
+	store.admit(c, rope)
+	return rope
+}
+
+// evict chunk from memory, dirty chunks are written to spill file
+// if configured, else they are left in memory. Dirty chunks that
+// failed to spill are left in memory as well, and so are chunks
+// whose raw bytes would read back as different runes.
+func (c *pageChunk) evict() {
+	store := c.store
+	if c.where == chunkMemory {
+		if !store.spill || !c.spillable() {
+			return
+		} else if err := c.spill(); err != nil {
+			if store.err == nil {
+				store.err = err
+			}
+			return
+		}
+	}
+	store.lru.Remove(c.elem)
+	store.resident -= c.size
+	c.rope, c.elem = nil, nil
+	store.evictions++
+}
+
+// release chunk, replaced by an edit, from memory budget. Clean
+// chunks are evicted, dirty chunks keep their rope until the older
+// buffers referring to them are garbage collected, and are counted
+// again if loaded by any of them.
+func (c *pageChunk) release() {
+	store := c.store
+	if c.elem == nil {
+		return
+	}
+	store.lru.Remove(c.elem)
+	store.resident -= c.size
+	c.elem = nil
+	if c.where != chunkMemory {
+		c.rope = nil
+	}
+}
+
+// spillable check whether chunk's bytes decode to the same runes
+// once written to spill file and loaded back.
+func (c *pageChunk) spillable() bool {
+	return int64(utf8.RuneCount(c.rope.Bytes())) == c.rn
+}
+
+// spill write chunk to a free extent in spill file, or to its end.
+func (c *pageChunk) spill() (err error) {
+	store := c.store
+	if store.spillFd == nil {
+		if store.spillFd, err = ioutil.TempFile("", "pagedspill"); err != nil {
+			return err
+		}
+	}
+	bs := c.rope.Bytes()
+	extent := store.allocExtent(int64(len(bs)))
+	if _, err = store.spillFd.WriteAt(bs, extent.off); err != nil {
+		store.freeExtent(extent)
+		return err
+	}
+	runtime.SetFinalizer(extent, store.freeExtent)
+	c.where, c.off, c.extent = chunkSpill, extent.off, extent
+	store.spills++
+	return nil
+}
+
+// allocExtent of size bytes, from the first free extent that can
+// hold it, else from the end of spill file.
+func (store *pageStore) allocExtent(size int64) *spillExtent {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for i, free := range store.free {
+		if free.size < size {
+			continue
+		}
+		extent := &spillExtent{off: free.off, size: size}
+		if free.off, free.size = free.off+size, free.size-size; free.size == 0 {
+			store.free = append(store.free[:i], store.free[i+1:]...)
+		}
+		return extent
+	}
+	extent := &spillExtent{off: store.spillOff, size: size}
+	store.spillOff += size
+	return extent
+}
+
+// freeExtent once no chunk refers to it, merging it with adjacent
+// free extents. Called by the garbage collector.
+func (store *pageStore) freeExtent(extent *spillExtent) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	free := &spillExtent{off: extent.off, size: extent.size}
+	i := sort.Search(len(store.free), func(i int) bool {
+		return store.free[i].off > free.off
+	})
+	store.free = append(store.free, nil)
+	copy(store.free[i+1:], store.free[i:])
+	store.free[i] = free
+	if j := i + 1; j < len(store.free) && free.off+free.size == store.free[j].off {
+		free.size += store.free[j].size
+		store.free = append(store.free[:j], store.free[j+1:]...)
+	}
+	if j := i - 1; j >= 0 && store.free[j].off+store.free[j].size == free.off {
+		store.free[j].size += free.size
+		store.free = append(store.free[:i], store.free[i+1:]...)
+	}
+}
+
+// trim return a new chunk holding runes between [from, till). Trim
+// of a chunk with an extent in file or spill file refers to a sub
+// extent, and remain clean.
+func (c *pageChunk) trim(from, till int64) *pageChunk {
+	store, rope := c.store, c.load()
+	slice := rope.Slice(from, till-from).(*RopeBuffer)
+	if c.where == chunkMemory {
+		return store.memoryChunks(slice)[0]
+	}
+	off := c.off + rope.RuneToByte(from)
+	newc := &pageChunk{
+		store: store, where: c.where, off: off, size: slice.Size(),
+		rn: till - from, extent: c.extent,
+	}
+	store.admit(newc, slice)
+	return newc
+}
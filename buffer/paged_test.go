@@ -0,0 +1,253 @@
+package buffer
+
+import "os"
+import "io/ioutil"
+import "math/rand"
+import "runtime"
+import "strings"
+import "testing"
+
+func TestPagedRead(t *testing.T) {
+	data := []byte(testChinese + testChinese + testChinese)
+	pb, filename := pagedTestFile(t, data, 32, 128, false)
+	defer os.Remove(filename)
+	defer pb.Close()
+
+	ref := []rune(string(data))
+	if x, y := int64(len(data)), pb.Size(); x != y {
+		t.Fatalf("expected %v, got %v", x, y)
+	} else if x, y := int64(len(ref)), pb.Length(); x != y {
+		t.Fatalf("expected %v, got %v", x, y)
+	} else if x, y := string(data), string(pb.Runes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	} else if x, y := string(data), string(pb.Bytes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	}
+	for rCur := int64(0); rCur <= int64(len(ref)); rCur += 7 {
+		runes, _ := readAllRunes(pb.StreamFrom(rCur))
+		if x, y := string(ref[rCur:]), string(runes); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+		runes, _ = readAllRunes(pb.BackStreamFrom(rCur))
+		if x, y := string(ref[:rCur]), string(reverseRunes(runes)); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+		slice := pb.Slice(rCur, 20)
+		end := rCur + 20
+		if end > int64(len(ref)) {
+			end = int64(len(ref))
+		}
+		if x, y := string(ref[rCur:end]), string(slice.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+	stats, _ := pb.Stats()
+	chunks, budget := stats["chunks"].(int64), stats["budget"].(int64)
+	resident, evicted := stats["resident"].(int64), stats["evicted"].(int64)
+	if resident+evicted != chunks {
+		t.Fatalf("expected %v chunks, got %v+%v", chunks, resident, evicted)
+	} else if evicted == 0 {
+		t.Fatalf("expected evicted chunks, %v", stats)
+	} else if x := stats["residentBytes"].(int64); x > budget {
+		t.Fatalf("resident %v exceeds budget %v", x, budget)
+	}
+}
+
+func TestPagedEdit(t *testing.T) {
+	for _, spill := range []bool{false, true} {
+		data := []byte(testChinese + testChinese)
+		pb, filename := pagedTestFile(t, data, 32, 128, spill)
+
+		lb := NewLinearBuffer(data)
+		var buf Buffer = pb
+		history := map[Buffer]string{}
+		for i := 0; i < 200; i++ {
+			l := buf.Length()
+			rCur := rand.Int63n(l + 1)
+			switch rand.Intn(4) {
+			case 0:
+				text := []rune(`中國;pinyin`)[:rand.Intn(9)]
+				buf, lb = buf.Insert(rCur, text), lb.Insert(rCur, text)
+			case 1:
+				rn := rand.Int63n(l - rCur + 1)
+				buf, lb = buf.Delete(rCur, rn), lb.Delete(rCur, rn)
+			case 2:
+				text := []rune("hello world")
+				buf.InsertIn(rCur, text)
+				lb = lb.Insert(rCur, text)
+			case 3:
+				rn := rand.Int63n(l - rCur + 1)
+				buf.DeleteIn(rCur, rn)
+				lb = lb.Delete(rCur, rn)
+			}
+			if x, y := string(lb.Runes()), string(buf.Runes()); x != y {
+				t.Fatalf("spill:%v expected %q, got %q", spill, x, y)
+			}
+			history[buf.Slice(0, buf.Length())] = string(lb.Runes())
+		}
+		for oldbuf, val := range history {
+			if x := string(oldbuf.Runes()); x != val {
+				t.Fatalf("spill:%v expected %q, got %q", spill, val, x)
+			}
+		}
+		stats, _ := buf.Stats()
+		if spill && stats["spills"].(int64) == 0 {
+			t.Fatalf("expected chunks to spill, %v", stats)
+		} else if !spill && stats["spills"].(int64) != 0 {
+			t.Fatalf("unexpected spill, %v", stats)
+		}
+		pb.Close()
+		os.Remove(filename)
+	}
+}
+
+func TestPagedBudget(t *testing.T) {
+	for _, spill := range []bool{false, true} {
+		data := []byte(strings.Repeat(testChinese, 100))
+		pb, filename := pagedTestFile(t, data, 256, 2048, spill)
+
+		lb := NewLinearBuffer(data)
+		var buf Buffer = pb
+		for i := 0; i < 10000; i++ {
+			l := buf.Length()
+			rCur := rand.Int63n(l + 1)
+			if i%2 == 0 {
+				buf, lb = buf.Insert(rCur, []rune("x")), lb.Insert(rCur, []rune("x"))
+			} else if rCur < l {
+				buf.DeleteIn(rCur, 1)
+				lb = lb.Delete(rCur, 1)
+			}
+			if i%200 == 0 {
+				runtime.GC() // free spill extents of replaced chunks.
+			}
+			stats, _ := buf.Stats()
+			resident, budget := stats["residentBytes"].(int64), stats["budget"].(int64)
+			if !spill { // dirty chunks stay in memory.
+				for _, c := range buf.(*PagedBuffer).chunks {
+					if c.where == chunkMemory {
+						budget += c.size
+					}
+				}
+			}
+			if resident > budget {
+				t.Fatalf("spill:%v resident %v exceeds budget %v", spill, resident, budget)
+			}
+		}
+		if x, y := string(lb.Runes()), string(buf.Runes()); x != y {
+			t.Fatalf("spill:%v expected %q, got %q", spill, x, y)
+		}
+		if x := pb.store.spillOff; x > 4*int64(len(data)) {
+			t.Fatalf("spill:%v spill file of %v bytes for %v", spill, x, len(data))
+		}
+		pb.Close()
+		os.Remove(filename)
+	}
+}
+
+func TestPagedEmpty(t *testing.T) {
+	pb, filename := pagedTestFile(t, []byte{}, 32, 128, false)
+	defer os.Remove(filename)
+	defer pb.Close()
+
+	if x := pb.Length(); x != 0 {
+		t.Fatalf("expected %v, got %v", 0, x)
+	} else if x := pb.Runes(); x != nil {
+		t.Fatalf("expected nil, got %v", x)
+	}
+	buf := pb.Insert(0, []rune("hello"))
+	if x := string(buf.Runes()); x != "hello" {
+		t.Fatalf("expected %q, got %q", "hello", x)
+	}
+	if _, err := NewPagedBuffer(filename+".missing", 0, 0, false); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestPagedLazy(t *testing.T) {
+	data := []byte(strings.Repeat(testChinese, 20))
+	pb, filename := pagedTestFile(t, data, 32, 1024*1024, false)
+	defer os.Remove(filename)
+	defer pb.Close()
+
+	ref := []rune(string(data))
+	buf := pb.Insert(5, []rune("hello")).Delete(10, 3)
+	buf.InsertIn(2, []rune("world"))
+	buf.Split(20)
+	pb.StreamFrom(3)
+	stats, _ := buf.Stats()
+	if x, y := stats["loads"].(int64), stats["chunks"].(int64); x > 4 {
+		t.Fatalf("expected at most 4 of %v chunks loaded, got %v", y, x)
+	}
+	refs := string(ref[:2]) + "world" + string(ref[2:5]) + "hello" +
+		string(ref[8:])
+	if x := string(buf.Runes()); x != refs {
+		t.Fatalf("expected %q, got %q", refs, x)
+	}
+}
+
+func TestPagedError(t *testing.T) {
+	data := []byte(testChinese + testChinese)
+	pb, filename := pagedTestFile(t, data, 32, 1024*1024, false)
+	defer os.Remove(filename)
+	defer pb.Close()
+
+	ebuf := NewEditBuffer(0, pb, nil)
+	ebuf.filename = filename
+	pb.store.file.Close() // reads shall fail hereafter.
+	if err := pb.Err(); err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	l := pb.Length()
+	if err := pb.Err(); err == nil {
+		t.Fatalf("expected error")
+	} else if _, err1 := pb.Stats(); err1 != err {
+		t.Fatalf("expected %v, got %v", err, err1)
+	} else if x := int64(len(pb.Runes())); x != l {
+		t.Fatalf("expected %v, got %v", l, x)
+	} else if err1 := ebuf.Save(""); err1 != err {
+		t.Fatalf("expected %v, got %v", err, err1)
+	} else if x, _ := ioutil.ReadFile(filename); string(x) != string(data) {
+		t.Fatalf("unexpected %q", x)
+	}
+}
+
+func BenchmarkPagedStrmFrm(b *testing.B) {
+	pb, filename := pagedTestFile(b, sampleData, 0, 256*1024, false)
+	defer os.Remove(filename)
+	defer pb.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		readAllRunes(pb.StreamFrom(0))
+	}
+	b.SetBytes(pb.Size())
+}
+
+func BenchmarkPagedInsert(b *testing.B) {
+	pb, filename := pagedTestFile(b, sampleData, 0, 256*1024, true)
+	defer os.Remove(filename)
+	defer pb.Close()
+	itext := []rune(`中國;pinyin`)
+	l := pb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pb.InsertIn(int64(i*1009)%l, itext)
+	}
+	b.SetBytes(int64(len(string(itext))))
+}
+
+func pagedTestFile(
+	tb testing.TB, data []byte,
+	chunksize, budget int64, spill bool) (*PagedBuffer, string) {
+
+	fd, err := ioutil.TempFile("", "pagedtest")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	fd.Write(data)
+	fd.Close()
+	pb, err := NewPagedBuffer(fd.Name(), chunksize, budget, spill)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return pb, fd.Name()
+}
@@ -0,0 +1,416 @@
+package buffer
+
+import "fmt"
+import "io"
+import "unicode/utf8"
+
+var _ = fmt.Sprintf("dummy")
+
+// pieceStore holds the backing stores shared by all piece-tables
+// derived from the same document.
+type pieceStore struct {
+	orig []byte // read-only, original text.
+	add  []byte // append-only, inserted text.
+}
+
+// piece describes a contiguous span of text in one of the stores.
+type piece struct {
+	add  bool  // span is in add-store, else in original-store.
+	off  int64 // byte offset into the store.
+	size int64 // no. of bytes in the span.
+	rn   int64 // no. of runes in the span.
+}
+
+// PieceTable implements Buffer as a sequence of pieces, each
+// describing a span of text either from the original text, which
+// is never modified, or from an append-only store that accumulates
+// all inserted text. Tables derived from each other via Slice,
+// Split, Concat, Insert and Delete share the same stores.
+type PieceTable struct {
+	store  *pieceStore
+	pieces []piece
+	length int64 // no. of runes.
+	size   int64 // no. of bytes.
+}
+
+// NewPieceTable returns a new buffer that refers to text as its
+// original store. Text is not copied and shall not be modified
+// by the caller after this call.
+func NewPieceTable(bs []byte) Buffer {
+	pt := &PieceTable{store: &pieceStore{orig: bs}}
+	if l := int64(len(bs)); l > 0 {
+		rn := int64(utf8.RuneCount(bs))
+		pt.pieces = []piece{piece{off: 0, size: l, rn: rn}}
+		pt.length, pt.size = rn, l
+	}
+	return pt
+}
+
+// newPieceOverlay returns a piece-table over orig[off:off+size],
+// holding rn runes. Orig is neither copied nor validated, it is
+// used to record changes on top of read-only buffers.
+func newPieceOverlay(orig []byte, off, size, rn int64) *PieceTable {
+	pt := &PieceTable{store: &pieceStore{orig: orig}}
+	if size > 0 {
+		pt.setPieces([]piece{piece{off: off, size: size, rn: rn}})
+	}
+	return pt
+}
+
+//----------
+// rune APIs
+//----------
+
+// Length implement Buffer{} interface.
+func (pt *PieceTable) Length() int64 {
+	return pt.length
+}
+
+// Slice implement Buffer{} interface.
+func (pt *PieceTable) Slice(rCur, rn int64) Buffer {
+	endCur := rCur + rn
+	if !pt.isValidCursor(rCur) {
+		return nil
+	} else if endCur < rCur {
+		return nil
+	} else if !pt.isValidCursor(endCur) {
+		endCur = pt.Length()
+	}
+	pieces := pt.slicePieces(rCur, endCur)
+	return pt.newTable(pieces)
+}
+
+// Runes implement Buffer{} interface.
+func (pt *PieceTable) Runes() []rune {
+	if pt.length == 0 {
+		return nil
+	}
+	rs, n := make([]rune, pt.length), 0
+	for _, p := range pt.pieces {
+		for bs := pt.text(p); len(bs) > 0; n++ {
+			r, size := decodeRune(bs)
+			rs[n], bs = r, bs[size:]
+		}
+	}
+	return rs
+}
+
+// Concat implement Buffer{} interface. If other buffer shares the
+// same stores, pieces are simply concatenated, otherwise the text
+// from other buffer is copied into the add-store, piece by piece.
+func (pt *PieceTable) Concat(right Buffer) Buffer {
+	rpt := right.(*PieceTable)
+	if pt == nil {
+		return rpt
+	} else if rpt == nil {
+		return pt
+	}
+	pieces := make([]piece, 0, len(pt.pieces)+len(rpt.pieces))
+	pieces = append(pieces, pt.pieces...)
+	if rpt.store == pt.store {
+		pieces = append(pieces, rpt.pieces...)
+	} else {
+		for _, p := range rpt.pieces {
+			pieces = append(pieces, pt.appendText(rpt.text(p), p.rn))
+		}
+	}
+	return pt.newTable(pieces)
+}
+
+// Split implement Buffer{} interface.
+func (pt *PieceTable) Split(rCur int64) (left, right Buffer) {
+	if pt == nil {
+		return left, right
+	}
+	l := pt.Length()
+	if rCur >= l {
+		return pt, nil
+	} else if rCur == 0 {
+		return nil, pt
+	}
+	left = pt.Slice(0, rCur)
+	right = pt.Slice(rCur, l-rCur)
+	return
+}
+
+// Insert implement Buffer{} interface.
+func (pt *PieceTable) Insert(rCur int64, text []rune) Buffer {
+	if text == nil {
+		return pt
+	} else if pt == nil {
+		panic(ErrorBufferNil)
+	} else if !pt.isValidCursor(rCur) {
+		panic(ErrorIndexOutofbound)
+	}
+	pieces := pt.insertPieces(rCur, text)
+	return pt.newTable(pieces)
+}
+
+// Delete implement Buffer{} interface.
+func (pt *PieceTable) Delete(rCur, rn int64) Buffer {
+	if rn == 0 {
+		return pt
+	} else if pt == nil {
+		panic(ErrorBufferNil)
+	} else if !pt.isValidCursor(rCur) || !pt.isValidCursor(rCur+rn) {
+		panic(ErrorIndexOutofbound)
+	}
+	pieces := pt.slicePieces(0, rCur)
+	pieces = append(pieces, pt.slicePieces(rCur+rn, pt.length)...)
+	return pt.newTable(pieces)
+}
+
+// InsertIn implement Buffer{} interface.
+func (pt *PieceTable) InsertIn(rCur int64, text []rune) Buffer {
+	if text == nil {
+		return pt
+	} else if pt == nil {
+		panic(ErrorBufferNil)
+	} else if !pt.isValidCursor(rCur) {
+		panic(ErrorIndexOutofbound)
+	}
+	pt.setPieces(pt.insertPieces(rCur, text))
+	return pt
+}
+
+// DeleteIn implement Buffer{} interface.
+func (pt *PieceTable) DeleteIn(rCur, rn int64) Buffer {
+	if rn == 0 {
+		return pt
+	} else if pt == nil {
+		panic(ErrorBufferNil)
+	} else if !pt.isValidCursor(rCur) || !pt.isValidCursor(rCur+rn) {
+		panic(ErrorIndexOutofbound)
+	}
+	pieces := pt.slicePieces(0, rCur)
+	pieces = append(pieces, pt.slicePieces(rCur+rn, pt.length)...)
+	pt.setPieces(pieces)
+	return pt
+}
+
+//------------
+// search APIs
+//------------
+
+// StreamFrom implement Buffer interface{}.
+func (pt *PieceTable) StreamFrom(rCur int64) RuneReader {
+	if !pt.isValidCursor(rCur) {
+		return nil
+	}
+	return pt.StreamCount(rCur, pt.length-rCur)
+}
+
+// StreamCount implement Buffer interface{}.
+func (pt *PieceTable) StreamCount(rCur, count int64) RuneReader {
+	if !pt.isValidCursor(rCur) {
+		return nil
+	}
+	i, off := pt.locate(rCur)
+	var bs []byte
+	if i < len(pt.pieces) {
+		bs = pt.text(pt.pieces[i])[off:]
+	}
+	return iterator(func(finish bool) (r rune, size int, err error) {
+		if count <= 0 || finish {
+			count = 0
+			return r, size, io.EOF
+		}
+		for len(bs) == 0 {
+			if i++; i >= len(pt.pieces) {
+				return r, size, io.EOF
+			}
+			bs = pt.text(pt.pieces[i])
+		}
+		r, size = decodeRune(bs)
+		bs = bs[size:]
+		count--
+		return r, size, nil
+	})
+}
+
+// BackStreamFrom implement Buffer interface{}.
+func (pt *PieceTable) BackStreamFrom(rCur int64) RuneReader {
+	if !pt.isValidCursor(rCur) {
+		return nil
+	}
+	return pt.BackStreamCount(rCur, rCur)
+}
+
+// BackStreamCount implement Buffer interface{}.
+func (pt *PieceTable) BackStreamCount(rCur, count int64) RuneReader {
+	if !pt.isValidCursor(rCur) {
+		return nil
+	}
+	i, off := pt.locate(rCur)
+	var bs []byte
+	if i < len(pt.pieces) {
+		bs = pt.text(pt.pieces[i])[:off]
+	}
+	return iterator(func(finish bool) (r rune, size int, err error) {
+		if count <= 0 || finish {
+			count = 0
+			return r, size, io.EOF
+		}
+		for len(bs) == 0 {
+			if i--; i < 0 {
+				return r, size, io.EOF
+			}
+			bs = pt.text(pt.pieces[i])
+		}
+		r, size = decodeLastRune(bs)
+		bs = bs[:len(bs)-size]
+		count--
+		return r, size, nil
+	})
+}
+
+//----------
+// byte APIs
+//----------
+
+// Size implement Buffer{} interface.
+func (pt *PieceTable) Size() int64 {
+	return pt.size
+}
+
+// Bytes implement Buffer{} interface.
+func (pt *PieceTable) Bytes() []byte {
+	bs := make([]byte, 0, pt.size)
+	for _, p := range pt.pieces {
+		bs = append(bs, pt.text(p)...)
+	}
+	return bs
+}
+
+// Stats implement Buffer{} interface.
+func (pt *PieceTable) Stats() (Statistics, error) {
+	if pt == nil {
+		return nil, ErrorBufferNil
+	}
+	stats := Statistics{
+		"length":   pt.length,                 // no. of runes
+		"size":     pt.size,                   // no. of bytes
+		"pieces":   int64(len(pt.pieces)),     // no. of pieces
+		"origSize": int64(len(pt.store.orig)), // size of original-store
+		"addSize":  int64(len(pt.store.add)),  // size of add-store
+	}
+	return stats, nil
+}
+
+//---------------
+// local function
+//---------------
+
+func (pt *PieceTable) isValidCursor(rCur int64) bool {
+	return 0 <= rCur && rCur <= pt.Length()
+}
+
+// newTable create a new piece-table sharing the stores.
+func (pt *PieceTable) newTable(pieces []piece) *PieceTable {
+	newpt := &PieceTable{store: pt.store}
+	newpt.setPieces(pieces)
+	return newpt
+}
+
+func (pt *PieceTable) setPieces(pieces []piece) {
+	pt.pieces, pt.length, pt.size = pieces, 0, 0
+	for _, p := range pieces {
+		pt.length, pt.size = pt.length+p.rn, pt.size+p.size
+	}
+}
+
+// text return the span of bytes referred by piece.
+func (pt *PieceTable) text(p piece) []byte {
+	if p.add {
+		return pt.store.add[p.off : p.off+p.size]
+	}
+	return pt.store.orig[p.off : p.off+p.size]
+}
+
+// appendText to add-store and return a piece referring to it.
+func (pt *PieceTable) appendText(bs []byte, rn int64) piece {
+	off := int64(len(pt.store.add))
+	pt.store.add = append(pt.store.add, bs...)
+	return piece{add: true, off: off, size: int64(len(bs)), rn: rn}
+}
+
+// locate the piece containing rCur, return the index of the piece
+// and the byte offset of rCur within the piece. If rCur falls on a
+// piece boundary, the piece starting at rCur is returned, and for
+// rCur at the end of text the index is len(pieces) with offset 0.
+func (pt *PieceTable) locate(rCur int64) (int, int64) {
+	for i, p := range pt.pieces {
+		if rCur < p.rn {
+			return i, runeOffset(pt.text(p), rCur)
+		}
+		rCur -= p.rn
+	}
+	return len(pt.pieces), 0
+}
+
+// split piece at rune offset rn within the piece.
+func (pt *PieceTable) splitPiece(p piece, rn int64) (piece, piece) {
+	off := runeOffset(pt.text(p), rn)
+	left := piece{add: p.add, off: p.off, size: off, rn: rn}
+	right := piece{add: p.add, off: p.off + off, size: p.size - off, rn: p.rn - rn}
+	return left, right
+}
+
+// slicePieces return a new list of pieces covering runes between
+// [from, till).
+func (pt *PieceTable) slicePieces(from, till int64) []piece {
+	pieces := make([]piece, 0, len(pt.pieces))
+	start := int64(0)
+	for _, p := range pt.pieces {
+		end := start + p.rn
+		if end <= from {
+			start = end
+			continue
+		} else if start >= till {
+			break
+		}
+		if from > start {
+			_, p = pt.splitPiece(p, from-start)
+			start = from
+		}
+		if till < end {
+			p, _ = pt.splitPiece(p, till-start)
+		}
+		pieces = append(pieces, p)
+		start = end
+	}
+	return pieces
+}
+
+// insertPieces return a new list of pieces with text inserted at
+// rCur. When text is inserted right after the last piece added to
+// add-store, the piece is extended instead of creating a new one.
+// Raw-runes that would make up a valid rune once encoded are kept
+// in separate pieces.
+func (pt *PieceTable) insertPieces(rCur int64, text []rune) []piece {
+	segs := runes2Segments(text)
+	if len(segs[0]) == 0 {
+		return pt.pieces
+	}
+	left := pt.slicePieces(0, rCur)
+	right := pt.slicePieces(rCur, pt.length)
+	pieces := make([]piece, 0, len(left)+len(right)+len(segs))
+	pieces = append(pieces, left...)
+	for _, bs := range segs {
+		rn := int64(utf8.RuneCount(bs))
+		n := len(pieces)
+		if n > 0 && pt.isAddTail(pieces[n-1]) && joinable(pt.text(pieces[n-1]), bs) {
+			pt.store.add = append(pt.store.add, bs...)
+			pieces[n-1].size += int64(len(bs))
+			pieces[n-1].rn += rn
+		} else {
+			pieces = append(pieces, pt.appendText(bs, rn))
+		}
+	}
+	return append(pieces, right...)
+}
+
+// isAddTail check whether piece ends at the tail of add-store.
+func (pt *PieceTable) isAddTail(p piece) bool {
+	return p.add && (p.off+p.size) == int64(len(pt.store.add))
+}
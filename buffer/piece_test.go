@@ -0,0 +1,412 @@
+package buffer
+
+import "testing"
+import "io"
+import "fmt"
+import "math/rand"
+
+var _ = fmt.Sprintf("dummy")
+
+func TestPieceLength(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	if l := pt.Length(); l != 51 {
+		t.Fatalf("expected 51 got %v\n", l)
+	} else if size := pt.Size(); size != int64(len(testChinese)) {
+		t.Fatalf("expected %v got %v\n", len(testChinese), size)
+	}
+	pt = NewPieceTable([]byte(""))
+	if l := pt.Length(); l != 0 {
+		t.Fatalf("expected 0 got %v\n", l)
+	}
+}
+
+func TestPieceNoCopy(t *testing.T) {
+	text := []byte(testChinese)
+	pt := NewPieceTable(text).(*PieceTable)
+	if &pt.store.orig[0] != &text[0] {
+		t.Fatalf("expected original text to be referred, not copied")
+	}
+}
+
+func TestPieceSliceRunes(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	pt = pt.Insert(10, []rune("abc")).Delete(10, 3)
+	rs := pt.Runes()
+	if pt.Slice(-1, 0) != nil {
+		t.Fatalf("expected nil")
+	} else if pt.Slice(52, 0) != nil {
+		t.Fatalf("expected nil")
+	} else if string(pt.Slice(0, 52).Runes()) != testChinese {
+		t.Fatalf("expected full string %v", len(pt.Slice(0, 52).Runes()))
+	}
+	for i := int64(0); i < pt.Length(); i++ {
+		for n := int64(0); i+n <= pt.Length(); n++ {
+			if x, y := string(rs[i:i+n]), string(pt.Slice(i, n).Runes()); x != y {
+				t.Fatalf("expected %q, got %q", x, y)
+			}
+		}
+	}
+}
+
+func TestPieceConcat(t *testing.T) {
+	testcases := [][]string{
+		[]string{testChinese, testChinese},
+		[]string{``, testChinese},
+		[]string{testChinese, ``},
+		[]string{``, ``},
+	}
+	for _, testcase := range testcases {
+		s1, s2 := testcase[0], testcase[1]
+		pt1 := NewPieceTable([]byte(s1))
+		pt2 := NewPieceTable([]byte(s2))
+		pt := pt1.Concat(pt2)
+		if string(pt.Runes()) != (s1 + s2) {
+			t.Fatalf("expected concat of s1 and s2")
+		}
+	}
+}
+
+func TestPieceSplitShare(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese)).(*PieceTable)
+	pt = pt.Insert(5, []rune("abc")).(*PieceTable)
+	addSize := len(pt.store.add)
+	for rCur := int64(1); rCur < pt.Length(); rCur++ {
+		left, right := pt.Split(rCur)
+		lpt, rpt := left.(*PieceTable), right.(*PieceTable)
+		if lpt.store != pt.store || rpt.store != pt.store {
+			t.Fatalf("expected split tables to share stores")
+		}
+		joined := lpt.Concat(rpt).(*PieceTable)
+		if joined.store != pt.store {
+			t.Fatalf("expected concat table to share stores")
+		} else if x, y := string(pt.Runes()), string(joined.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+	if len(pt.store.add) != addSize {
+		t.Fatalf("expected no copy into add-store")
+	}
+}
+
+func TestPieceSplit(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	testcases := [][]interface{}{
+		[]interface{}{int64(-1), nil, nil},
+		[]interface{}{int64(0), nil, string(pt.Runes())},
+		[]interface{}{int64(10), string(pt.Runes()[:10]), string(pt.Runes()[10:])},
+		[]interface{}{pt.Length(), string(pt.Runes()), nil},
+		[]interface{}{pt.Length() + 1, string(pt.Runes()), nil},
+	}
+	for _, tc := range testcases {
+		rCur, l, r := tc[0].(int64), tc[1], tc[2]
+		lpt, rpt := pt.Split(int64(rCur))
+		t.Logf("rCur: %v\n", rCur)
+		if l == nil && lpt != nil {
+			t.Fatalf("expected %v != %v", l, lpt)
+		} else if l != nil && (l.(string) != string(lpt.Runes())) {
+			t.Fatalf("expected %v != %v", l, lpt)
+		}
+		if r == nil && rpt != nil {
+			t.Fatalf("expected %v != %v", r, rpt)
+		} else if r != nil && (r.(string) != string(rpt.Runes())) {
+			t.Fatalf("expected %v != %v", r, rpt)
+		}
+	}
+}
+
+func TestPieceInsert(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	lb := NewLinearBuffer([]byte(testChinese))
+	history := []Buffer{pt}
+	refs := []string{testChinese}
+	for i := 0; i < 100; i++ {
+		rCur := rand.Int63n(pt.Length() + 1)
+		text := []rune(`中國;pinyin`)[:rand.Intn(9)]
+		pt, lb = pt.Insert(rCur, text), lb.Insert(rCur, text)
+		if x, y := string(lb.Runes()), string(pt.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+		history, refs = append(history, pt), append(refs, string(lb.Runes()))
+	}
+	for i, old := range history { // verify persistence.
+		if x, y := refs[i], string(old.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != ErrorIndexOutofbound {
+				t.Fatalf("expected panic ErrorIndexOutofbound, got %v", r)
+			}
+		}()
+		pt.Insert(pt.Length()+1, []rune("a"))
+	}()
+}
+
+func TestPieceDelete(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	lb := NewLinearBuffer([]byte(testChinese))
+	for pt.Length() > 0 {
+		rCur := rand.Int63n(pt.Length())
+		rn := rand.Int63n(pt.Length() - rCur + 1)
+		ref := string(pt.Runes())
+		newpt, newlb := pt.Delete(rCur, rn), lb.Delete(rCur, rn)
+		if x, y := string(newlb.Runes()), string(newpt.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		} else if s := string(pt.Runes()); s != ref {
+			t.Fatalf("persistence failed, expected %q, got %q", ref, s)
+		}
+		pt, lb = newpt, newlb
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != ErrorIndexOutofbound {
+				t.Fatalf("expected panic ErrorIndexOutofbound, got %v", r)
+			}
+		}()
+		pt.Delete(0, 1)
+	}()
+}
+
+func TestPieceInsertIn(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	lb := NewLinearBuffer([]byte(testChinese))
+	for i := 0; i < 100; i++ {
+		rCur := rand.Int63n(pt.Length() + 1)
+		text := []rune(`中國;pinyin`)[:rand.Intn(9)]
+		if x := pt.InsertIn(rCur, text); x != pt {
+			t.Fatalf("expected same reference")
+		}
+		lb = lb.Insert(rCur, text)
+		if x, y := string(lb.Runes()), string(pt.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+}
+
+func TestPieceTyping(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	for i, r := range []rune(`中國;pinyin`) {
+		pt.InsertIn(10+int64(i), []rune{r})
+	}
+	stats, _ := pt.Stats()
+	if n := stats["pieces"].(int64); n != 3 {
+		t.Fatalf("expected %v pieces, got %v", 3, n)
+	}
+	ref := []rune(testChinese)
+	x := string(ref[:10]) + `中國;pinyin` + string(ref[10:])
+	if y := string(pt.Runes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	}
+}
+
+func TestPieceDeleteIn(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	lb := NewLinearBuffer([]byte(testChinese))
+	for pt.Length() > 0 {
+		rCur := rand.Int63n(pt.Length())
+		rn := rand.Int63n(pt.Length() - rCur + 1)
+		if x := pt.DeleteIn(rCur, rn); x != pt {
+			t.Fatalf("expected same reference")
+		}
+		lb = lb.Delete(rCur, rn)
+		if x, y := string(lb.Runes()), string(pt.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+}
+
+func TestPieceStreamFrom(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	pt = pt.Insert(20, []rune("abc")).Insert(30, []rune("xyz"))
+	ref := pt.Runes()
+	if pt.StreamFrom(-1) != nil {
+		t.Fatalf("expected nil")
+	}
+	for i := int64(0); i <= pt.Length(); i++ {
+		runes, size := readAllRunes(pt.StreamFrom(i))
+		if x, y := string(ref[i:]), string(runes); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		} else if size != len(x) {
+			t.Fatalf("expected %v, got %v", len(x), size)
+		}
+	}
+}
+
+func TestPieceStreamCount(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	pt = pt.Insert(20, []rune("abc")).Insert(30, []rune("xyz"))
+	ref := pt.Runes()
+	for i := int64(0); i <= pt.Length(); i++ {
+		n := rand.Int63n(pt.Length() - i + 1)
+		runes, _ := readAllRunes(pt.StreamCount(i, n))
+		if x, y := string(ref[i:i+n]), string(runes); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+}
+
+func TestPieceBackStreamFrom(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	pt = pt.Insert(20, []rune("abc")).Insert(30, []rune("xyz"))
+	ref := pt.Runes()
+	for i := int64(0); i <= pt.Length(); i++ {
+		runes, _ := readAllRunes(pt.BackStreamFrom(i))
+		if x, y := string(ref[:i]), string(reverseRunes(runes)); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+}
+
+func TestPieceBackStreamCount(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	pt = pt.Insert(20, []rune("abc")).Insert(30, []rune("xyz"))
+	ref := pt.Runes()
+	for i := int64(0); i <= pt.Length(); i++ {
+		n := rand.Int63n(i + 1)
+		runes, _ := readAllRunes(pt.BackStreamCount(i, n))
+		if x, y := string(ref[i-n:i]), string(reverseRunes(runes)); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+}
+
+func TestPieceBytes(t *testing.T) {
+	pt := NewPieceTable([]byte(testChinese))
+	pt = pt.Insert(20, []rune("abc")).Delete(20, 3)
+	if s := string(pt.Bytes()); s != testChinese {
+		t.Fatalf("expected %q, got %q", testChinese, s)
+	} else if size := pt.Size(); size != int64(len(testChinese)) {
+		t.Fatalf("expected %v, got %v", len(testChinese), size)
+	}
+}
+
+func BenchmarkPieceOpen2M(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NewPieceTable(sampleData)
+	}
+	b.SetBytes(int64(len(sampleData)))
+}
+
+func BenchmarkPieceSlice(b *testing.B) {
+	pt := NewPieceTable([]byte(testChinese))
+	l := pt.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pt.Slice(0, int64(i)%l)
+	}
+}
+
+func BenchmarkPieceRunes(b *testing.B) {
+	pt := NewPieceTable([]byte(testChinese))
+	for i := 0; i < b.N; i++ {
+		pt.Runes()
+	}
+	b.SetBytes(int64(len(testChinese)))
+}
+
+func BenchmarkPieceConcat(b *testing.B) {
+	pt := NewPieceTable([]byte(testChinese))
+	left, right := pt.Split(10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		left.Concat(right)
+	}
+	b.SetBytes(int64(len(testChinese)))
+}
+
+func BenchmarkPieceSplit(b *testing.B) {
+	pt := NewPieceTable([]byte(testChinese))
+	l := pt.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pt.Split(int64(i) % l)
+	}
+	b.SetBytes(int64(len(testChinese)))
+}
+
+func BenchmarkPieceInsert(b *testing.B) {
+	// insert small text into a small buffer.
+	pt := NewPieceTable([]byte(testChinese))
+	itext := []rune(`中國;pinyin`)
+	l := pt.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pt.Insert(int64(i)%l, itext)
+	}
+	b.SetBytes(int64(len(string(itext))))
+}
+
+func BenchmarkPieceInsert2M(b *testing.B) {
+	// insert small text into a large buffer.
+	pt := NewPieceTable(sampleData)
+	itext := []rune(`中國;pinyin`)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pt.Insert(int64(i)%1000, itext)
+	}
+	b.SetBytes(int64(len(string(itext))))
+}
+
+func BenchmarkPieceDelete(b *testing.B) {
+	pt := NewPieceTable([]byte(testChinese))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pt.Delete(int64(i)%10, 10)
+	}
+}
+
+func BenchmarkPieceDelete2M(b *testing.B) {
+	pt := NewPieceTable(sampleData)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pt.Delete(int64(i)%1000, 10)
+	}
+}
+
+func BenchmarkPieceInsIn2M(b *testing.B) {
+	// type text, one rune at a time, into a large buffer.
+	pt := NewPieceTable(sampleData)
+	itext := []rune(`中國;pinyin`)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pt.InsertIn(1000+int64(i), itext[i%len(itext):i%len(itext)+1])
+	}
+}
+
+func BenchmarkPieceDelIn2M(b *testing.B) {
+	pt := NewPieceTable(sampleData)
+	itext := []rune(`中國;pinyin`)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pt.DeleteIn(1000, 10)
+		pt.InsertIn(1000, itext)
+	}
+	b.SetBytes(int64(len(string(itext))))
+}
+
+func BenchmarkPieceStrmFrm(b *testing.B) {
+	pt := NewPieceTable([]byte(testChinese))
+	for i := 0; i < b.N; i++ {
+		var err error
+		reader := pt.StreamFrom(0)
+		for err != io.EOF {
+			_, _, err = reader.ReadRune()
+		}
+	}
+	b.SetBytes(int64(len(testChinese)))
+}
+
+func BenchmarkPieceBStrmFrm(b *testing.B) {
+	pt := NewPieceTable([]byte(testChinese))
+	l := pt.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		reader := pt.BackStreamFrom(l)
+		for err != io.EOF {
+			_, _, err = reader.ReadRune()
+		}
+	}
+	b.SetBytes(int64(len(testChinese)))
+}
@@ -0,0 +1,266 @@
+package buffer
+
+import "regexp/syntax"
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// Regexp is a compiled regular expression that is matched straight
+// against a RuneReader, like the streams returned by Buffer, and
+// report matches as rune offsets, so that the buffer is never
+// flattened to search it. Syntax is same as that of regexp package,
+// with leftmost-first semantics, unless Longest is called.
+//
+// Find run a lazily built DFA, whose states are sets of Thompson
+// NFA threads, while FindSubmatch simulate the NFA to track the
+// capture groups, refer http://swtch.com/~rsc/regexp/regexp1.html
+// Every pattern is also compiled in reverse, to search backward
+// from a cursor, refer FindBackward.
+//
+// Not thread safe.
+type Regexp struct {
+	expr    string
+	prog    *syntax.Prog
+	ncap    int // no. of offsets in a submatch, 2 for each group.
+	longest bool
+	dfa     *dfa
+	nfa     *nfa
+	// reversed automata, matching the pattern from its end.
+	rdfa *dfa
+	rnfa *nfa
+}
+
+// CompileRegexp parse expr and return a Regexp that can be used to
+// search buffers.
+func CompileRegexp(expr string) (*Regexp, error) {
+	ast, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	ncap := (ast.MaxCap() + 1) * 2
+	prog, err := syntax.Compile(ast.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	rprog, err := syntax.Compile(reverseSyntax(ast).Simplify())
+	if err != nil {
+		return nil, err
+	}
+	re := &Regexp{expr: expr, prog: prog, ncap: ncap}
+	re.dfa = newDFA(prog, false)
+	re.nfa = newNFA(prog, ncap, false)
+	re.rdfa = newDFA(rprog, true /*longest*/)
+	re.rnfa = newNFA(rprog, ncap, true /*longest*/)
+	return re, nil
+}
+
+// MustCompileRegexp is like CompileRegexp, but panics if expr
+// cannot be parsed.
+func MustCompileRegexp(expr string) *Regexp {
+	re, err := CompileRegexp(expr)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+// Longest make future searches prefer leftmost-longest matches.
+func (re *Regexp) Longest() *Regexp {
+	re.longest = true
+	re.dfa = newDFA(re.prog, true)
+	re.nfa = newNFA(re.prog, re.ncap, true)
+	return re
+}
+
+// NumSubexp return the number of capture groups in re.
+func (re *Regexp) NumSubexp() int {
+	return re.ncap/2 - 1
+}
+
+// String implement fmt.Stringer interface.
+func (re *Regexp) String() string {
+	return re.expr
+}
+
+//------------
+// search APIs
+//------------
+
+// Finder iterate over successive non-overlapping matches, return
+// nil after the last match.
+type Finder func() []int64
+
+// Find return an iterator over successive matches of re in reader,
+// each match is a pair of rune offsets {start, end}, counted from
+// the first rune read from reader. Beginning of reader is treated
+// as beginning of text. Reader is closed after the last match.
+func Find(re *Regexp, reader RuneReader) Finder {
+	return re.finder(reader, re.dfa.search)
+}
+
+// FindSubmatch is like Find, each match is followed by a pair of
+// offsets for every capture group in re, -1 for groups that did
+// not take part in the match.
+func FindSubmatch(re *Regexp, reader RuneReader) Finder {
+	return re.finder(reader, re.nfa.search)
+}
+
+// FindBackward return an iterator over successive matches of re,
+// going backward from rCur, in reader that streams runes backward
+// from rCur, like Buffer.BackStreamFrom(rCur). Each match is a pair
+// of rune offsets {start, end} within the buffer. The match ending
+// nearest to rCur is found first, preferring the one that starts
+// earliest, which is leftmost-longest semantics applied to the
+// reversed text. Runes after rCur are not seen, rCur is treated as
+// end of text.
+func FindBackward(re *Regexp, reader RuneReader, rCur int64) Finder {
+	return backward(re.finder(reader, re.rdfa.search), rCur)
+}
+
+// FindSubmatchBackward is like FindBackward, each match is
+// followed by a pair of offsets for every capture group in re, -1
+// for groups that did not take part in the match.
+func FindSubmatchBackward(re *Regexp, reader RuneReader, rCur int64) Finder {
+	return backward(re.finder(reader, re.rnfa.search), rCur)
+}
+
+// backward convert offsets from a backward search, counted from
+// rCur in reverse, to offsets within the buffer.
+func backward(iter Finder, rCur int64) Finder {
+	return Finder(func() []int64 {
+		loc := iter()
+		for i := 0; i < len(loc); i += 2 {
+			if loc[i] >= 0 {
+				loc[i], loc[i+1] = rCur-loc[i+1], rCur-loc[i]
+			}
+		}
+		return loc
+	})
+}
+
+func (re *Regexp) finder(
+	reader RuneReader, search func(*runeInput, int64) []int64) Finder {
+
+	if reader == nil {
+		return Finder(func() []int64 { return nil })
+	}
+	in := &runeInput{reader: reader}
+	pos, prevEnd, done := int64(0), int64(-1), false
+	return Finder(func() []int64 {
+		for !done {
+			loc := search(in, pos)
+			if loc == nil {
+				done = true
+				break
+			}
+			accept := true
+			if loc[1] == pos { // empty match, skip it if adjacent.
+				accept = loc[0] != prevEnd
+				if in.at(pos) < 0 {
+					done = true
+				}
+				pos++
+			} else {
+				pos = loc[1]
+			}
+			prevEnd = loc[1]
+			in.trim(pos - 1)
+			if accept {
+				return loc
+			}
+		}
+		in.close()
+		return nil
+	})
+}
+
+//-----------
+// rune input
+//-----------
+
+// runeInput read runes from reader on demand and remember them, so
+// that search can resume from the end of last match, which can be
+// behind the runes already read.
+type runeInput struct {
+	reader RuneReader
+	base   int64  // offset of runes[0]
+	runes  []rune // runes read from base onwards
+	eof    bool
+}
+
+// at return rune at pos, -1 at the end of input and before its
+// beginning.
+func (in *runeInput) at(pos int64) rune {
+	if pos < 0 {
+		return -1
+	} else if pos < in.base {
+		panic("impossible situation")
+	}
+	for !in.eof && pos >= in.base+int64(len(in.runes)) {
+		r, _, err := in.reader.ReadRune()
+		if err != nil {
+			in.eof = true
+			break
+		}
+		in.runes = append(in.runes, r)
+	}
+	if i := pos - in.base; i < int64(len(in.runes)) {
+		return in.runes[i]
+	}
+	return -1
+}
+
+// trim forget runes before pos, they are no more needed.
+func (in *runeInput) trim(pos int64) {
+	n := pos - in.base
+	if n > int64(len(in.runes)) {
+		n = int64(len(in.runes))
+	}
+	// amortize the copy, forget only if it frees half the runes.
+	if n < 1024 || n < int64(len(in.runes))/2 {
+		return
+	}
+	copy(in.runes, in.runes[n:])
+	in.runes = in.runes[:int64(len(in.runes))-n]
+	in.base += n
+}
+
+func (in *runeInput) close() {
+	if in.reader != nil {
+		in.reader.Close()
+	}
+	in.reader, in.runes, in.eof = nil, nil, true
+}
+
+// reverseSyntax return a copy of re that matches the reversed text,
+// for every match of re, where line and text boundaries are
+// swapped.
+func reverseSyntax(re *syntax.Regexp) *syntax.Regexp {
+	x := *re
+	x.Sub = make([]*syntax.Regexp, len(re.Sub))
+	for i, sub := range re.Sub {
+		x.Sub[i] = reverseSyntax(sub)
+	}
+	switch re.Op {
+	case syntax.OpLiteral:
+		x.Rune = reverseRunes(re.Rune)
+	case syntax.OpConcat:
+		x.Sub = reverseSubs(x.Sub)
+	case syntax.OpBeginLine:
+		x.Op = syntax.OpEndLine
+	case syntax.OpEndLine:
+		x.Op = syntax.OpBeginLine
+	case syntax.OpBeginText:
+		x.Op = syntax.OpEndText
+	case syntax.OpEndText:
+		x.Op = syntax.OpBeginText
+	}
+	return &x
+}
+
+func reverseSubs(subs []*syntax.Regexp) []*syntax.Regexp {
+	for i, j := 0, len(subs)-1; i < j; i, j = i+1, j-1 {
+		subs[i], subs[j] = subs[j], subs[i]
+	}
+	return subs
+}
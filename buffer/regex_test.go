@@ -0,0 +1,331 @@
+package buffer
+
+import "bytes"
+import "math/rand"
+import "reflect"
+import "regexp"
+import "testing"
+import "unicode/utf8"
+
+var regexPatterns = []string{
+	`a`, `ab`, `a|ab`, `ab|a`, `a*`, `a+?`, `(a|ab)(c|bcd)(d*)`, `x*`,
+	`[a-c]+`, `(?i)AB`, `.`, `.*`, `(?s).*`, `a.c`, `^a`, `a$`,
+	`(?m)^a`, `(?m)a$`, `(?m)^$`, `\bab\b`, `\Bb`, `\w+`, `\s+`,
+	`(a*)*`, `(a*)+b`, `(a|b)*?c`, `中+`, `[^a\n]+`, `\A|\z`, `b{2,3}`,
+	`(?U)a+`, `((a)|b)+`, `(a)|(b)|(c)`, `$`, `^`, `()`, `\n`,
+}
+
+var regexTexts = []string{
+	"", "a", "ab", "abc", "abcd", "aaa", "abab\nab ab", "xabcdx",
+	"a\nb\n\nab\n", "中中a中", "b ab ba abba", "cabbbbc", "\n\n",
+	"aAbB\tc", "a\xffb",
+}
+
+func TestRegexFind(t *testing.T) {
+	for _, longest := range []bool{false, true} {
+		for _, pattern := range regexPatterns {
+			ref := regexp.MustCompile(pattern)
+			re := MustCompileRegexp(pattern)
+			if longest {
+				ref.Longest()
+				re.Longest()
+			}
+			for _, text := range regexTexts {
+				testFindSubmatch(t, ref, re, text)
+			}
+		}
+	}
+}
+
+func TestRegexRandom(t *testing.T) {
+	seed := rand.Int63()
+	rnd := rand.New(rand.NewSource(seed))
+	atoms := []string{`a`, `b`, `.`, `[ab]`, `\b`, `^`, `$`, `(?m:^)`, `x`}
+	var gen func(depth int) string
+	gen = func(depth int) string {
+		if depth == 0 {
+			return atoms[rnd.Intn(len(atoms))]
+		}
+		switch rnd.Intn(6) {
+		case 0:
+			return gen(depth-1) + gen(depth-1)
+		case 1:
+			return gen(depth-1) + "|" + gen(depth-1)
+		case 2:
+			return "(" + gen(depth-1) + ")*"
+		case 3:
+			return "(" + gen(depth-1) + ")+?"
+		case 4:
+			return "(" + gen(depth-1) + ")?"
+		}
+		return "(" + gen(depth-1) + ")"
+	}
+	alphabet := []rune("ab \nx")
+	for i := 0; i < 2000; i++ {
+		pattern := gen(rnd.Intn(4))
+		text := make([]rune, rnd.Intn(16))
+		for j := range text {
+			text[j] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		ref, re := regexp.MustCompile(pattern), MustCompileRegexp(pattern)
+		if i%2 == 1 {
+			ref.Longest()
+			re.Longest()
+		}
+		if !testFindSubmatch(t, ref, re, string(text)) {
+			t.Fatalf("seed %v", seed)
+		}
+	}
+}
+
+func TestRegexLarge(t *testing.T) {
+	// match far from the beginning, and spanning rope leaves.
+	text := bytes.Repeat([]byte("hello world 中文 "), 100000)
+	text = append(text, "needle in the haystack"...)
+	rb := NewRopebuffer(text, testRopeBufferCapacity)
+	n := int64(utf8.RuneCount(text))
+
+	re := MustCompileRegexp(`n(e+)dle|hay(stack)`)
+	for _, find := range []func(*Regexp, RuneReader) Finder{Find, FindSubmatch} {
+		iter := find(re, rb.StreamFrom(0))
+		if loc := iter(); loc[0] != n-22 || loc[1] != n-16 {
+			t.Fatalf("expected %v, got %v", []int64{n - 22, n - 16}, loc)
+		} else if loc := iter(); loc[0] != n-8 || loc[1] != n {
+			t.Fatalf("expected %v, got %v", []int64{n - 8, n}, loc)
+		} else if loc := iter(); loc != nil {
+			t.Fatalf("unexpected %v", loc)
+		}
+	}
+	count := 0
+	for iter := Find(MustCompileRegexp(`中文`), rb.StreamFrom(0)); iter() != nil; {
+		count++
+	}
+	if count != 100000 {
+		t.Fatalf("expected %v, got %v", 100000, count)
+	}
+	// invalid cursor.
+	if loc := Find(re, rb.StreamFrom(-1))(); loc != nil {
+		t.Fatalf("unexpected %v", loc)
+	}
+}
+
+func TestRegexBackward(t *testing.T) {
+	assertions := regexp.MustCompile(`[\^$]|\\[bBAz]`)
+	for _, pattern := range regexPatterns {
+		if !assertions.MatchString(pattern) {
+			for _, text := range regexTexts {
+				testFindBackward(t, pattern, text)
+			}
+		}
+	}
+
+	seed := rand.Int63()
+	rnd := rand.New(rand.NewSource(seed))
+	atoms := []string{`a`, `b`, `.`, `[ab]`, `ab`, `a*`, `b+?`, `(a|ab)`}
+	alphabet := []rune("ab x")
+	for i := 0; i < 500; i++ {
+		pattern := ""
+		for j := rnd.Intn(3); j >= 0; j-- {
+			pattern += atoms[rnd.Intn(len(atoms))]
+		}
+		if rnd.Intn(2) == 0 {
+			pattern += "|" + atoms[rnd.Intn(len(atoms))]
+		}
+		text := make([]rune, rnd.Intn(12))
+		for j := range text {
+			text[j] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		if !testFindBackward(t, pattern, string(text)) {
+			t.Fatalf("seed %v", seed)
+		}
+	}
+}
+
+func TestRegexBackwardContext(t *testing.T) {
+	testcases := []struct {
+		pattern, text string
+		rCur          int64
+		refs          [][]int64
+	}{
+		{`(?m)^\w+`, "ab cd\nef gh", 11, [][]int64{{6, 8}, {0, 2}}},
+		{`\w+$`, "ab cd", 5, [][]int64{{3, 5}}},
+		{`\w+$`, "ab cd", 4, [][]int64{{3, 4}}},
+		{`\bcd\b`, "abcd cd", 7, [][]int64{{5, 7}}},
+		{`\Acd`, "cdcd", 4, [][]int64{{0, 2}}},
+		{`(?m)$`, "a\n\nb", 4, [][]int64{{4, 4}, {2, 2}, {1, 1}}},
+		{`a|ab`, "xab", 3, [][]int64{{1, 3}}},
+		{`(\w+)@(\w+)`, "x a@b c@d", 9, [][]int64{{6, 9, 6, 7, 8, 9}, {2, 5, 2, 3, 4, 5}}},
+		{`(a)|(b)`, "ab", 2, [][]int64{{1, 2, -1, -1, 1, 2}, {0, 1, 0, 1, -1, -1}}},
+	}
+	for _, tcase := range testcases {
+		rb := NewRopebuffer([]byte(tcase.text), 8)
+		re := MustCompileRegexp(tcase.pattern)
+		locs := [][]int64{}
+		iter := FindSubmatchBackward(re, rb.BackStreamFrom(tcase.rCur), tcase.rCur)
+		for loc := iter(); loc != nil; loc = iter() {
+			locs = append(locs, loc)
+		}
+		if !reflect.DeepEqual(locs, tcase.refs) {
+			t.Fatalf("%q %q expected %v, got %v", tcase.pattern, tcase.text, tcase.refs, locs)
+		}
+	}
+	re := MustCompileRegexp(`a`)
+	if loc := FindBackward(re, nil, 0)(); loc != nil {
+		t.Fatalf("unexpected %v", loc)
+	}
+}
+
+func TestRegexCompile(t *testing.T) {
+	if _, err := CompileRegexp(`a(b`); err == nil {
+		t.Fatalf("expected error")
+	}
+	re := MustCompileRegexp(`(a)(?P<x>b)`)
+	if x := re.NumSubexp(); x != 2 {
+		t.Fatalf("expected %v, got %v", 2, x)
+	} else if x := re.String(); x != `(a)(?P<x>b)` {
+		t.Fatalf("unexpected %v", x)
+	}
+}
+
+func BenchmarkRegexFind(b *testing.B) {
+	rb := NewRopebuffer(sampleData, testRopeBufferCapacity)
+	re := MustCompileRegexp(`(?i)[a-z]+ing\b`)
+	b.SetBytes(int64(len(sampleData)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for iter := Find(re, rb.StreamFrom(0)); iter() != nil; {
+		}
+	}
+}
+
+func BenchmarkRegexSubmatch(b *testing.B) {
+	rb := NewRopebuffer(sampleData, testRopeBufferCapacity)
+	re := MustCompileRegexp(`(?i)([a-z]+)ing\b`)
+	b.SetBytes(int64(len(sampleData)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for iter := FindSubmatch(re, rb.StreamFrom(0)); iter() != nil; {
+		}
+	}
+}
+
+// testFindSubmatch compare all matches of re in text, using Find and
+// FindSubmatch, with that of regexp package.
+func testFindSubmatch(t *testing.T, ref *regexp.Regexp, re *Regexp, text string) bool {
+	t.Helper()
+
+	rb := NewRopebuffer([]byte(text), 8)
+	positions := runePositions([]byte(text))
+	toRunes := func(off int) int64 {
+		if off < 0 {
+			return -1
+		}
+		for i, pos := range positions {
+			if pos == int64(off) {
+				return int64(i)
+			}
+		}
+		return int64(len(positions))
+	}
+
+	refs := [][]int64{}
+	for _, locs := range ref.FindAllStringSubmatchIndex(text, -1) {
+		loc := []int64{}
+		for _, off := range locs {
+			loc = append(loc, toRunes(off))
+		}
+		refs = append(refs, loc)
+	}
+	subs := [][]int64{}
+	for iter := FindSubmatch(re, rb.StreamFrom(0)); ; {
+		loc := iter()
+		if loc == nil {
+			break
+		}
+		subs = append(subs, loc)
+	}
+	if len(refs) == 0 && len(subs) == 0 {
+		// ok
+	} else if !reflect.DeepEqual(refs, subs) {
+		t.Errorf("%q %q submatch expected %v, got %v", ref, text, refs, subs)
+		return false
+	}
+	locs := [][]int64{}
+	for iter := Find(re, rb.StreamFrom(0)); ; {
+		loc := iter()
+		if loc == nil {
+			break
+		}
+		locs = append(locs, loc)
+	}
+	for i, loc := range locs {
+		if i >= len(refs) || !reflect.DeepEqual(loc, refs[i][:2]) {
+			t.Errorf("%q %q find expected %v, got %v", ref, text, refs, locs)
+			return false
+		}
+	}
+	if len(locs) != len(refs) {
+		t.Errorf("%q %q find expected %v, got %v", ref, text, refs, locs)
+		return false
+	}
+	return true
+}
+
+// testFindBackward compare all matches of pattern, searching backward
+// from the end of text, with brute force search using regexp
+// package, for patterns without empty-width assertions.
+func testFindBackward(t *testing.T, pattern, text string) bool {
+	t.Helper()
+
+	runes := []rune(text)
+	n := int64(len(runes))
+	anchored := regexp.MustCompile(`^(?:` + pattern + `)$`)
+	// match ending nearest to cursor, starting earliest.
+	nearest := func(cursor int64) []int64 {
+		for e := cursor; e >= 0; e-- {
+			for s := int64(0); s <= e; s++ {
+				if anchored.MatchString(string(runes[s:e])) {
+					return []int64{s, e}
+				}
+			}
+		}
+		return nil
+	}
+	refs := [][]int64{}
+	for pos, prevEnd := int64(0), int64(-1); pos <= n; { // reversed offsets.
+		m := nearest(n - pos)
+		if m == nil {
+			break
+		}
+		start, end, accept := n-m[1], n-m[0], true
+		if end == pos {
+			accept = start != prevEnd
+			pos++
+		} else {
+			pos = end
+		}
+		prevEnd = end
+		if accept {
+			refs = append(refs, m)
+		}
+	}
+
+	rb := NewRopebuffer([]byte(text), 8)
+	re := MustCompileRegexp(pattern)
+	for _, find := range []func(*Regexp, RuneReader, int64) Finder{
+		FindBackward, FindSubmatchBackward} {
+
+		locs := [][]int64{}
+		iter := find(re, rb.BackStreamFrom(n), n)
+		for loc := iter(); loc != nil; loc = iter() {
+			locs = append(locs, loc[:2])
+		}
+		if len(locs) == 0 && len(refs) == 0 {
+			continue
+		} else if !reflect.DeepEqual(locs, refs) {
+			t.Errorf("%q %q expected %v, got %v", pattern, text, refs, locs)
+			return false
+		}
+	}
+	return true
+}
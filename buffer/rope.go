@@ -1,528 +1,638 @@
-// +build ignore
-
 package buffer
 
 import "fmt"
 import "io"
+import "bytes"
 import "math"
 import "unicode/utf8"
 
 var RopeBufferCapacity = int64(64)
 
-// RopeBuffer represents a persistent rope data structure.
+var newlineBytes = []byte{'\n'}
+
+// ropeFibonacci sequence, F(0) = 0, F(1) = 1, F(2) = 1 ..., used to
+// check whether a rope is balanced.
+var ropeFibonacci = func() []int64 {
+	fib := []int64{0, 1}
+	for {
+		n := len(fib)
+		x := fib[n-1] + fib[n-2]
+		if x < fib[n-1] { // overflow
+			return fib
+		}
+		fib = append(fib, x)
+	}
+}()
+
+// RopeBuffer represents a persistent rope data structure. Text is
+// held as utf8 encoded bytes in leaf nodes, Weight, Len and Cap
+// are in bytes. Every node also caches the number of runes and
+// the number of newlines, so that rune offsets, byte offsets and
+// lines can be mapped to each other in O(log n). Buffer APIs
+// address the rope in runes.
 type RopeBuffer struct { // buffer implementation.
-	Text   []byte      `json:"text,omitempty"`
-	Weight int64       `json:"weight,omitempty"`
-	Len    int64       `json:"length,omitempty"`
-	Cap    int64       `json:"capacity,omitempty"`
-	Left   *RopeBuffer `json:"left,omitempty"`
-	Right  *RopeBuffer `json:"right,omitempty"`
+	Text    []byte      `json:"text,omitempty"`
+	Weight  int64       `json:"weight,omitempty"`   // bytes on the left
+	Len     int64       `json:"length,omitempty"`   // bytes in the tree
+	Cap     int64       `json:"capacity,omitempty"` // max bytes in leaf
+	RWeight int64       `json:"rweight,omitempty"`  // runes on the left
+	RLen    int64       `json:"runes,omitempty"`    // runes in the tree
+	NWeight int64       `json:"nweight,omitempty"`  // newlines on the left
+	NLen    int64       `json:"newlines,omitempty"` // newlines in the tree
+	Depth   int64       `json:"depth,omitempty"`
+	Left    *RopeBuffer `json:"left,omitempty"`
+	Right   *RopeBuffer `json:"right,omitempty"`
 }
 
 // NewRopebuffer returns a new buffer, initialized with text.
 // Capacity will decide the maximum length the buffer can hold
 // beyond which it splits. The buffer always splits at unicode
 // boundary.
-func NewRopebuffer(text []byte, capacity int64) (*RopeBuffer, error) {
-	l := int64(len(text))
-	newt := make([]byte, l)
+func NewRopebuffer(text []byte, capacity int64) *RopeBuffer {
+	newt := make([]byte, len(text))
 	copy(newt, text)
-	rb := &RopeBuffer{Text: newt, Weight: l, Len: l, Cap: capacity}
-	return rb.build(capacity)
+	return newRopeLeaf(newt, capacity).build(capacity)
 }
 
 // NewRopeLevel returns a new level of rope-buffer initialized
 // with left and right. Uses the length of left-buffer as weight
 // and left-buffer capacity as its own capacity.
 func NewRopeLevel(length int64, left, right *RopeBuffer) *RopeBuffer {
-	return &RopeBuffer{
-		Weight: left.Len, Len: length, Left: left, Right: right, Cap: left.Cap,
+	rb := &RopeBuffer{Left: left, Right: right, Cap: left.Cap}
+	rb.updateLevel()
+	rb.Len = length
+	return rb
+}
+
+// newRopeRunes return a rope for text, raw-runes that would make
+// up a valid rune once encoded are kept in separate leaves.
+func newRopeRunes(text []rune, capacity int64) *RopeBuffer {
+	var acc *RopeBuffer
+	for _, seg := range runes2Segments(text) {
+		acc = acc.concat(NewRopebuffer(seg, capacity))
 	}
+	return acc
 }
 
 func (rb *RopeBuffer) String() string {
 	return fmt.Sprintf("{W%d L%d C%d}", rb.Weight, rb.Len, rb.Cap)
 }
 
-//----------------------
-// Buffer implementation
-//----------------------
+//----------
+// rune APIs
+//----------
 
 // Length implement Buffer{} interface.
-func (rb *RopeBuffer) Length() (n int64, err error) {
+func (rb *RopeBuffer) Length() int64 {
 	if rb == nil {
-		return n, ErrorBufferNil
+		return 0
 	}
-	return rb.Len, nil
-}
-
-// Value implement Buffer{} interface.
-func (rb *RopeBuffer) Value() []byte {
-	if rb == nil {
-		return nil
-	} else if rb.Len == 0 {
-		return []byte{}
-	}
-	acc := make([]byte, rb.Len)
-	rb.value(0, rb.Len, acc)
-	return acc
+	return rb.RLen
 }
 
 // Slice implement Buffer{} interface.
-func (rb *RopeBuffer) Slice(bCur, bn int64) ([]byte, error) {
-	if rb == nil {
-		return nil, ErrorBufferNil
-	} else if bn == 0 || rb.Len == 0 {
-		return []byte{}, nil
-	} else if bCur < 0 || bCur > rb.Len {
-		return nil, ErrorIndexOutofbound
-	} else if bCur+bn > rb.Len {
-		return nil, ErrorIndexOutofbound
-	}
-
-	acc := make([]byte, bn)
-	rb.value(bCur, bn, acc)
-	return acc, nil
-}
-
-// RuneAt implement Buffer{} interface.
-func (rb *RopeBuffer) RuneAt(bCur int64) (ch rune, size int, err error) {
-	if rb == nil {
-		return ch, size, ErrorBufferNil
-	} else if bCur < 0 || bCur >= rb.Len {
-		return ch, size, ErrorIndexOutofbound
+func (rb *RopeBuffer) Slice(rCur, rn int64) Buffer {
+	l := rb.Length()
+	endCur := rCur + rn
+	if rCur < 0 || rCur > l {
+		return nil
+	} else if endCur < rCur {
+		return nil
+	} else if endCur > l {
+		endCur = l
 	}
-
-	if rb.isLeaf() {
-		ch, size := utf8.DecodeRune(rb.Text[bCur:])
-		if ch == utf8.RuneError {
-			return ch, size, ErrorInvalidEncoding
-		}
-		return ch, size, nil
-
-	} else if bCur >= rb.Weight {
-		return rb.Right.RuneAt(bCur - rb.Weight)
+	bCur, bEnd := rb.byteOffset(rCur), rb.byteOffset(endCur)
+	if bCur == bEnd {
+		return NewRopebuffer([]byte{}, rb.Cap)
 	}
-	return rb.Left.RuneAt(bCur)
+	_, right := rb.own().split(bCur)
+	left, _ := right.split(bEnd - bCur)
+	return left.own()
 }
 
 // Runes implement Buffer{} interface.
-func (rb *RopeBuffer) Runes() ([]rune, error) {
-	acc := make([]rune, rb.Len)
-	count, size, err := rb.runes(0, rb.Len, acc)
-	if err != nil {
-		return nil, err
-	} else if size != rb.Len {
-		panic("mismatch in decoded bytes and length")
-	}
-	return acc[:count], nil
-}
-
-// RuneSlice implement Buffer{} interface.
-func (rb *RopeBuffer) RuneSlice(bCur, rn int64) ([]rune, int64, error) {
-	if rb == nil {
-		return nil, 0, ErrorBufferNil
-	} else if rn == 0 {
-		return []rune{}, 0, nil
-	} else if bCur < 0 || bCur >= rb.Len {
-		return nil, 0, ErrorIndexOutofbound
-	} else if rb.Len == 0 {
-		return nil, 0, ErrorIndexOutofbound
-	}
-
-	var reader RuneReader
-	if rn > 0 {
-		reader = rb.StreamCount(bCur, rn)
-	} else {
-		reader = rb.BackStreamCount(bCur, rn)
-	}
-	defer reader.Close()
-
-	var err error
-	var r rune
-	var sz int
-	i, runes, size := 0, make([]rune, rn), int64(0)
-	for {
-		if r, sz, err = reader.ReadRune(); err == io.EOF {
-			break
-		} else if err == nil {
-			runes[i] = r
-			i, size = i+1, size+int64(sz)
-		} else {
-			return nil, 0, err
-		}
+func (rb *RopeBuffer) Runes() []rune {
+	if rb == nil || rb.Len == 0 {
+		return nil
 	}
-	return runes[:i], size, nil
+	acc := make([]rune, rb.Len)
+	count := rb.runes(acc)
+	return acc[:count]
 }
 
 // Concat implement Buffer{} interface.
-func (rb *RopeBuffer) Concat(right Buffer) (Buffer, error) {
-	rightbuf := right.(*RopeBuffer)
-	if rb == nil {
-		return rightbuf, nil
-	} else if rightbuf == nil {
-		return rb, nil
-	}
-	return NewRopeLevel(rb.Len+rightbuf.Len, rb, rightbuf), nil
+func (rb *RopeBuffer) Concat(right Buffer) Buffer {
+	return rb.own().concat(right.(*RopeBuffer).own()).own()
 }
 
 // Split implement Buffer{} interface.
-func (rb *RopeBuffer) Split(bCur int64) (left, right Buffer, err error) {
-	if rb == nil {
-		return nil, nil, ErrorBufferNil
-
-	} else if bCur < 0 || bCur > rb.Len {
-		return nil, nil, ErrorIndexOutofbound
+func (rb *RopeBuffer) Split(rCur int64) (left, right Buffer) {
+	if rb == nil || rCur < 0 {
+		return left, right
 	}
-	return rb.split(bCur, nil)
+	l := rb.Length()
+	if rCur >= l {
+		return rb, nil
+	} else if rCur == 0 {
+		return nil, rb
+	}
+	lrb, rrb := rb.own().split(rb.byteOffset(rCur))
+	return lrb.own(), rrb.own()
 }
 
 // Insert implement Buffer{} interface.
-func (rb *RopeBuffer) Insert(bCur int64, text []rune) (Buffer, error) {
+func (rb *RopeBuffer) Insert(rCur int64, text []rune) Buffer {
 	if text == nil {
-		return rb, nil
-
+		return rb
 	} else if rb == nil {
-		return rb, ErrorBufferNil
-
-	} else if bCur < 0 || bCur > rb.Len {
-		return rb, ErrorIndexOutofbound
-	}
-	textb := []byte(string(text))
-	if len(textb) == 0 { // nothing to insert
-		return rb, nil
+		panic(ErrorBufferNil)
+	} else if !rb.isValidCursor(rCur) {
+		panic(ErrorIndexOutofbound)
 	}
-	insrtRight, err := NewRopebuffer(textb, rb.Cap)
-	if err != nil {
-		return rb, err
+	insrt := newRopeRunes(text, rb.Cap)
+	if insrt.Len == 0 { // nothing to insert
+		return rb.own()
 	}
-
-	left, right, err := rb.Split(bCur)
-	if err != nil {
-		return rb, err
-	}
-	x, err := left.Concat(insrtRight)
-	if err != nil {
-		return rb, err
-	}
-	return x.Concat(right)
+	left, right := rb.own().split(rb.byteOffset(rCur))
+	return left.concat(insrt).concat(right).own()
 }
 
 // Delete implement Buffer{} interface.
-func (rb *RopeBuffer) Delete(bCur, rn int64) (Buffer, error) {
-	if rb == nil {
-		return rb, ErrorBufferNil
-	} else if rn == 0 {
-		return rb, nil
-	} else if bCur < 0 || bCur > rb.Len-1 {
-		return rb, ErrorIndexOutofbound
-	}
-
-	runes, size, err := rb.RuneSlice(bCur, rn)
-	if err != nil {
-		return rb, err
-	} else if int64(len(runes)) != rn {
-		return rb, ErrorIndexOutofbound
-	} else if end := bCur + size; end < 0 || end > rb.Len {
-		return rb, ErrorIndexOutofbound
-	} else if bCur == 0 && size == rb.Len { // to delete entire buffer
-		return NewRopebuffer([]byte{}, rb.Cap)
-	}
-
-	left, forRight, err := rb.Split(bCur)
-	if err != nil {
-		return rb, err
+func (rb *RopeBuffer) Delete(rCur, rn int64) Buffer {
+	if rn == 0 {
+		return rb
+	} else if rb == nil {
+		panic(ErrorBufferNil)
+	} else if !rb.isValidCursor(rCur) || !rb.isValidCursor(rCur+rn) {
+		panic(ErrorIndexOutofbound)
 	}
-	_, right, err := forRight.Split(size)
-	if err != nil {
-		return rb, err
+	bCur, bEnd := rb.byteOffset(rCur), rb.byteOffset(rCur+rn)
+	if bCur == 0 && bEnd == rb.Len { // to delete entire buffer
+		return NewRopebuffer([]byte{}, rb.Cap)
 	}
-	return left.Concat(right)
+	left, forRight := rb.own().split(bCur)
+	_, right := forRight.split(bEnd - bCur)
+	return left.concat(right).own()
 }
 
 // InsertIn implement Buffer{} interface.
-func (rb *RopeBuffer) InsertIn(bCur int64, text []rune) (Buffer, error) {
-	textb := []byte(string(text)) // TODO: this could be inefficient
-	if text == nil || len(textb) == 0 {
-		return rb, nil
+func (rb *RopeBuffer) InsertIn(rCur int64, text []rune) Buffer {
+	if text == nil {
+		return rb
 	} else if rb == nil {
-		return rb, ErrorBufferNil
-	} else if rb.Len == 0 {
-		return NewRopebuffer(textb, rb.Cap)
-	} else if bCur < 0 || bCur > rb.Len {
-		return rb, ErrorIndexOutofbound
+		panic(ErrorBufferNil)
+	} else if !rb.isValidCursor(rCur) {
+		panic(ErrorIndexOutofbound)
 	}
-
-	if rb.isLeaf() { // make inplace modification
-		text := ioInsert(rb.Text, textb, bCur)
-		newrb, err := NewRopebuffer(text, rb.Cap)
-		if err != nil {
-			return rb, err
-		}
-		rb.copyrefs(newrb)
-		return rb, nil
-	}
-	if bCur >= rb.Weight {
-		right, err := rb.Right.InsertIn(bCur-rb.Weight, text)
-		if err != nil {
-			return rb, err
-		}
-		rb.Right = right.(*RopeBuffer)
-		rb.Len = rb.Left.Len + rb.Right.Len
-		return rb, nil
+	insrt := newRopeRunes(text, rb.Cap)
+	if insrt.Len == 0 {
+		return rb
 	}
-	left, err := rb.Left.InsertIn(bCur, text)
-	if err != nil {
-		return rb, err
+	rb.insertIn(rb.byteOffset(rCur), insrt)
+	if !rb.IsBalanced() {
+		rb.copyrefs(rb.Rebalance())
 	}
-	rb.Left = left.(*RopeBuffer)
-	rb.Weight, rb.Len = rb.Left.Len, rb.Left.Len+rb.Right.Len
-	return rb, nil
+	return rb
 }
 
 // DeleteIn implement Buffer{} interface.
-func (rb *RopeBuffer) DeleteIn(bCur, rn int64) (Buffer, error) {
-	if rb == nil {
-		return rb, ErrorBufferNil
-	} else if rn == 0 {
-		return rb, nil
-	} else if bCur < 0 || bCur > (rb.Len-1) {
-		return rb, ErrorIndexOutofbound
+func (rb *RopeBuffer) DeleteIn(rCur, rn int64) Buffer {
+	if rn == 0 {
+		return rb
+	} else if rb == nil {
+		panic(ErrorBufferNil)
+	} else if !rb.isValidCursor(rCur) || !rb.isValidCursor(rCur+rn) {
+		panic(ErrorIndexOutofbound)
 	}
-	_, size, err := rb.RuneSlice(bCur, rn)
-	if err != nil {
-		return rb, err
-	} else if end := bCur + size; end < 0 || end > rb.Len {
-		return rb, ErrorIndexOutofbound
+	bCur, bEnd := rb.byteOffset(rCur), rb.byteOffset(rCur+rn)
+	rb.deleteIn(bCur, bEnd-bCur)
+	if !rb.IsBalanced() {
+		rb.copyrefs(rb.Rebalance())
 	}
-	return rb.deleteIn(bCur, size)
+	return rb
 }
 
+//------------
+// search APIs
+//------------
+
 // StreamFrom implement Buffer interface{}.
-func (rb *RopeBuffer) StreamFrom(bCur int64) RuneReader {
-	// clear up the corner cases.
-	if bCur < 0 || rb.Len == 0 {
+func (rb *RopeBuffer) StreamFrom(rCur int64) RuneReader {
+	if !rb.isValidCursor(rCur) {
+		return nil
+	} else if rb.Len == 0 {
 		return iterator(runeEnd)
 	}
-	return rb.runeIterator(bCur)
+	return rb.runeIterator(rCur)
 }
 
 // StreamCount implement Buffer interface{}.
-func (rb *RopeBuffer) StreamCount(bCur, count int64) RuneReader {
-	// clear up the corner cases.
-	if bCur < 0 || (rb.Len == 0) || (count <= 0) {
+func (rb *RopeBuffer) StreamCount(rCur, count int64) RuneReader {
+	if !rb.isValidCursor(rCur) {
+		return nil
+	} else if rb.Len == 0 || count <= 0 {
 		return iterator(runeEnd)
 	}
-	return rb.runeIteratorCount(bCur, count)
+	return rb.runeIteratorCount(rCur, count)
 }
 
-// StreamTill implement Buffer interface{}.
-func (rb *RopeBuffer) StreamTill(bCur, till int64) RuneReader {
-	// clear up the corner cases.
-	if bCur < 0 || (rb.Len == 0) || (till >= bCur) {
-		return iterator(runeEnd)
-	}
-	return rb.runeIteratorTill(bCur, till)
+// StreamTill returns a RuneReader starting from `rCur` till the
+// rune offset `till`.
+func (rb *RopeBuffer) StreamTill(rCur, till int64) RuneReader {
+	return rb.StreamCount(rCur, till-rCur)
 }
 
 // BackStreamFrom implement Buffer interface{}.
-func (rb *RopeBuffer) BackStreamFrom(bCur int64) RuneReader {
-	if bCur < 0 || rb.Len == 0 {
+func (rb *RopeBuffer) BackStreamFrom(rCur int64) RuneReader {
+	if !rb.isValidCursor(rCur) {
+		return nil
+	} else if rb.Len == 0 {
 		return iterator(runeEnd)
 	}
-	return rb.runeRIterator(bCur)
+	return rb.runeRIterator(rCur)
 }
 
 // BackStreamCount implement Buffer interface{}.
-func (rb *RopeBuffer) BackStreamCount(bCur, count int64) RuneReader {
-	if bCur < 0 || (rb.Len == 0) || (count <= 0) {
+func (rb *RopeBuffer) BackStreamCount(rCur, count int64) RuneReader {
+	if !rb.isValidCursor(rCur) {
+		return nil
+	} else if rb.Len == 0 || count <= 0 {
 		return iterator(runeEnd)
 	}
-	return rb.runeRIteratorCount(bCur, count)
+	return rb.runeRIteratorCount(rCur, count)
 }
 
-// BackStreamTill implement Buffer interface{}.
-func (rb *RopeBuffer) BackStreamTill(bCur, till int64) RuneReader {
-	if bCur < 0 || (rb.Len == 0) || (till >= bCur) {
-		return iterator(runeEnd)
+// BackStreamTill returns a RuneReader starting from `rCur`,
+// streaming in the backward direction till the rune offset
+// `till`.
+func (rb *RopeBuffer) BackStreamTill(rCur, till int64) RuneReader {
+	return rb.BackStreamCount(rCur, rCur-till)
+}
+
+//----------
+// byte APIs
+//----------
+
+// Size implement Buffer{} interface.
+func (rb *RopeBuffer) Size() int64 {
+	if rb == nil {
+		return 0
+	}
+	return rb.Len
+}
+
+// Bytes implement Buffer{} interface.
+func (rb *RopeBuffer) Bytes() []byte {
+	if rb == nil {
+		return nil
+	} else if rb.Len == 0 {
+		return []byte{}
 	}
-	return rb.runeRIteratorTill(bCur, till)
+	acc := make([]byte, rb.Len)
+	rb.value(0, rb.Len, acc)
+	return acc
 }
 
 // Stats implement Buffer{} interface.
 func (rb *RopeBuffer) Stats() (Statistics, error) {
+	if rb == nil {
+		return nil, ErrorBufferNil
+	}
 	s := newRBStatistics()
 	rb.stats(1, s)
 	return Statistics(s), nil
 }
 
+// IsBalanced check the rope against the Fibonacci depth criterion
+// of Boehm, Atkinson and Plass, a rope of depth n is balanced if
+// its length is atleast F(n+2).
+func (rb *RopeBuffer) IsBalanced() bool {
+	if rb == nil || rb.isLeaf() {
+		return true
+	} else if n := rb.Depth + 2; n < int64(len(ropeFibonacci)) {
+		return rb.Len >= ropeFibonacci[n]
+	}
+	return false
+}
+
+// Rebalance return a balanced rope with the same content, leaf
+// nodes are shared with the original rope and adjacent leaf nodes
+// are merged when their combined length fits within capacity.
+// Persistent API.
+func (rb *RopeBuffer) Rebalance() *RopeBuffer {
+	if rb == nil || rb.isLeaf() {
+		return rb
+	}
+	forest := make([]*RopeBuffer, len(ropeFibonacci))
+	rb.Walk(0, func(_ int64, leaf *RopeBuffer) bool {
+		ropeForestInsert(forest, leaf)
+		return true
+	})
+	var acc *RopeBuffer
+	for _, x := range forest {
+		if x != nil {
+			acc = x.join(acc)
+		}
+	}
+	if acc == nil { // all leaves are empty
+		return NewRopebuffer([]byte{}, rb.Cap)
+	}
+	return acc.own()
+}
+
+//----------
+// line APIs
+//----------
+
+// Lines return the number of lines in the rope, which is one more
+// than the number of newlines.
+func (rb *RopeBuffer) Lines() int64 {
+	if rb == nil {
+		return 0
+	}
+	return rb.NLen + 1
+}
+
+// LineStart return the rune offset of the first rune in line,
+// lines are counted from 0. Return -1 if line is not in the rope.
+func (rb *RopeBuffer) LineStart(line int64) int64 {
+	if rb == nil || line < 0 || line > rb.NLen {
+		return -1
+	} else if line == 0 {
+		return 0
+	}
+	return rb.nthNewline(line) + 1
+}
+
+// LineOf return the line containing rune offset rCur, lines are
+// counted from 0. A newline belongs to the line it terminates.
+// Return -1 if rCur is not a valid cursor.
+func (rb *RopeBuffer) LineOf(rCur int64) int64 {
+	if rb == nil || !rb.isValidCursor(rCur) {
+		return -1
+	}
+	return rb.newlinesBefore(rCur)
+}
+
+// RuneToByte return the byte offset for rune offset rCur. Return
+// -1 if rCur is not a valid cursor.
+func (rb *RopeBuffer) RuneToByte(rCur int64) int64 {
+	if rb == nil || !rb.isValidCursor(rCur) {
+		return -1
+	}
+	return rb.byteOffset(rCur)
+}
+
+// ByteToRune return the rune offset for utf8 aligned byte offset
+// bCur. Return -1 if bCur is outside the rope.
+func (rb *RopeBuffer) ByteToRune(bCur int64) int64 {
+	if rb == nil || bCur < 0 || bCur > rb.Len {
+		return -1
+	}
+	return rb.runeOffset(bCur)
+}
+
 // JohnnieWalker gets called for every leaf node in the
 // rope-tree. If johnnie walks forward, bCur points to
 // first/start rune in the buffer. If johnnie walks
-// backward, bCur points to the last/start rune in the
+// backward, bCur points to the end of last rune in the
 // buffer.
 type JohnnieWalker func(bCur int64, rb *RopeBuffer) bool
 
 // Walk the rope-tree starting from `bCur`,
 // walk until johnnie return false.
 func (rb *RopeBuffer) Walk(bCur int64, walkFn JohnnieWalker) bool {
-	if rb.isLeaf() && bCur < rb.Len {
-		return walkFn(bCur, rb)
+	if rb.isLeaf() {
+		if bCur < rb.Len {
+			return walkFn(bCur, rb)
+		}
+		return true
 
-	} else if !rb.isLeaf() {
-		if bCur >= rb.Weight && rb.Weight < rb.Len {
-			return rb.Right.Walk(bCur-rb.Weight, walkFn)
+	} else if bCur >= rb.Weight {
+		return rb.Right.Walk(bCur-rb.Weight, walkFn)
 
-		} else {
-			var cont bool
-			if rb.Left != nil {
-				if cont = rb.Left.Walk(bCur, walkFn); cont && rb.Right != nil {
-					cont = rb.Right.Walk(0, walkFn)
-				}
-			} else if rb.Right != nil {
-				cont = rb.Right.Walk(0, walkFn)
-			} else {
-				return true
-			}
-			return cont
-		}
+	} else if rb.Left.Walk(bCur, walkFn) {
+		return rb.Right.Walk(0, walkFn)
 	}
-	return true
+	return false
 }
 
 // WalkBack in the rope-tree starting from `bCur`.
 // walk until johnnie return false.
 func (rb *RopeBuffer) WalkBack(bCur int64, walkFn JohnnieWalker) bool {
-	if rb.isLeaf() && bCur >= 0 {
-		if bCur == rb.Weight {
-			n, err := getRuneStart(rb.Text[:bCur], true /*reverse*/)
-			if err == nil {
-				return walkFn(n, rb)
-			}
-		} else {
+	if rb.isLeaf() {
+		if bCur > 0 {
 			return walkFn(bCur, rb)
 		}
+		return true
 
-	} else if !rb.isLeaf() {
-		if bCur >= rb.Weight && rb.Weight < rb.Len {
-			var cont bool
-			if rb.Right != nil {
-				cont = rb.Right.WalkBack(bCur-rb.Weight, walkFn)
-				if cont && rb.Left != nil {
-					cont = rb.Left.WalkBack(rb.Weight, walkFn)
-				}
-			} else if rb.Left != nil {
-				cont = rb.Left.WalkBack(rb.Weight, walkFn)
-			} else {
-				return true
-			}
-			return cont
+	} else if bCur <= rb.Weight {
+		return rb.Left.WalkBack(bCur, walkFn)
 
-		} else if rb.Left != nil {
-			return rb.Left.WalkBack(bCur, walkFn)
-		}
+	} else if rb.Right.WalkBack(bCur-rb.Weight, walkFn) {
+		return rb.Left.WalkBack(rb.Weight, walkFn)
 	}
-	return true
+	return false
 }
 
 //----------------
 // Local functions
 //----------------
 
-func (rb *RopeBuffer) clear() *RopeBuffer {
-	rb.Text = []byte{}
-	rb.Weight, rb.Len = int64(len(rb.Text)), int64(len(rb.Text))
-	rb.Left, rb.Right = nil, nil
-	return rb
-}
-
 func (rb *RopeBuffer) isLeaf() bool {
 	return rb.Left == nil
 }
 
+func (rb *RopeBuffer) isValidCursor(rCur int64) bool {
+	return 0 <= rCur && rCur <= rb.Length()
+}
+
 func (rb *RopeBuffer) copyrefs(newrb *RopeBuffer) {
 	rb.Text = newrb.Text
 	rb.Weight, rb.Len, rb.Cap = newrb.Weight, newrb.Len, newrb.Cap
-	rb.Left, rb.Right = newrb.Left, newrb.Right
+	rb.RWeight, rb.RLen = newrb.RWeight, newrb.RLen
+	rb.NWeight, rb.NLen = newrb.NWeight, newrb.NLen
+	rb.Depth, rb.Left, rb.Right = newrb.Depth, newrb.Left, newrb.Right
 }
 
-func (rb *RopeBuffer) build(capacity int64) (*RopeBuffer, error) {
-	var x, y *RopeBuffer
+// newRopeLeaf return a leaf node for text, without copying.
+func newRopeLeaf(text []byte, capacity int64) *RopeBuffer {
+	rb := &RopeBuffer{Text: text, Cap: capacity}
+	rb.updateLeaf()
+	return rb
+}
+
+// updateLeaf compute the cached counts for a leaf node from its text.
+func (rb *RopeBuffer) updateLeaf() {
+	rb.Len = int64(len(rb.Text))
+	rb.RLen = int64(utf8.RuneCount(rb.Text))
+	rb.NLen = int64(bytes.Count(rb.Text, newlineBytes))
+	rb.Weight, rb.RWeight, rb.NWeight = rb.Len, rb.RLen, rb.NLen
+}
 
+// updateLevel compute the cached counts for an intermediate node
+// from its children.
+func (rb *RopeBuffer) updateLevel() {
+	left, right := rb.Left, rb.Right
+	rb.Weight, rb.Len = left.Len, left.Len+right.Len
+	rb.RWeight, rb.RLen = left.RLen, left.RLen+right.RLen
+	rb.NWeight, rb.NLen = left.NLen, left.NLen+right.NLen
+	rb.Depth = maxDepth(left, right) + 1
+}
+func (rb *RopeBuffer) build(capacity int64) *RopeBuffer {
 	if rb.isLeaf() && rb.Len > 0 && rb.Len > capacity {
-		splitAt := rb.Len / 2
-		n, err := getRuneStart(rb.Text[splitAt:], false /*reverse*/)
-		if err != nil {
-			return nil, err
-		}
-		splitAt = splitAt + n
-		l, r, err := rb.Split(splitAt)
-		if err != nil {
-			return nil, err
+		splitAt := alignRune(rb.Text, rb.Len/2)
+		if splitAt >= rb.Len {
+			splitAt = alignRune(rb.Text, 1)
 		}
-		left, right := l.(*RopeBuffer), r.(*RopeBuffer)
-		if x, err = left.build(capacity); err != nil {
-			return nil, err
+		if splitAt >= rb.Len { // leaf is a single rune.
+			return rb
 		}
-		if y, err = right.build(capacity); err != nil {
-			return nil, err
-		}
-		buf, err := x.Concat(y)
-		return buf.(*RopeBuffer), err
+		left, right := rb.split(splitAt)
+		return left.build(capacity).concat(right.build(capacity))
 	}
-	return rb, nil
+	return rb
 }
 
-func (rb *RopeBuffer) split(
-	bCur int64, right *RopeBuffer) (*RopeBuffer, *RopeBuffer, error) {
+// concat two ropes, either of them can be nil. If the resulting
+// rope is not balanced, it is rebalanced.
+func (rb *RopeBuffer) concat(right *RopeBuffer) *RopeBuffer {
+	x := rb.join(right)
+	if !x.IsBalanced() {
+		return x.Rebalance()
+	}
+	return x
+}
 
-	var err error
-	if bCur == rb.Weight { // exact
-		if rb.isLeaf() {
-			return rb, rb.Right /*nil*/, nil
+// join two ropes, either of them can be nil. Two leaf nodes are
+// merged into a single leaf if they fit within capacity, and their
+// text stays the same runes once joined.
+func (rb *RopeBuffer) join(right *RopeBuffer) *RopeBuffer {
+	if rb == nil || rb.Len == 0 {
+		if right == nil {
+			return rb
 		}
-		return rb.Left, rb.Right, nil
+		return right
+	} else if right == nil || right.Len == 0 {
+		return rb
+	} else if rb.isLeaf() && right.isLeaf() && rb.Len+right.Len <= rb.Cap &&
+		joinable(rb.Text, right.Text) {
 
-	} else if bCur > rb.Weight { // recurse on the right
-		newRight, right, err := rb.Right.split(bCur-rb.Weight, right)
-		if err != nil {
-			return nil, nil, err
+		text := make([]byte, rb.Len+right.Len)
+		copy(text, rb.Text)
+		copy(text[rb.Len:], right.Text)
+		return newRopeLeaf(text, rb.Cap)
+	}
+	return NewRopeLevel(rb.Len+right.Len, rb, right)
+}
+
+// ropeForestInsert add leaf to the forest of balanced ropes, where
+// forest[i] holds a rope of length in [F(i), F(i+1)). Ropes in higher
+// slots precede the ropes in lower slots.
+func ropeForestInsert(forest []*RopeBuffer, leaf *RopeBuffer) {
+	var prefix *RopeBuffer
+	i := 2
+	for ; leaf.Len >= ropeFibonacci[i+1]; i++ {
+		if forest[i] != nil {
+			prefix, forest[i] = forest[i].join(prefix), nil
+		}
+	}
+	x := prefix.join(leaf)
+	for {
+		if forest[i] != nil {
+			x, forest[i] = forest[i].join(x), nil
 		}
-		left, err := rb.Left.Concat(newRight)
-		if err != nil {
-			return nil, nil, err
+		if x.Len < ropeFibonacci[i+1] {
+			break
 		}
-		return left.(*RopeBuffer), right, nil
+		i++
+	}
+	forest[i] = x
+}
+
+func maxDepth(left, right *RopeBuffer) int64 {
+	if left.Depth > right.Depth {
+		return left.Depth
+	}
+	return right.Depth
+}
+
+// split rope at byte offset bCur, either of the returned ropes
+// can be nil.
+func (rb *RopeBuffer) split(bCur int64) (*RopeBuffer, *RopeBuffer) {
+	if bCur <= 0 {
+		return nil, rb
+	} else if bCur >= rb.Len {
+		return rb, nil
+
+	} else if rb.isLeaf() { // splitting leaf at index
+		ltext, rtext := make([]byte, bCur), make([]byte, rb.Len-bCur)
+		copy(ltext, rb.Text[:bCur])
+		copy(rtext, rb.Text[bCur:])
+		return newRopeLeaf(ltext, rb.Cap), newRopeLeaf(rtext, rb.Cap)
 
+	} else if bCur == rb.Weight { // exact
+		return rb.Left, rb.Right
+
+	} else if bCur > rb.Weight { // recurse on the right
+		newRight, right := rb.Right.split(bCur - rb.Weight)
+		return rb.Left.concat(newRight), right
 	}
 	// recurse on the left
-	if rb.isLeaf() { // splitting leaf at index
-		if bCur > 0 {
-			l, err := NewRopebuffer(rb.Text[0:bCur], rb.Cap)
-			if err != nil {
-				return nil, nil, err
-			}
-			r, err := NewRopebuffer(rb.Text[bCur:len(rb.Text)], rb.Cap)
-			if err != nil {
-				return nil, nil, err
+	left, newLeft := rb.Left.split(bCur)
+	return left, newLeft.concat(rb.Right)
+}
+
+// byteOffset return the byte offset for rune offset rCur.
+func (rb *RopeBuffer) byteOffset(rCur int64) int64 {
+	if rb.isLeaf() {
+		return runeOffset(rb.Text, rCur)
+	} else if rCur >= rb.RWeight {
+		return rb.Weight + rb.Right.byteOffset(rCur-rb.RWeight)
+	}
+	return rb.Left.byteOffset(rCur)
+}
+
+// runeOffset return the rune offset for byte offset bCur.
+func (rb *RopeBuffer) runeOffset(bCur int64) int64 {
+	if rb.isLeaf() {
+		return int64(utf8.RuneCount(rb.Text[:bCur]))
+	} else if bCur >= rb.Weight {
+		return rb.RWeight + rb.Right.runeOffset(bCur-rb.Weight)
+	}
+	return rb.Left.runeOffset(bCur)
+}
+
+// nthNewline return the rune offset of the nth newline, n counts
+// from 1.
+func (rb *RopeBuffer) nthNewline(n int64) int64 {
+	if rb.isLeaf() {
+		rCur := int64(0)
+		for _, r := range string(rb.Text) {
+			if r == '\n' {
+				if n--; n == 0 {
+					return rCur
+				}
 			}
-			return l, r, nil
-		}
-		r, err := NewRopebuffer(rb.Text[bCur:len(rb.Text)], rb.Cap)
-		if err != nil {
-			return nil, nil, err
+			rCur++
 		}
-		return nil, r, nil
-	}
-	newLeft, right, err := rb.Left.split(bCur, right)
-	if err != nil {
-		return nil, nil, err
+		panic("impossible situation")
+	} else if n > rb.NWeight {
+		return rb.RWeight + rb.Right.nthNewline(n-rb.NWeight)
 	}
-	rbuf, err := right.Concat(rb.Right)
-	if err != nil {
-		return nil, nil, err
+	return rb.Left.nthNewline(n)
+}
+
+// newlinesBefore return the number of newlines before rune
+// offset rCur.
+func (rb *RopeBuffer) newlinesBefore(rCur int64) int64 {
+	if rb.isLeaf() {
+		bCur := runeOffset(rb.Text, rCur)
+		return int64(bytes.Count(rb.Text[:bCur], newlineBytes))
+	} else if rCur >= rb.RWeight {
+		return rb.NWeight + rb.Right.newlinesBefore(rCur-rb.RWeight)
 	}
-	return newLeft, rbuf.(*RopeBuffer), nil
+	return rb.Left.newlinesBefore(rCur)
 }
 
 func (rb *RopeBuffer) value(bCur int64, n int64, acc []byte) {
@@ -549,78 +659,97 @@ func (rb *RopeBuffer) value(bCur int64, n int64, acc []byte) {
 	}
 }
 
-func (rb *RopeBuffer) runes(
-	bCur int64, rn int64, acc []rune) (int64, int64, error) {
-
+// runes decode the full rope into acc, return the number of
+// runes decoded.
+func (rb *RopeBuffer) runes(acc []rune) int64 {
 	if rb.isLeaf() {
-		count, size, err := bytes2NRunes(rb.Text[bCur:], rn, acc)
-		if err != nil {
-			return 0, 0, err
-		}
-		return count, size, nil
-
-	} else if bCur >= rb.Weight { // recurse to right
-		count, size, err := rb.Right.runes(bCur-rb.Weight, rn, acc[:rn])
-		return count, size, err
-	}
-	// else split the work
-	lcount, lsize, err := rb.Left.runes(bCur, rn, acc)
-	if err != nil {
-		return 0, 0, err
-
-	} else if lcount < rn {
-		if bCur+lsize != rb.Weight {
-			err := fmt.Errorf(
-				"mismatch in boundary %v, but %v", rb.Weight, bCur+lsize)
-			panic(err)
+		count := int64(0)
+		for bs := rb.Text; len(bs) > 0; count++ {
+			r, size := decodeRune(bs)
+			acc[count], bs = r, bs[size:]
 		}
-		rcount, rsize, err := rb.Right.runes(0, rn-lcount, acc[lcount:rn])
-		return lcount + rcount, lsize + rsize, err
+		return count
 	}
-	return lcount, lsize, err
+	count := rb.Left.runes(acc)
+	return count + rb.Right.runes(acc[count:])
 }
 
-func (rb *RopeBuffer) deleteIn(bCur, bn int64) (*RopeBuffer, error) {
+// insertIn rope insrt at bCur, rb is modified in place while nodes
+// and leaf text below it, that are shared with other versions of
+// the rope, are copied on the way down. If the inserted text would
+// combine with the leaf's text into different runes, the leaf is
+// split rather than modified.
+func (rb *RopeBuffer) insertIn(bCur int64, insrt *RopeBuffer) *RopeBuffer {
 	if rb.isLeaf() {
-		rb.Text = ioDelete(rb.Text, bCur, bn)
-		rb.Weight, rb.Len = int64(len(rb.Text)), int64(len(rb.Text))
-		return rb, nil
-	}
+		if insrt.isLeaf() && joinable(rb.Text[:bCur], insrt.Text) &&
+			joinable(insrt.Text, rb.Text[bCur:]) {
 
-	var err error
-	var left, right *RopeBuffer
-
-	if bCur >= rb.Weight { // go right
-		right, err = rb.Right.deleteIn(bCur-rb.Weight, bn)
-		if err != nil {
-			return rb, err
+			text := ioInsert(rb.Text, insrt.Text, bCur)
+			rb.copyrefs(NewRopebuffer(text, rb.Cap))
+			return rb
 		}
-		rb.Right, rb.Len = right, rb.Len-bn
-		return rb, nil
+		left := newRopeLeaf(rb.Text[:bCur], rb.Cap)
+		right := newRopeLeaf(rb.Text[bCur:], rb.Cap)
+		rb.copyrefs(left.join(insrt).join(right))
+		return rb
 
-	} else if bCur+bn < rb.Weight { // delete affects only the left path.
-		left, err = rb.Left.deleteIn(bCur, bn)
-		if err != nil {
-			return rb, err
-		}
-		rb.Left, rb.Len, rb.Weight = left, rb.Len-bn, rb.Weight-bn
-		return rb, nil
+	} else if bCur > rb.Weight {
+		rb.Right = rb.Right.clone().insertIn(bCur-rb.Weight, insrt)
+		rb.updateLevel()
+		return rb
 	}
+	rb.Left = rb.Left.clone().insertIn(bCur, insrt)
+	rb.updateLevel()
+	return rb
+}
 
-	leftbn, rightbn := rb.Weight-bCur, bCur+bn-rb.Weight
-	if rb.Left != nil {
-		if left, err = rb.Left.deleteIn(bCur, leftbn); err != nil {
-			return rb, err
+// deleteIn bn bytes from bCur, refer insertIn.
+func (rb *RopeBuffer) deleteIn(bCur, bn int64) *RopeBuffer {
+	if rb.isLeaf() {
+		if joinable(rb.Text[:bCur], rb.Text[bCur+bn:]) {
+			rb.Text = ioDelete(rb.Text, bCur, bn)
+			rb.updateLeaf()
+			return rb
 		}
+		left := newRopeLeaf(rb.Text[:bCur], rb.Cap)
+		right := newRopeLeaf(rb.Text[bCur+bn:], rb.Cap)
+		rb.copyrefs(NewRopeLevel(left.Len+right.Len, left, right))
+		return rb
+
+	} else if bCur >= rb.Weight { // go right
+		rb.Right = rb.Right.clone().deleteIn(bCur-rb.Weight, bn)
+		rb.updateLevel()
+		return rb
+
+	} else if bCur+bn <= rb.Weight { // delete affects only the left path.
+		rb.Left = rb.Left.clone().deleteIn(bCur, bn)
+		rb.updateLevel()
+		return rb
 	}
-	if rb.Right != nil {
-		if right, err = rb.Right.deleteIn(0, rightbn); err != nil {
-			return rb, err
-		}
+
+	leftbn, rightbn := rb.Weight-bCur, bCur+bn-rb.Weight
+	rb.Left = rb.Left.clone().deleteIn(bCur, leftbn)
+	rb.Right = rb.Right.clone().deleteIn(0, rightbn)
+	rb.updateLevel()
+	return rb
+}
+
+// clone return a copy of the node, sharing its children and text.
+func (rb *RopeBuffer) clone() *RopeBuffer {
+	x := *rb
+	return &x
+}
+
+// own return a copy of the root node, nil if rb is nil. Persistent
+// APIs neither embed their receiver's root nor return a node that
+// is part of another rope, since in-place APIs modify the root.
+// Except for no-op edits and splits at either end, which return
+// the receiver itself.
+func (rb *RopeBuffer) own() *RopeBuffer {
+	if rb == nil {
+		return nil
 	}
-	rb.Len, rb.Weight = rb.Len-bn, rb.Weight-leftbn
-	rb.Left, rb.Right = left, right
-	return rb, nil
+	return rb.clone()
 }
 
 func (rb *RopeBuffer) stats(depth int64, s rbStats) {
@@ -636,199 +765,46 @@ func (rb *RopeBuffer) stats(depth int64, s rbStats) {
 	}
 }
 
-func walkRoutine(
-	rb *RopeBuffer, bCur int64, ch chan []interface{}, killch chan bool) {
-
-	rb.Walk(bCur, func(bCur int64, leaf *RopeBuffer) bool {
-		select {
-		case ch <- []interface{}{bCur, leaf}:
-			return true
-		case <-killch:
-			return false
-		}
-	})
-	close(ch)
-}
-
-func walkBackRoutine(
-	rb *RopeBuffer, bCur int64, ch chan []interface{}, killch chan bool) {
-
-	rb.WalkBack(bCur, func(bCur int64, leaf *RopeBuffer) bool {
-		select {
-		case ch <- []interface{}{bCur, leaf}:
-			return true
-		case <-killch:
-			return false
-		}
-	})
-	close(ch)
-}
-
-func walkLeaf(ch chan []interface{}) func() (int64, *RopeBuffer) {
-	return func() (int64, *RopeBuffer) {
-		if iterVal, ok := <-ch; ok {
-			off, leaf := iterVal[0].(int64), iterVal[1].(*RopeBuffer)
-			return off, leaf
-		}
-		return 0, nil
-	}
-}
-
 func runeEnd(finish bool) (r rune, size int, err error) {
 	return r, size, io.EOF
 }
 
-// iterate on runes in buffer starting from `bCur`.
-func (rb *RopeBuffer) runeIterator(bCur int64) iterator {
-	ch, killch := make(chan []interface{}), make(chan bool)
-	go walkRoutine(rb, bCur, ch, killch)
-	nextLeaf := walkLeaf(ch)
-	off, leaf := nextLeaf()
-	return iterator(func(finish bool) (r rune, size int, err error) {
-		if (leaf == nil) || finish {
-			defer func() { recover() }()
-			close(killch)
-			return r, size, io.EOF
-
-		} else if off < leaf.Len {
-			r, size = utf8.DecodeRune(leaf.Text[off:])
-			off += int64(size)
-			if off == leaf.Len {
-				off, leaf = nextLeaf()
-			} else if off > leaf.Len {
-				panic("impossible situation")
-			}
-			return r, size, nil
-		}
-		panic("impossible situation")
-	})
-}
-
-// iterate on count runes in buffer starting from `bCur`.
-func (rb *RopeBuffer) runeIteratorCount(bCur, count int64) iterator {
-	ch, killch := make(chan []interface{}), make(chan bool)
-	go walkRoutine(rb, bCur, ch, killch)
-	nextLeaf := walkLeaf(ch)
-	off, leaf := nextLeaf()
-	return iterator(func(finish bool) (r rune, size int, err error) {
-		if (leaf == nil) || (count <= 0) || finish {
-			defer func() { recover() }()
-			close(killch)
-			return r, size, io.EOF
-
-		} else if off < leaf.Len {
-			r, size = utf8.DecodeRune(leaf.Text[off:])
-			off += int64(size)
-			if off == leaf.Len {
-				off, leaf = nextLeaf()
-			} else if off > leaf.Len {
-				panic("impossible situation")
-			}
-			count--
-			return r, size, nil
-		}
-		panic("impossible situation")
-	})
+// iterate on runes in buffer starting from `rCur`.
+func (rb *RopeBuffer) runeIterator(rCur int64) iterator {
+	return rb.runeIteratorCount(rCur, math.MaxInt64)
 }
 
-// iterate `till` offset in buffer starting from `bCur`.
-func (rb *RopeBuffer) runeIteratorTill(bCur, till int64) iterator {
-	ch, killch := make(chan []interface{}), make(chan bool)
-	go walkRoutine(rb, bCur, ch, killch)
-	nextLeaf := walkLeaf(ch)
-	off, leaf := nextLeaf()
+// iterate on count runes in buffer starting from `rCur`.
+func (rb *RopeBuffer) runeIteratorCount(rCur, count int64) iterator {
+	cur := rb.Cursor(rCur)
 	return iterator(func(finish bool) (r rune, size int, err error) {
-		if (leaf == nil) || (bCur >= till) || finish {
-			defer func() { recover() }()
-			close(killch)
+		if count <= 0 || finish {
+			count = 0
 			return r, size, io.EOF
-
-		} else if off < leaf.Len {
-			r, size = utf8.DecodeRune(leaf.Text[off:])
-			off += int64(size)
-			till += int64(size)
-			if off == leaf.Len {
-				off, leaf = nextLeaf()
-			} else if off > leaf.Len {
-				panic("impossible situation")
-			}
-			return r, size, nil
 		}
-		panic("impossible situation")
-	})
-}
-
-// iterate on runes in buffer starting from `bCur`,
-// in reverse direction.
-func (rb *RopeBuffer) runeRIterator(bCur int64) iterator {
-	ch, killch := make(chan []interface{}), make(chan bool)
-	go walkBackRoutine(rb, bCur, ch, killch)
-	prevLeaf := walkLeaf(ch)
-	off, leaf := prevLeaf()
-	return iterator(func(finish bool) (r rune, size int, err error) {
-		if leaf == nil || finish {
-			defer func() { recover() }()
-			close(killch)
-			return r, size, io.EOF
-		}
-		r, size = utf8.DecodeRune(leaf.Text[off:])
-		if off <= 0 {
-			off, leaf = prevLeaf()
-			return r, size, nil
-
-		} else if off, err = getRuneStart(leaf.Text[:off], true); err == nil {
-			return r, size, nil
+		if r, size, err = cur.Next(); err == nil {
+			count--
 		}
 		return r, size, err
 	})
 }
 
-// iterate on count runes in buffer starting from `bCur`,
-// in reverse direction.
-func (rb *RopeBuffer) runeRIteratorCount(bCur, count int64) iterator {
-	ch, killch := make(chan []interface{}), make(chan bool)
-	go walkBackRoutine(rb, bCur, ch, killch)
-	prevLeaf := walkLeaf(ch)
-	off, leaf := prevLeaf()
-	return iterator(func(finish bool) (r rune, size int, err error) {
-		if (leaf == nil) || (count == 0) || finish {
-			defer func() { recover() }()
-			close(killch)
-			return r, size, io.EOF
-		}
-		r, size = utf8.DecodeRune(leaf.Text[off:])
-		count--
-		if off <= 0 {
-			off, leaf = prevLeaf()
-			return r, size, nil
-
-		} else if off, err = getRuneStart(leaf.Text[:off], true); err == nil {
-			return r, size, nil
-		}
-		return r, size, err
-	})
+// iterate on runes in buffer before `rCur`, in reverse direction.
+func (rb *RopeBuffer) runeRIterator(rCur int64) iterator {
+	return rb.runeRIteratorCount(rCur, math.MaxInt64)
 }
 
-// iterate `till` offset in buffer starting from `bCur`,
+// iterate on count runes in buffer before `rCur`,
 // in reverse direction.
-func (rb *RopeBuffer) runeRIteratorTill(bCur, till int64) iterator {
-	ch, killch := make(chan []interface{}), make(chan bool)
-	go walkBackRoutine(rb, bCur, ch, killch)
-	prevLeaf := walkLeaf(ch)
-	off, leaf := prevLeaf()
+func (rb *RopeBuffer) runeRIteratorCount(rCur, count int64) iterator {
+	cur := rb.Cursor(rCur)
 	return iterator(func(finish bool) (r rune, size int, err error) {
-		if (leaf == nil) || (bCur == 0) || (bCur < till) || finish {
-			defer func() { recover() }()
-			close(killch)
+		if count <= 0 || finish {
+			count = 0
 			return r, size, io.EOF
 		}
-		r, size = utf8.DecodeRune(leaf.Text[off:])
-		bCur -= int64(size)
-		if off <= 0 {
-			off, leaf = prevLeaf()
-			return r, size, nil
-		} else if off, err = getRuneStart(leaf.Text[:off], true); err == nil {
-			return r, size, nil
+		if r, size, err = cur.Prev(); err == nil {
+			count--
 		}
 		return r, size, err
 	})
@@ -912,16 +888,19 @@ func (s rbStats) incVariance(avg, varc float64, n, an int64) float64 {
 	return math.Sqrt(varcn)
 }
 
+// ioInsert return a new slice with text inserted into dest at
+// bCur, dest is not modified, it may be shared.
 func ioInsert(dest, text []byte, bCur int64) []byte {
-	leftSl := make([]byte, int64(len(dest))-bCur)
-	copy(leftSl, dest[bCur:])
-	dest = append(dest[:bCur], text...)
-	dest = append(dest, leftSl...)
-	return dest
+	out := make([]byte, 0, len(dest)+len(text))
+	out = append(out, dest[:bCur]...)
+	out = append(out, text...)
+	return append(out, dest[bCur:]...)
 }
 
+// ioDelete return a new slice with n bytes removed from dest at
+// bCur, dest is not modified, it may be shared.
 func ioDelete(dest []byte, bCur int64, n int64) []byte {
-	l := int64(len(dest))
-	copy(dest[bCur:], dest[bCur+n:])
-	return dest[:l-n]
+	out := make([]byte, 0, int64(len(dest))-n)
+	out = append(out, dest[:bCur]...)
+	return append(out, dest[bCur+n:]...)
 }
@@ -1,31 +1,16 @@
-// +build ignore
-
 package buffer
 
 import "testing"
 import "reflect"
 import "io"
 import "fmt"
-import "log"
 import "math/rand"
-import "io/ioutil"
+import "unicode/utf8"
 
 var testRopeBufferCapacity = int64(10 * 1024) // 10KB nodes in rope.
-var sampleData []byte                         // contains 2.5MB data.
-
-func init() {
-	var err error
-	sampleData, err = ioutil.ReadFile("../tools/monstrun/sample.txt")
-	if err != nil {
-		log.Fatal(err)
-	}
-}
 
 func TestRopeSample256(t *testing.T) {
-	rb, err := NewRopebuffer(sampleData, 256)
-	if err != nil {
-		t.Fatal(err)
-	}
+	rb := NewRopebuffer(sampleData, 256)
 	stats, err := rb.Stats()
 	if err != nil {
 		t.Fatal(err)
@@ -33,16 +18,13 @@ func TestRopeSample256(t *testing.T) {
 	length := validateRopeBuild(t, stats)
 	if l := int64(len(sampleData)); length != l {
 		t.Fatalf("mismatch in length %v, got %v", length, l)
-	} else if v := string(rb.Value()); string(sampleData) != v {
+	} else if v := string(rb.Bytes()); string(sampleData) != v {
 		t.Fatalf("mismatch expected %v, got %v", len(sampleData), len(v))
 	}
 }
 
 func TestRopeSample1MB(t *testing.T) {
-	rb, err := NewRopebuffer(sampleData, 1024*1024)
-	if err != nil {
-		t.Fatal(err)
-	}
+	rb := NewRopebuffer(sampleData, 1024*1024)
 	stats, err := rb.Stats()
 	if err != nil {
 		t.Fatal(err)
@@ -54,117 +36,358 @@ func TestRopeSample1MB(t *testing.T) {
 }
 
 func TestRopeIndex(t *testing.T) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		t.Fatal(err)
-	}
+	rb := NewRopebuffer([]byte(testChinese), 8)
 	runes := []rune(testChinese)
 	if err := validateRead(rb, runes); err != nil {
 		t.Fatal(err)
 	}
 }
 
-func TestRopeRuneSlice(t *testing.T) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if err := validateRuneSlice(rb); err != nil {
+func TestRopeSlice(t *testing.T) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	if err := validateSlice(rb); err != nil {
 		t.Fatal(err)
+	} else if rb.Slice(-1, 0) != nil {
+		t.Fatalf("expected nil")
+	} else if rb.Slice(52, 0) != nil {
+		t.Fatalf("expected nil")
+	} else if string(rb.Slice(0, 52).Runes()) != testChinese {
+		t.Fatalf("expected full string")
 	}
 }
 
 func TestRopeDicing(t *testing.T) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		t.Fatal(err)
-	}
+	rb := NewRopebuffer([]byte(testChinese), 8)
 	if err := validateDicing(rb); err != nil {
 		t.Fatal(err)
 	}
 }
 
-func TestRopeInsert(t *testing.T) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		t.Fatal(err)
+func TestRopeConcat(t *testing.T) {
+	testcases := [][]string{
+		[]string{testChinese, testChinese},
+		[]string{``, testChinese},
+		[]string{testChinese, ``},
+		[]string{``, ``},
 	}
-	if _, err := rb.Insert(-1, []rune("a")); err != ErrorIndexOutofbound {
-		t.Fatalf("expecting err ErrorIndexOutofbound")
-	} else if _, err = rb.Insert(170, []rune("a")); err != ErrorIndexOutofbound {
-		t.Fatalf("expecting err ErrorIndexOutofbound")
+	for _, testcase := range testcases {
+		s1, s2 := testcase[0], testcase[1]
+		rb1 := NewRopebuffer([]byte(s1), 8)
+		rb2 := NewRopebuffer([]byte(s2), 8)
+		rb := rb1.Concat(rb2)
+		if string(rb.Bytes()) != (s1 + s2) {
+			t.Fatalf("expected concat of s1 and s2")
+		}
 	}
-	offs := runePositions([]byte(testChinese))
-	for _, dot := range offs {
-		rb, err := rb.Insert(dot, []rune("道"))
-		if err != nil {
-			t.Fatal(err)
+}
+
+func TestRopeSplit(t *testing.T) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	testcases := [][]interface{}{
+		[]interface{}{int64(-1), nil, nil},
+		[]interface{}{int64(0), nil, string(rb.Runes())},
+		[]interface{}{int64(10), string(rb.Runes()[:10]), string(rb.Runes()[10:])},
+		[]interface{}{rb.Length(), string(rb.Runes()), nil},
+		[]interface{}{rb.Length() + 1, string(rb.Runes()), nil},
+	}
+	for _, tc := range testcases {
+		rCur, l, r := tc[0].(int64), tc[1], tc[2]
+		lrb, rrb := rb.Split(int64(rCur))
+		t.Logf("rCur: %v\n", rCur)
+		if l == nil && lrb != nil {
+			t.Fatalf("expected %v != %v", l, lrb)
+		} else if l != nil && (l.(string) != string(lrb.Runes())) {
+			t.Fatalf("expected %v != %v", l, lrb)
 		}
-		runes := []rune(string(rb.Value()))
-		if err := validateRead(rb.(*RopeBuffer), runes); err != nil {
+		if r == nil && rrb != nil {
+			t.Fatalf("expected %v != %v", r, rrb)
+		} else if r != nil && (r.(string) != string(rrb.Runes())) {
+			t.Fatalf("expected %v != %v", r, rrb)
+		}
+	}
+}
+
+func TestRopeInsert(t *testing.T) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	for _, rCur := range []int64{-1, 52} {
+		func() {
+			defer func() {
+				if r := recover(); r != ErrorIndexOutofbound {
+					t.Fatalf("expected panic ErrorIndexOutofbound, got %v", r)
+				}
+			}()
+			rb.Insert(rCur, []rune("a"))
+		}()
+	}
+	ref := []rune(testChinese)
+	for rCur := int64(0); rCur <= rb.Length(); rCur++ {
+		newrb := rb.Insert(rCur, []rune("道"))
+		runes := []rune(string(ref[:rCur]) + "道" + string(ref[rCur:]))
+		if err := validateRead(newrb.(*RopeBuffer), runes); err != nil {
 			t.Fatal(err)
 		}
 	}
 }
 
 func TestRopeDelete(t *testing.T) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		t.Fatal(err)
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	lb := NewLinearBuffer([]byte(testChinese))
+	for rb.Length() > 0 {
+		rCur := rand.Int63n(rb.Length())
+		rn := rand.Int63n(rb.Length() - rCur + 1)
+		rb = rb.Delete(rCur, rn).(*RopeBuffer)
+		lb = lb.Delete(rCur, rn)
+		if err := validateRead(rb, lb.Runes()); err != nil {
+			t.Fatal(err)
+		}
 	}
-	var buf Buffer
-	for rb.Len > 0 {
-		offs := runePositions(rb.Value())
-		i := rand.Intn(len(offs))
-		n := rand.Intn(len(offs) - i + 1)
-		dot := offs[i]
-		if buf, err = rb.Delete(dot, int64(n)); err != nil {
+	func() {
+		defer func() {
+			if r := recover(); r != ErrorIndexOutofbound {
+				t.Fatalf("expected panic ErrorIndexOutofbound, got %v", r)
+			}
+		}()
+		rb.Delete(0, 1)
+	}()
+}
+
+func TestRopeInsertIn(t *testing.T) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	lb := NewLinearBuffer([]byte(testChinese))
+	for i := 0; i < 20; i++ {
+		rCur := rand.Int63n(rb.Length() + 1)
+		text := []rune(`中國;pinyin`)[:rand.Intn(9)]
+		if x := rb.InsertIn(rCur, text); x != rb {
+			t.Fatalf("expected same reference")
+		}
+		lb = lb.Insert(rCur, text)
+		if err := validateRead(rb, lb.Runes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRopeDeleteIn(t *testing.T) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	lb := NewLinearBuffer([]byte(testChinese))
+	for rb.Length() > 0 {
+		rCur := rand.Int63n(rb.Length())
+		rn := rand.Int63n(rb.Length() - rCur + 1)
+		if x := rb.DeleteIn(rCur, rn); x != rb {
+			t.Fatalf("expected same reference")
+		}
+		lb = lb.Delete(rCur, rn)
+		if err := validateRead(rb, lb.Runes()); err != nil {
 			t.Fatal(err)
 		}
-		rb = buf.(*RopeBuffer)
 	}
 }
 
 func TestRopePersistence(t *testing.T) {
-	offs := runePositions([]byte(testChinese))
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	lb := NewLinearBuffer(rb.Bytes())
+	history := map[*RopeBuffer]string{rb: string(rb.Bytes())}
+	for rCur := int64(0); rCur <= int64(len([]rune(testChinese))); rCur++ {
+		rb = rb.Insert(rCur, []rune("abc")).(*RopeBuffer)
+		lb = lb.Insert(rCur, []rune("abc"))
+		if _, ok := history[rb]; ok {
+			t.Fatalf("persistence ref failed %v", string(rb.Bytes()))
+		}
+		history[rb] = string(rb.Bytes())
+		if x, y := string(lb.Runes()), string(rb.Runes()); x != y {
+			t.Fatalf("expected %q, got %q", x, y)
+		}
+	}
+	if err := validateRead(rb, lb.Runes()); err != nil {
 		t.Fatal(err)
 	}
-	lb := NewLinearBuffer(rb.Value())
-	history := map[*RopeBuffer]bool{rb: true}
-	for _, off := range offs {
-		buf, err := rb.Insert(off, []rune("abc"))
-		if err != nil {
-			t.Fatal(err)
+	for oldrb, val := range history {
+		if string(oldrb.Bytes()) != val {
+			t.Fatalf("persistence value failed %v", val)
 		}
-		rb = buf.(*RopeBuffer)
-		lb, _ = lb.Insert(off, []rune("abc"))
-		if _, ok := history[rb]; ok {
-			t.Fatal("persistence ref failed %v", rb.Value())
+	}
+}
+
+func TestRopeRebalance(t *testing.T) {
+	// build a left leaning rope, one leaf at a time.
+	var rb *RopeBuffer
+	ref := ""
+	for _, r := range testChinese {
+		leaf := NewRopebuffer([]byte(string(r)), 8)
+		if rb == nil {
+			rb = leaf
+		} else {
+			rb = NewRopeLevel(rb.Len+leaf.Len, rb, leaf)
 		}
-		val := string(rb.Value())
-		for oldrb := range history {
-			if string(oldrb.Value()) == val {
-				t.Fatal("persistence value failed %v", val)
+		ref += string(r)
+	}
+	if rb.IsBalanced() {
+		t.Fatalf("expected unbalanced rope, depth %v", rb.Depth)
+	}
+	newrb := rb.Rebalance()
+	if !newrb.IsBalanced() {
+		t.Fatalf("expected balanced rope, depth %v", newrb.Depth)
+	} else if x := string(newrb.Bytes()); x != ref {
+		t.Fatalf("expected %q, got %q", ref, x)
+	} else if x := string(rb.Bytes()); x != ref {
+		t.Fatalf("expected %q, got %q", ref, x)
+	}
+	// adjacent leaves are merged upto capacity.
+	stats, _ := newrb.Stats()
+	if x, y := stats["leafs"].(int64), newrb.Len/8; x < y || x > 2*y+1 {
+		t.Fatalf("unexpected %v leafs for %v bytes", x, newrb.Len)
+	}
+	// leaf and empty ropes are returned as is.
+	leaf := NewRopebuffer([]byte("hello"), 8)
+	if x := leaf.Rebalance(); x != leaf {
+		t.Fatalf("expected same reference")
+	}
+}
+
+func TestRopeBalancedInsert(t *testing.T) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	lb := NewLinearBuffer([]byte(testChinese))
+	itext := []rune(`中國;pinyin`)
+	for i := 0; i < 1000; i++ {
+		rb = rb.Insert(10, itext).(*RopeBuffer)
+		lb = lb.Insert(10, itext)
+		if !rb.IsBalanced() {
+			t.Fatalf("unbalanced rope at %v, depth %v", i, rb.Depth)
+		}
+	}
+	if x, y := string(lb.Runes()), string(rb.Runes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	}
+	for i := 0; i < 1000; i++ {
+		rb.InsertIn(10, itext)
+		lb = lb.Insert(10, itext)
+		if !rb.IsBalanced() {
+			t.Fatalf("unbalanced rope at %v, depth %v", i, rb.Depth)
+		}
+	}
+	if x, y := string(lb.Runes()), string(rb.Runes()); x != y {
+		t.Fatalf("expected %q, got %q", x, y)
+	}
+}
+
+func TestRopeLines(t *testing.T) {
+	text := "中國\npinyin\n\nhello 世界\n"
+	for i := 0; i < 4; i++ {
+		text += text
+	}
+	rb := NewRopebuffer([]byte(text), 8)
+	validateLines(t, rb)
+	// persistent and in-place changes keep the counts.
+	rb = rb.Insert(7, []rune("a\nb\n")).(*RopeBuffer)
+	validateLines(t, rb)
+	rb = rb.Delete(3, 20).(*RopeBuffer)
+	validateLines(t, rb)
+	rb.InsertIn(11, []rune("\n中\n國\n"))
+	validateLines(t, rb)
+	rb.DeleteIn(0, 17)
+	validateLines(t, rb)
+
+	if x := rb.LineStart(-1); x != -1 {
+		t.Fatalf("expected %v, got %v", -1, x)
+	} else if x := rb.LineStart(rb.Lines()); x != -1 {
+		t.Fatalf("expected %v, got %v", -1, x)
+	} else if x := rb.LineOf(rb.Length() + 1); x != -1 {
+		t.Fatalf("expected %v, got %v", -1, x)
+	} else if x := rb.RuneToByte(-1); x != -1 {
+		t.Fatalf("expected %v, got %v", -1, x)
+	} else if x := rb.ByteToRune(rb.Size() + 1); x != -1 {
+		t.Fatalf("expected %v, got %v", -1, x)
+	}
+	empty := NewRopebuffer([]byte{}, 8)
+	if x := empty.Lines(); x != 1 {
+		t.Fatalf("expected %v, got %v", 1, x)
+	} else if x := empty.LineStart(0); x != 0 {
+		t.Fatalf("expected %v, got %v", 0, x)
+	} else if x := empty.LineOf(0); x != 0 {
+		t.Fatalf("expected %v, got %v", 0, x)
+	}
+}
+
+func TestRopeBinary(t *testing.T) {
+	bs := make([]byte, 64*1024)
+	rand.Read(bs)
+	rb := NewRopebuffer(bs, 8)
+	if x := rb.Bytes(); string(x) != string(bs) {
+		t.Fatalf("mismatch in content")
+	} else if x, y := int64(utf8.RuneCount(bs)), rb.Length(); x != y {
+		t.Fatalf("expected %v, got %v", x, y)
+	}
+	runes, _ := readAllRunes(rb.StreamFrom(0))
+	if x, y := string(bytes2Runes(bs)), string(runes); x != y {
+		t.Fatalf("mismatch in runes")
+	}
+}
+
+func TestRopeCursor(t *testing.T) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	shared := rb.Concat(rb).(*RopeBuffer) // same node on both sides.
+	holes := NewRopebuffer([]byte(testChinese), 8)
+	holes.DeleteIn(10, 20) // may leave empty leaves.
+	for _, rb := range []*RopeBuffer{rb, shared, holes} {
+		ref := rb.Runes()
+		for _, rCur := range []int64{0, 1, 7, rb.Length() / 2, rb.Length()} {
+			cur := rb.Cursor(rCur)
+			for i := rCur; i < int64(len(ref)); i++ {
+				if r, _, err := cur.Next(); err != nil {
+					t.Fatal(err)
+				} else if r != ref[i] {
+					t.Fatalf("at %v expected %q, got %q", i, ref[i], r)
+				}
+			}
+			if _, _, err := cur.Next(); err != io.EOF {
+				t.Fatalf("expected io.EOF, got %v", err)
+			}
+			// and all the way back.
+			for i := int64(len(ref)) - 1; i >= 0; i-- {
+				if r, _, err := cur.Prev(); err != nil {
+					t.Fatal(err)
+				} else if r != ref[i] {
+					t.Fatalf("at %v expected %q, got %q", i, ref[i], r)
+				} else if x := cur.Offset(); x != i {
+					t.Fatalf("expected %v, got %v", i, x)
+				}
+			}
+			if _, _, err := cur.Prev(); err != io.EOF {
+				t.Fatalf("expected io.EOF, got %v", err)
 			}
 		}
-		history[rb] = true
-		ref, err := lb.Runes()
-		if err != nil {
-			t.Fatal(err)
+	}
+	// zig-zag and seek.
+	cur, ref := rb.Cursor(0), rb.Runes()
+	for i := 0; i < 100; i++ {
+		rCur := rand.Int63n(rb.Length() + 1)
+		if !cur.SeekTo(rCur) {
+			t.Fatalf("unexpected failure seeking to %v", rCur)
 		}
-		if err := validateRead(rb, ref); err != nil {
-			t.Fatal(err)
+		if r, _, err := cur.Next(); err == nil && r != ref[rCur] {
+			t.Fatalf("at %v expected %q, got %q", rCur, ref[rCur], r)
+		} else if err == nil {
+			cur.Prev()
+		}
+		if r, _, err := cur.Prev(); err == nil && r != ref[rCur-1] {
+			t.Fatalf("at %v expected %q, got %q", rCur, ref[rCur-1], r)
 		}
 	}
+	if cur.SeekTo(-1) || cur.SeekTo(rb.Length()+1) {
+		t.Fatalf("expected seek to fail")
+	} else if rb.Cursor(rb.Length()+1) != nil {
+		t.Fatalf("expected nil")
+	}
+	empty := NewRopebuffer([]byte{}, 8).Cursor(0)
+	if _, _, err := empty.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	} else if _, _, err := empty.Prev(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
 }
 
 func TestJohnnie(t *testing.T) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		t.Fatal(err)
-	}
+	rb := NewRopebuffer([]byte(testChinese), 8)
 	rbs1 := make(map[*RopeBuffer]bool)
 	rb.Walk(0, func(bCur int64, rb *RopeBuffer) bool {
 		rbs1[rb] = true
@@ -172,11 +395,7 @@ func TestJohnnie(t *testing.T) {
 	})
 
 	rbs2 := make(map[*RopeBuffer]bool)
-	l, err := rb.Length()
-	if err != nil {
-		t.Fatal(err)
-	}
-	rb.WalkBack(l, func(bCur int64, rb *RopeBuffer) bool {
+	rb.WalkBack(rb.Size(), func(bCur int64, rb *RopeBuffer) bool {
 		rbs2[rb] = true
 		return true
 	})
@@ -184,22 +403,8 @@ func TestJohnnie(t *testing.T) {
 		t.Fatalf("mismatch in leaf nodes")
 	}
 
-	runes1 := make([]rune, 0)
-	for reader := rb.StreamFrom(0); true; {
-		r, _, err := reader.ReadRune()
-		if err == io.EOF {
-			break
-		}
-		runes1 = append(runes1, r)
-	}
-	runes2 := make([]rune, 0)
-	for reader := rb.BackStreamFrom(l); true; {
-		r, _, err := reader.ReadRune()
-		if err == io.EOF {
-			break
-		}
-		runes2 = append(runes2, r)
-	}
+	runes1, _ := readAllRunes(rb.StreamFrom(0))
+	runes2, _ := readAllRunes(rb.BackStreamFrom(rb.Length()))
 	runes2 = reverseRunes(runes2)
 	if x, y := string(runes1), string(runes2); x != y {
 		t.Fatalf("expected %s, got %s\n", x, y)
@@ -207,119 +412,54 @@ func TestJohnnie(t *testing.T) {
 }
 
 func TestRopeStreamFrom(t *testing.T) {
-	bytes := []byte(testChinese)
-	rb, err := NewRopebuffer(bytes, 8)
-	if err != nil {
-		t.Fatal(err)
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	ref := rb.Runes()
+	if rb.StreamFrom(-1) != nil {
+		t.Fatalf("expected nil")
 	}
-	offs := runePositions(bytes)
-	offs = append(append([]int64{}, -1), offs...)
-	for _, off := range offs {
-		runes := make([]rune, 0)
-		for reader := rb.StreamFrom(off); true; {
-			r, _, err := reader.ReadRune()
-			if err == io.EOF {
-				break
-			}
-			runes = append(runes, r)
-		}
-		if off == -1 || off == rb.Len {
-			if len(runes) != 0 {
-				t.Fatalf("mismatch for %d %q", off, string(runes))
-			}
-		} else if x, y := string(runes), string(rb.Value()[off:]); x != y {
-			t.Fatalf("mismatch for %d %q, got %q", off, y, x)
+	for rCur := int64(0); rCur <= rb.Length(); rCur++ {
+		runes, size := readAllRunes(rb.StreamFrom(rCur))
+		if x, y := string(ref[rCur:]), string(runes); x != y {
+			t.Fatalf("mismatch for %d %q, got %q", rCur, x, y)
+		} else if size != len(x) {
+			t.Fatalf("expected %v, got %v", len(x), size)
 		}
 	}
 }
 
 func TestRopeStreamCount(t *testing.T) {
-	bytes := []byte(testChinese)
-	rb, err := NewRopebuffer(bytes, 8)
-	if err != nil {
-		t.Fatal(err)
-	}
-	ref, err := rb.Runes()
-	if err != nil {
-		t.Fatal(err)
-	}
-	offs := runePositions(bytes)
-	for i, off := range offs {
-		runes := make([]rune, 0)
-		n := rand.Intn(len(offs) - i)
-		for reader := rb.StreamCount(off, int64(n)); true; {
-			r, _, err := reader.ReadRune()
-			if err == io.EOF {
-				break
-			}
-			runes = append(runes, r)
-		}
-		x, y := string(runes), string(ref[i:i+n])
-		if n == i {
-			if len(runes) != 0 {
-				t.Fatalf("mismatch for %d %q", off, string(runes))
-			}
-		} else if x != y {
-			t.Fatalf("mismatch for %d %q, got %q", off, y, x)
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	ref := rb.Runes()
+	for rCur := int64(0); rCur <= rb.Length(); rCur++ {
+		n := rand.Int63n(rb.Length() - rCur + 1)
+		runes, _ := readAllRunes(rb.StreamCount(rCur, n))
+		if x, y := string(ref[rCur:rCur+n]), string(runes); x != y {
+			t.Fatalf("mismatch for {%d,%d} %q, got %q", rCur, n, x, y)
 		}
 	}
 }
 
 func TestRopeBackStreamFrom(t *testing.T) {
-	bytes := []byte(testChinese)
-	rb, err := NewRopebuffer(bytes, 8)
-	if err != nil {
-		t.Fatal(err)
-	}
-	ref, err := rb.Runes()
-	if err != nil {
-		t.Fatal(err)
-	}
-	offs := runePositions(bytes)
-	for i, off := range offs {
-		runes := make([]rune, 0)
-		for reader := rb.BackStreamFrom(off); true; {
-			r, _, err := reader.ReadRune()
-			if err == io.EOF {
-				break
-			}
-			runes = append(runes, r)
-		}
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	ref := rb.Runes()
+	for rCur := int64(0); rCur <= rb.Length(); rCur++ {
+		runes, _ := readAllRunes(rb.BackStreamFrom(rCur))
 		runes = reverseRunes(runes)
-		if off == rb.Len {
-			if len(runes) != 0 {
-				t.Fatalf("mismatch for %d %q", off, string(runes))
-			}
-		} else if x, y := string(runes), string(ref[0:i+1]); x != y {
-			t.Fatalf("mismatch for {%d,%d} %q, got %q", off, i, y, x)
+		if x, y := string(ref[:rCur]), string(runes); x != y {
+			t.Fatalf("mismatch for %d %q, got %q", rCur, x, y)
 		}
 	}
 }
 
 func TestRopeBackStreamCount(t *testing.T) {
-	bytes := []byte(testChinese)
-	rb, err := NewRopebuffer(bytes, 8)
-	if err != nil {
-		t.Fatal(err)
-	}
-	ref, err := rb.Runes()
-	if err != nil {
-		t.Fatal(err)
-	}
-	offs := runePositions(bytes)
-	for i, off := range offs {
-		runes := make([]rune, 0)
-		n := rand.Intn(i + 1)
-		for reader := rb.BackStreamCount(off, int64(n)); true; {
-			r, _, err := reader.ReadRune()
-			if err == io.EOF {
-				break
-			}
-			runes = append(runes, r)
-		}
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	ref := rb.Runes()
+	for rCur := int64(0); rCur <= rb.Length(); rCur++ {
+		n := rand.Int63n(rCur + 1)
+		runes, _ := readAllRunes(rb.BackStreamCount(rCur, n))
 		runes = reverseRunes(runes)
-		if x, y := string(runes), string(ref[i-n+1:i+1]); x != y {
-			t.Fatalf("mismatch for {%d,%d,%d} %q, got %q", off, n, i, y, x)
+		if x, y := string(ref[rCur-n:rCur]), string(runes); x != y {
+			t.Fatalf("mismatch for {%d,%d} %q, got %q", rCur, n, x, y)
 		}
 	}
 }
@@ -334,181 +474,172 @@ func BenchmarkRopeSample8(b *testing.B) {
 }
 
 func BenchmarkRopeLength(b *testing.B) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		b.Fatal(err)
-	}
+	rb := NewRopebuffer([]byte(testChinese), 8)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if _, err := rb.Length(); err != nil {
-			b.Fatal(err)
-		}
+		rb.Length()
 	}
 }
 
-func BenchmarkRopeValue(b *testing.B) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		b.Fatal(err)
-	}
+func BenchmarkRopeBytes(b *testing.B) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		rb.Value()
+		rb.Bytes()
 	}
 }
 
 func BenchmarkRopeSlice(b *testing.B) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		b.Fatal(err)
-	}
-	l, err := rb.Length()
-	if err != nil {
-		b.Fatal(err)
-	}
-	size := 0
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	l := rb.Length()
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		bs, err := rb.Slice(0, int64(i)%l)
-		if err != nil {
-			b.Fatal(err)
-		}
-		size += len(bs)
+		rb.Slice(0, int64(i)%l)
 	}
-	b.SetBytes(int64(size / b.N))
 }
 
-func BenchmarkRopeRuneAt(b *testing.B) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		b.Fatal(err)
-	}
-	offs := runePositions([]byte(testChinese))
-	b.ResetTimer()
+func BenchmarkRopeRunes(b *testing.B) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
 	for i := 0; i < b.N; i++ {
-		if _, _, err := rb.RuneAt(offs[i%len(offs)]); err != nil {
-			b.Fatal(err)
-		}
+		rb.Runes()
 	}
+	b.SetBytes(int64(len(testChinese)))
 }
 
-func BenchmarkRopeRunes(b *testing.B) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		b.Fatal(err)
-	}
+func BenchmarkRopeRuneAt(b *testing.B) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	l := rb.Length()
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if _, err := rb.Runes(); err != nil {
+		reader := rb.StreamCount(int64(i)%l, 1)
+		if _, _, err := reader.ReadRune(); err != nil {
 			b.Fatal(err)
 		}
+		reader.Close()
 	}
-	b.SetBytes(int64(len(testChinese)))
 }
 
 func BenchmarkRopeRuneSlice(b *testing.B) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		b.Fatal(err)
-	}
-	offs := runePositions([]byte(testChinese))
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	l := rb.Length()
 	b.ResetTimer()
 	size := int64(0)
 	for i := 0; i < b.N; i++ {
-		_, sz, err := rb.RuneSlice(0, offs[i%len(offs)])
-		if err != nil {
-			b.Fatal(err)
-		}
-		size += sz
+		size += int64(len(rb.Slice(0, int64(i)%l).Runes()))
 	}
-	b.SetBytes(size / int64(b.N))
+	b.SetBytes(size * 4 / int64(b.N))
 }
 
 func BenchmarkRopeConcat(b *testing.B) {
-	rb1, _ := NewRopebuffer([]byte(testChinese), 8)
-	rb2, _ := NewRopebuffer([]byte(testChinese), 8)
+	rb1 := NewRopebuffer([]byte(testChinese), 8)
+	rb2 := NewRopebuffer([]byte(testChinese), 8)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if _, err := rb1.Concat(rb2); err != nil {
-			b.Fatal(err)
-		}
+		rb1.Concat(rb2)
 	}
 	b.SetBytes(int64(len(testChinese) * 2))
 }
 
 func BenchmarkRopeSplit(b *testing.B) {
-	rb, _ := NewRopebuffer([]byte(testChinese), 8)
-	l, _ := rb.Length()
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	l := rb.Length()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if _, _, err := rb.Split(int64(i) % l); err != nil {
-			b.Fatal(err)
-		}
+		rb.Split(int64(i) % l)
 	}
 	b.SetBytes(int64(len(testChinese)))
 }
 
 func BenchmarkRopeInsert(b *testing.B) {
 	// insert small text into a small buffer.
-	rb, _ := NewRopebuffer([]byte(testChinese), 8)
+	rb := NewRopebuffer([]byte(testChinese), 8)
 	itext := []rune(`中國;pinyin`)
-	offs := runePositions([]byte(testChinese))
+	l := rb.Length()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		bCur := offs[i%len(offs)]
-		if _, err := rb.Insert(bCur, itext); err != nil {
-			b.Fatal(err)
-		}
+		rb.Insert(int64(i)%l, itext)
 	}
 	b.SetBytes(int64(len(string(itext))))
 }
 
 func BenchmarkRopeInsert2M(b *testing.B) {
 	// insert small text into a large buffer.
-	rb, _ := NewRopebuffer([]byte(sampleData), 256)
+	rb := NewRopebuffer(sampleData, 256)
 	itext := []rune(`中國;pinyin`)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		bCur := i % len(sampleData)
-		if _, err := rb.Insert(int64(bCur), itext); err != nil {
-			b.Fatal(err)
-		}
+		rb.Insert(int64(i)%1000, itext)
 	}
 	b.SetBytes(int64(len(string(itext))))
 }
 
-func BenchmarkRopeDelete(b *testing.B) {
-	rb, err := NewRopebuffer([]byte(testChinese), 8)
-	if err != nil {
-		b.Fatal(err)
+func BenchmarkRopeInsertAt2M(b *testing.B) {
+	// insert small text at the same spot, in a large buffer, tree
+	// depth is expected to stay bounded.
+	rb := NewRopebuffer(sampleData, 256)
+	itext := []rune(`中國;pinyin`)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rb = rb.Insert(1000, itext).(*RopeBuffer)
 	}
+	b.SetBytes(int64(len(string(itext))))
+	b.ReportMetric(float64(rb.Depth), "depth")
+}
+
+func BenchmarkRopeRebalance(b *testing.B) {
+	rb := NewRopebuffer(sampleData, 256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rb.Rebalance()
+	}
+	b.SetBytes(rb.Len)
+}
+
+func BenchmarkRopeLineStart2M(b *testing.B) {
+	rb := NewRopebuffer(sampleData, 256)
+	lines := rb.Lines()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rb.LineStart(int64(i) % lines)
+	}
+}
+
+func BenchmarkRopeLineOf2M(b *testing.B) {
+	rb := NewRopebuffer(sampleData, 256)
+	l := rb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rb.LineOf(int64(i*1009) % l)
+	}
+}
+
+func BenchmarkRopeRune2Byte2M(b *testing.B) {
+	rb := NewRopebuffer(sampleData, 256)
+	l := rb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rb.RuneToByte(int64(i*1009) % l)
+	}
+}
+
+func BenchmarkRopeDelete(b *testing.B) {
+	rb := NewRopebuffer([]byte(testChinese), 8)
 	itext := []rune(`中國;pinyin`)
-	offs := runePositions([]byte(testChinese))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		bCur := offs[:10][int64(i)%10]
-		if _, err := rb.Delete(bCur, 10); err != nil {
-			b.Fatal(err)
-		}
-		if _, err := rb.Insert(bCur, itext); err != nil {
-			b.Fatal(err)
-		}
+		rCur := int64(i) % 10
+		rb.Delete(rCur, 10).Insert(rCur, itext)
 	}
 	b.SetBytes(int64(len(string(itext))))
 }
 
 func BenchmarkRopeDelete2M(b *testing.B) {
-	// insert small text from a small buffer.
-	rb, _ := NewRopebuffer([]byte(sampleData), 256)
+	rb := NewRopebuffer(sampleData, 256)
 	itext := []rune(`中國;pinyin`)
-	offs := runePositions([]byte(sampleData))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		bCur := offs[:1000][int64(i)%1000]
-		if _, err := rb.Delete(bCur, 10); err != nil {
-			b.Fatal(err)
-		}
-		if _, err := rb.Insert(bCur, itext); err != nil {
-			b.Fatal(err)
-		}
+		rCur := int64(i) % 1000
+		rb.Delete(rCur, 10).Insert(rCur, itext)
 	}
 	b.SetBytes(int64(len(string(itext))))
 }
@@ -516,72 +647,51 @@ func BenchmarkRopeDelete2M(b *testing.B) {
 func BenchmarkRopeInsIn(b *testing.B) {
 	// insert small text into a small buffer.
 	itext := []rune(`中國;pinyin`)
-	offs := runePositions([]byte(testChinese))
-	bCur := offs[10]
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		rb, _ := NewRopebuffer([]byte(testChinese), 8)
-		if _, err := rb.InsertIn(bCur, itext); err != nil {
-			b.Fatal(err)
-		}
+		rb := NewRopebuffer([]byte(testChinese), 8)
+		rb.InsertIn(10, itext)
 	}
 	b.SetBytes(int64(len(string(itext))))
 }
 
 func BenchmarkRopeInsIn2M(b *testing.B) {
 	// insert small text into a large buffer.
+	rb := NewRopebuffer(sampleData, 256)
 	itext := []rune(`中國;pinyin`)
-	offs := runePositions([]byte(sampleData))
-	bCur := offs[1000]
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		rb, _ := NewRopebuffer([]byte(sampleData), 256)
-		if _, err := rb.InsertIn(bCur, itext); err != nil {
-			b.Fatal(err)
-		}
+		rb.InsertIn(1000, itext)
+		rb.DeleteIn(1000, int64(len(itext)))
 	}
 	b.SetBytes(int64(len(string(itext))))
 }
 
 func BenchmarkRopeDelIn(b *testing.B) {
-	// insert small text from a small buffer.
-	rb, _ := NewRopebuffer([]byte(testChinese), 8)
+	rb := NewRopebuffer([]byte(testChinese), 8)
 	itext := []rune(`中國;pinyin`)
-	offs := runePositions([]byte(testChinese))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		bCur := offs[:10][int64(i)%10]
-		if _, err := rb.DeleteIn(bCur, 10); err != nil {
-			b.Fatal(err)
-		}
-		if _, err := rb.InsertIn(bCur, itext); err != nil {
-			b.Fatal(err)
-		}
+		rCur := int64(i) % 10
+		rb.DeleteIn(rCur, int64(len(itext)))
+		rb.InsertIn(rCur, itext)
 	}
 	b.SetBytes(int64(len(string(itext))))
 }
 
 func BenchmarkRopeDelIn2M(b *testing.B) {
-	// insert small text from a small buffer.
-	rb, _ := NewRopebuffer([]byte(sampleData), 256)
+	rb := NewRopebuffer(sampleData, 256)
 	itext := []rune(`中國;pinyin`)
-	offs := runePositions([]byte(sampleData))
-	bCur := offs[1000]
-	rb.InsertIn(bCur, itext)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if _, err := rb.DeleteIn(bCur, 10); err != nil {
-			b.Fatal(err)
-		}
-		if _, err := rb.InsertIn(bCur, itext); err != nil {
-			b.Fatal(err)
-		}
+		rb.DeleteIn(1000, int64(len(itext)))
+		rb.InsertIn(1000, itext)
 	}
 	b.SetBytes(int64(len(string(itext))))
 }
 
 func BenchmarkRopeStrmFrm(b *testing.B) {
-	rb, _ := NewRopebuffer([]byte(testChinese), 8)
+	rb := NewRopebuffer([]byte(testChinese), 8)
 	for i := 0; i < b.N; i++ {
 		var err error
 		reader := rb.StreamFrom(0)
@@ -593,11 +703,12 @@ func BenchmarkRopeStrmFrm(b *testing.B) {
 }
 
 func BenchmarkRopeStrmCnt(b *testing.B) {
-	rb, _ := NewRopebuffer([]byte(testChinese), 8)
-	offs := runePositions([]byte(testChinese))
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	l := rb.Length()
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		var err error
-		reader := rb.StreamCount(0, int64(len(offs)))
+		reader := rb.StreamCount(0, l)
 		for err != io.EOF {
 			_, _, err = reader.ReadRune()
 		}
@@ -606,12 +717,12 @@ func BenchmarkRopeStrmCnt(b *testing.B) {
 }
 
 func BenchmarkRopeBStrmFrm(b *testing.B) {
-	rb, _ := NewRopebuffer([]byte(testChinese), 8)
-	offs := runePositions([]byte(testChinese))
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	l := rb.Length()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		var err error
-		reader := rb.BackStreamFrom(offs[len(offs)-1])
+		reader := rb.BackStreamFrom(l)
 		for err != io.EOF {
 			_, _, err = reader.ReadRune()
 		}
@@ -620,11 +731,12 @@ func BenchmarkRopeBStrmFrm(b *testing.B) {
 }
 
 func BenchmarkRopeBStrmCnt(b *testing.B) {
-	rb, _ := NewRopebuffer([]byte(testChinese), 8)
-	offs := runePositions([]byte(testChinese))
+	rb := NewRopebuffer([]byte(testChinese), 8)
+	l := rb.Length()
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		var err error
-		reader := rb.BackStreamCount(offs[len(offs)-1], int64(len(offs)))
+		reader := rb.BackStreamCount(l, l)
 		for err != io.EOF {
 			_, _, err = reader.ReadRune()
 		}
@@ -632,6 +744,52 @@ func BenchmarkRopeBStrmCnt(b *testing.B) {
 	b.SetBytes(int64(len(testChinese)))
 }
 
+func BenchmarkRopeStrmFrm2M(b *testing.B) {
+	rb := NewRopebuffer(sampleData, 256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		reader := rb.StreamFrom(0)
+		for err != io.EOF {
+			_, _, err = reader.ReadRune()
+		}
+	}
+	b.SetBytes(int64(len(sampleData)))
+}
+
+func BenchmarkRopeBStrmFrm2M(b *testing.B) {
+	rb := NewRopebuffer(sampleData, 256)
+	l := rb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		reader := rb.BackStreamFrom(l)
+		for err != io.EOF {
+			_, _, err = reader.ReadRune()
+		}
+	}
+	b.SetBytes(int64(len(sampleData)))
+}
+
+func BenchmarkRopeStrmOpen(b *testing.B) {
+	// open a stream, read a rune, and drop it.
+	rb := NewRopebuffer(sampleData, 256)
+	l := rb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rb.StreamFrom(int64(i*1009) % l).ReadRune()
+	}
+}
+
+func BenchmarkRopeCursorSeek(b *testing.B) {
+	rb := NewRopebuffer(sampleData, 256)
+	cur, l := rb.Cursor(0), rb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cur.SeekTo(int64(i*1009) % l)
+	}
+}
+
 func BenchmarkBytes2Str(b *testing.B) {
 	var text string
 	b.ResetTimer()
@@ -693,137 +851,95 @@ func validateRopeBuild(t *testing.T, stats map[string]interface{}) int64 {
 	return length
 }
 
-func validateRead(rb *RopeBuffer, ref []rune) error {
-	offs := runePositions(rb.Value())
-	if rb == nil {
-		return fmt.Errorf("rope-buffer cannot be nil")
-	} else if len(ref) != len(offs) {
-		return fmt.Errorf("expected %v runes, got %v", len(ref), len(offs))
-	} else if ref != nil {
-		// verify length
-		if runes, err := rb.Runes(); err != nil {
-			return err
-		} else if x, y := len(ref), len(runes); x != y {
-			return fmt.Errorf("expecting length %d, got %d", x, y)
-		}
-		// verify value
-		if x, y := string(rb.Value()), string(ref); x != y {
-			return fmt.Errorf("expecting value %q, got %q", x, y)
-		}
-		// verify index
-		for i := range offs {
-			off := offs[i]
-			if y, size, err := rb.RuneAt(int64(off)); err != nil {
-				return err
-			} else if size == 0 {
-				return fmt.Errorf("expecting rune at %d for %q", off, string(ref))
-			} else if x := ref[i]; x != y {
-				return fmt.Errorf("expecting %v, got %v at %d", x, y, off)
-			}
+// validateLines compare the line and offset APIs against a
+// linear scan of the rope.
+func validateLines(t *testing.T, rb *RopeBuffer) {
+	runes, lineStarts := rb.Runes(), []int64{0}
+	line, bCur := int64(0), int64(0)
+	for rCur, r := range runes {
+		if x := rb.LineOf(int64(rCur)); x != line {
+			t.Fatalf("at %v expected line %v, got %v", rCur, line, x)
+		} else if x := rb.RuneToByte(int64(rCur)); x != bCur {
+			t.Fatalf("at %v expected byte %v, got %v", rCur, bCur, x)
+		} else if x := rb.ByteToRune(bCur); x != int64(rCur) {
+			t.Fatalf("at %v expected rune %v, got %v", bCur, rCur, x)
 		}
-		// out of bound index
-		if _, _, err := rb.RuneAt(-1); err != ErrorIndexOutofbound {
-			return fmt.Errorf("expecting ErrorIndexOutofbound at -1")
-		}
-		// out of bound index
-		_, _, err := rb.RuneAt(rb.Len)
-		if err != ErrorIndexOutofbound {
-			return fmt.Errorf("expecting ErrorIndexOutofbound at %d", rb.Len)
-		}
-		// verify substr
-		for i, dot := range offs {
-			for n := 0; n < len(offs)-i; n++ {
-				runes, _, err := rb.RuneSlice(int64(dot), int64(n))
-				if err != nil {
-					return err
-				}
-				if string(runes) != string(ref[i:i+n]) {
-					msg := "expecting %q, got %q"
-					return fmt.Errorf(msg, string(runes), string(ref[dot:n]))
-				}
-			}
+		bCur += int64(utf8.RuneLen(r))
+		if r == '\n' {
+			line++
+			lineStarts = append(lineStarts, int64(rCur+1))
 		}
 	}
-
-	lb := NewLinearBuffer(rb.Value())
-	ref, err := lb.Runes()
-	if err != nil {
-		return err
+	if x := rb.LineOf(rb.Length()); x != line {
+		t.Fatalf("at end expected line %v, got %v", line, x)
+	} else if x := rb.RuneToByte(rb.Length()); x != rb.Size() {
+		t.Fatalf("at end expected %v, got %v", rb.Size(), x)
+	} else if x := rb.Lines(); x != int64(len(lineStarts)) {
+		t.Fatalf("expected %v lines, got %v", len(lineStarts), x)
 	}
-	rbRunes, err := rb.Runes()
-	if err != nil {
-		return err
+	for line, rCur := range lineStarts {
+		if x := rb.LineStart(int64(line)); x != rCur {
+			t.Fatalf("line %v expected %v, got %v", line, rCur, x)
+		}
 	}
+}
 
-	if x, y := string(rbRunes), string(ref); x != y {
-		return fmt.Errorf("mismatch in value %q, got %q", x, y)
+func validateRead(rb *RopeBuffer, ref []rune) error {
+	if rb == nil {
+		return fmt.Errorf("rope-buffer cannot be nil")
 	}
-
-	length, err := rb.Length()
-	if err != nil {
-		return err
+	// verify length
+	if x, y := int64(len(ref)), rb.Length(); x != y {
+		return fmt.Errorf("expecting length %d, got %d", x, y)
+	} else if x, y := int64(len(string(ref))), rb.Size(); x != y {
+		return fmt.Errorf("expecting size %d, got %d", x, y)
 	}
-	if length == 0 {
-		if _, size, err := rb.RuneAt(0); err == nil || size > 0 {
-			return fmt.Errorf("expecting error")
-		}
-		if _, size, err := rb.RuneAt(1); err == nil || size > 0 {
-			return fmt.Errorf("expecting error")
-		}
-	} else {
-		if _, size, err := rb.RuneAt(length); err == nil || size > 0 {
-			return fmt.Errorf("expecting error for %v", length)
+	// verify value
+	if x, y := string(ref), string(rb.Bytes()); x != y {
+		return fmt.Errorf("expecting value %q, got %q", x, y)
+	} else if y := string(rb.Runes()); x != y {
+		return fmt.Errorf("expecting runes %q, got %q", x, y)
+	}
+	// verify index
+	for i := range ref {
+		runes, size := readAllRunes(rb.StreamCount(int64(i), 1))
+		if size == 0 {
+			return fmt.Errorf("expecting rune at %d for %q", i, string(ref))
+		} else if x, y := ref[i], runes[0]; x != y {
+			return fmt.Errorf("expecting %v, got %v at %d", x, y, i)
 		}
 	}
-
-	return validateRuneSlice(rb)
+	// out of bound index
+	if rb.StreamCount(-1, 1) != nil {
+		return fmt.Errorf("expecting nil reader at -1")
+	} else if rb.StreamCount(rb.Length()+1, 1) != nil {
+		return fmt.Errorf("expecting nil reader at %d", rb.Length()+1)
+	}
+	return validateSlice(rb)
 }
 
 func validateDicing(rbRef *RopeBuffer) error {
-	x := rbRef.Value()
+	x := rbRef.Runes()
 	lenx := int64(len(x))
-	for _, dot := range []int64{0, lenx/2 - 1, lenx/2 + 2, lenx / 2, lenx - 1} {
-		rbLeft, rbRight, err := rbRef.Split(dot)
-		if err != nil {
-			fmt.Errorf("failed splitting %q at %d: %v", string(x), dot, err)
-		}
-		rb, err := rbLeft.Concat(rbRight)
-		if err != nil {
-			x, y := string(rbLeft.Value()), string(rbRight.Value())
-			fmt.Errorf("failed concating %q and %q: %v", x, y, err)
-		}
-		if err := validateRead(rb.(*RopeBuffer), nil); err != nil {
-			fmt.Errorf("validateRead() %q at %d: %v", string(x), dot, err)
+	for _, rCur := range []int64{1, lenx/2 - 1, lenx/2 + 2, lenx / 2, lenx - 1} {
+		rbLeft, rbRight := rbRef.Split(rCur)
+		rb := rbLeft.Concat(rbRight)
+		if err := validateRead(rb.(*RopeBuffer), x); err != nil {
+			return fmt.Errorf("validateRead() %q at %d: %v", string(x), rCur, err)
 		}
 	}
-	_, _, err := rbRef.Split(lenx)
-	if err == nil {
-		fmt.Errorf("expecting error splitting %q at %d", string(x), lenx)
-	}
 	return nil
 }
 
-func validateRuneSlice(rb *RopeBuffer) error {
-	lb := NewLinearBuffer(rb.Value())
-	offs := runePositions(rb.Value())
-	argList := make([][2]int64, 0)
-	for i, dot := range offs {
-		for n := 0; n < (len(offs) - i); n++ {
-			argList = append(argList, [2]int64{int64(dot), int64(n)})
-		}
-	}
-	for _, arg := range argList {
-		dot, n := arg[0], arg[1]
-		rbRunes, _, err := rb.RuneSlice(dot, n)
-		if err != nil {
-			return fmt.Errorf("rb at %d size %d", dot, n)
-		}
-		lbRunes, _, err := lb.RuneSlice(dot, n)
-		if err != nil {
-			return fmt.Errorf("lb at %d size %d", dot, n)
-		}
-		if string(rbRunes) != string(lbRunes) {
-			return fmt.Errorf("at %d size %d", dot, n)
+func validateSlice(rb *RopeBuffer) error {
+	ref := rb.Runes()
+	for i := range ref {
+		for n := 0; n < (len(ref) - i); n++ {
+			x := string(ref[i : i+n])
+			y := string(rb.Slice(int64(i), int64(n)).Runes())
+			if x != y {
+				return fmt.Errorf("at %d size %d expected %q, got %q", i, n, x, y)
+			}
 		}
 	}
 	return nil
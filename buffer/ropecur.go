@@ -0,0 +1,140 @@
+package buffer
+
+import "io"
+
+// RopeCursor is a position within a rope that can move forward and
+// backward one rune at a time, or seek to any rune offset. It keeps
+// the path from root to the current leaf as an explicit stack, hence
+// there is nothing to release when the cursor is dropped.
+//
+// The cursor is valid only as long as the rope is not modified by
+// the in-place APIs, InsertIn and DeleteIn.
+type RopeCursor struct {
+	root  *RopeBuffer
+	stack []ropeFrame // path from root to current leaf.
+	off   int64       // byte offset within current leaf.
+	rCur  int64       // rune offset within rope.
+}
+
+// ropeFrame is a node in the cursor path, right says whether the
+// node is the right child of its parent. Same node can be both the
+// left and right child of its parent, hence the flag.
+type ropeFrame struct {
+	node  *RopeBuffer
+	right bool
+}
+
+// Cursor return a new cursor positioned at rune offset rCur. Return
+// nil if rCur is not a valid cursor.
+func (rb *RopeBuffer) Cursor(rCur int64) *RopeCursor {
+	if rb == nil {
+		return nil
+	}
+	cur := &RopeCursor{root: rb, stack: make([]ropeFrame, 0, rb.Depth+1)}
+	if !cur.SeekTo(rCur) {
+		return nil
+	}
+	return cur
+}
+
+// Offset return the rune offset of cursor.
+func (cur *RopeCursor) Offset() int64 {
+	return cur.rCur
+}
+
+// SeekTo position the cursor at rune offset rCur, in O(log n). Return
+// false and leave the cursor as it is, if rCur is not a valid cursor.
+func (cur *RopeCursor) SeekTo(rCur int64) bool {
+	if !cur.root.isValidCursor(rCur) {
+		return false
+	}
+	cur.stack = append(cur.stack[:0], ropeFrame{node: cur.root})
+	node, r := cur.root, rCur
+	for !node.isLeaf() {
+		if r >= node.RWeight {
+			node, r = node.Right, r-node.RWeight
+			cur.stack = append(cur.stack, ropeFrame{node: node, right: true})
+		} else {
+			node = node.Left
+			cur.stack = append(cur.stack, ropeFrame{node: node})
+		}
+	}
+	cur.off, cur.rCur = runeOffset(node.Text, r), rCur
+	return true
+}
+
+// Next return the rune after the cursor and move the cursor past
+// it. Return io.EOF at the end of rope.
+func (cur *RopeCursor) Next() (r rune, size int, err error) {
+	leaf := cur.leaf()
+	for cur.off >= leaf.Len {
+		if !cur.nextLeaf() {
+			return r, size, io.EOF
+		}
+		leaf = cur.leaf()
+	}
+	r, size = decodeRune(leaf.Text[cur.off:])
+	cur.off += int64(size)
+	cur.rCur++
+	return r, size, nil
+}
+
+// Prev return the rune before the cursor and move the cursor
+// before it. Return io.EOF at the beginning of rope.
+func (cur *RopeCursor) Prev() (r rune, size int, err error) {
+	leaf := cur.leaf()
+	for cur.off <= 0 {
+		if !cur.prevLeaf() {
+			return r, size, io.EOF
+		}
+		leaf = cur.leaf()
+	}
+	r, size = decodeLastRune(leaf.Text[:cur.off])
+	cur.off -= int64(size)
+	cur.rCur--
+	return r, size, nil
+}
+
+func (cur *RopeCursor) leaf() *RopeBuffer {
+	return cur.stack[len(cur.stack)-1].node
+}
+
+// nextLeaf move the cursor to the beginning of next leaf, return
+// false if current leaf is the last one.
+func (cur *RopeCursor) nextLeaf() bool {
+	i := len(cur.stack) - 1
+	for i > 0 && cur.stack[i].right {
+		i--
+	}
+	if i == 0 {
+		return false
+	}
+	node := cur.stack[i-1].node.Right
+	cur.stack = append(cur.stack[:i], ropeFrame{node: node, right: true})
+	for !node.isLeaf() {
+		node = node.Left
+		cur.stack = append(cur.stack, ropeFrame{node: node})
+	}
+	cur.off = 0
+	return true
+}
+
+// prevLeaf move the cursor to the end of previous leaf, return
+// false if current leaf is the first one.
+func (cur *RopeCursor) prevLeaf() bool {
+	i := len(cur.stack) - 1
+	for i > 0 && !cur.stack[i].right {
+		i--
+	}
+	if i == 0 {
+		return false
+	}
+	node := cur.stack[i-1].node.Left
+	cur.stack = append(cur.stack[:i], ropeFrame{node: node})
+	for !node.isLeaf() {
+		node = node.Right
+		cur.stack = append(cur.stack, ropeFrame{node: node, right: true})
+	}
+	cur.off = node.Len
+	return true
+}
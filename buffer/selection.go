@@ -0,0 +1,137 @@
+package buffer
+
+import "sort"
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// Selection is a range of runes between Anchor and Head, where
+// Head is the end that moves, like the cursor. Selection can go
+// backward, with Head before Anchor, and is a plain cursor when
+// Anchor and Head are same.
+type Selection struct {
+	Anchor int64
+	Head   int64
+}
+
+// Start return the beginning of selection.
+func (sel Selection) Start() int64 {
+	if sel.Head < sel.Anchor {
+		return sel.Head
+	}
+	return sel.Anchor
+}
+
+// End return the end of selection, exclusive.
+func (sel Selection) End() int64 {
+	if sel.Head < sel.Anchor {
+		return sel.Anchor
+	}
+	return sel.Head
+}
+
+// IsCursor return true if selection is empty.
+func (sel Selection) IsCursor() bool {
+	return sel.Anchor == sel.Head
+}
+
+// Selections is a set of selections, sorted by their start and
+// without any overlap.
+type Selections []Selection
+
+// NewSelections return a selection set from sels, overlapping
+// selections, and cursors at the same position, are merged.
+func NewSelections(sels ...Selection) Selections {
+	x := append(Selections{}, sels...)
+	sort.SliceStable(x, func(i, j int) bool { return x[i].Start() < x[j].Start() })
+	merged := x[:0]
+	for _, sel := range x {
+		n := len(merged)
+		if n == 0 {
+			merged = append(merged, sel)
+			continue
+		}
+		last := merged[n-1]
+		if sel.Start() < last.End() || sel.Start() == last.Start() {
+			start, end := last.Start(), last.End()
+			if sel.End() > end {
+				end = sel.End()
+			}
+			if last.Head < last.Anchor { // keep the direction.
+				merged[n-1] = Selection{Anchor: end, Head: start}
+			} else {
+				merged[n-1] = Selection{Anchor: start, Head: end}
+			}
+			continue
+		}
+		merged = append(merged, sel)
+	}
+	return merged
+}
+
+// Replace text of every selection in buffer, selections collapse
+// to cursors after the replaced text. Return the new buffer and
+// the new selection set.
+func (sels Selections) Replace(buffer Buffer, text []rune) (Buffer, Selections) {
+	edits := sels.replaceEdits(text)
+	return applyEdits(buffer, edits), sels.shift(edits)
+}
+
+// Delete text of every selection in buffer, for cursors delete n
+// runes after the cursor, or -n runes before the cursor if n is
+// negative. Selections collapse to cursors where the deleted text
+// was. Return the new buffer and the new selection set.
+func (sels Selections) Delete(buffer Buffer, n int64) (Buffer, Selections) {
+	edits := sels.deleteEdits(buffer, n)
+	return applyEdits(buffer, edits), sels.shift(edits)
+}
+
+func (sels Selections) replaceEdits(text []rune) []textEdit {
+	edits := make([]textEdit, 0, len(sels))
+	for _, sel := range sels {
+		edits = append(edits, textEdit{sel.Start(), sel.End(), text, nil})
+	}
+	return edits
+}
+
+func (sels Selections) deleteEdits(buffer Buffer, n int64) []textEdit {
+	edits, size := make([]textEdit, 0, len(sels)), buffer.Length()
+	for _, sel := range sels {
+		start, end := sel.Start(), sel.End()
+		if sel.IsCursor() && n > 0 {
+			end = start + n
+		} else if sel.IsCursor() {
+			start = start + n
+		}
+		if start < 0 {
+			start = 0
+		}
+		if end > size {
+			end = size
+		}
+		if start >= end {
+			continue
+		}
+		i := len(edits) - 1 // merge regions that overlap.
+		if i >= 0 && start <= edits[i].end {
+			if end > edits[i].end {
+				edits[i].end = end
+			}
+			continue
+		}
+		edits = append(edits, textEdit{start: start, end: end})
+	}
+	return edits
+}
+
+// shift selections across edits.
+func (sels Selections) shift(edits []textEdit) Selections {
+	x := make(Selections, 0, len(sels))
+	for _, sel := range sels {
+		x = append(x, Selection{
+			Anchor: shiftCursor(sel.Anchor, edits, true),
+			Head:   shiftCursor(sel.Head, edits, true),
+		})
+	}
+	return NewSelections(x...)
+}
@@ -0,0 +1,78 @@
+package buffer
+
+import "reflect"
+import "testing"
+
+func TestNewSelections(t *testing.T) {
+	testcases := []struct {
+		sels, ref Selections
+	}{
+		{Selections{{5, 5}, {1, 1}, {3, 3}}, Selections{{1, 1}, {3, 3}, {5, 5}}},
+		{Selections{{2, 2}, {2, 2}}, Selections{{2, 2}}},
+		{Selections{{0, 4}, {2, 6}}, Selections{{0, 6}}},
+		{Selections{{4, 0}, {6, 2}}, Selections{{6, 0}}},
+		{Selections{{0, 3}, {3, 5}}, Selections{{0, 3}, {3, 5}}},
+		{Selections{{0, 3}, {1, 1}, {3, 3}}, Selections{{0, 3}, {3, 3}}},
+		{Selections{{0, 10}, {2, 4}, {6, 8}}, Selections{{0, 10}}},
+	}
+	for _, tcase := range testcases {
+		if x := NewSelections(tcase.sels...); !reflect.DeepEqual(x, tcase.ref) {
+			t.Fatalf("%v expected %v, got %v", tcase.sels, tcase.ref, x)
+		}
+	}
+}
+
+func TestSelectionsReplace(t *testing.T) {
+	text := "foo bar baz 中文"
+	testcases := []struct {
+		sels Selections
+		text string
+		ref  string
+		out  Selections
+	}{
+		{Selections{{0, 0}, {4, 4}, {8, 8}}, "x", "xfoo xbar xbaz 中文", Selections{{1, 1}, {6, 6}, {11, 11}}},
+		{Selections{{0, 3}, {7, 4}, {8, 11}}, "qu", "qu qu qu 中文", Selections{{2, 2}, {5, 5}, {8, 8}}},
+		{Selections{{12, 14}, {0, 0}}, "", "foo bar baz ", Selections{{0, 0}, {12, 12}}},
+		{Selections{{3, 3}, {3, 4}}, "-", "foo-bar baz 中文", Selections{{4, 4}}},
+		{Selections{{14, 14}}, "字", "foo bar baz 中文字", Selections{{15, 15}}},
+	}
+	for _, tcase := range testcases {
+		rb := NewRopebuffer([]byte(text), 4)
+		sels := NewSelections(tcase.sels...)
+		buffer, out := sels.Replace(rb, []rune(tcase.text))
+		if x := string(buffer.Bytes()); x != tcase.ref {
+			t.Fatalf("%v expected %q, got %q", tcase.sels, tcase.ref, x)
+		} else if !reflect.DeepEqual(out, tcase.out) {
+			t.Fatalf("%v expected %v, got %v", tcase.sels, tcase.out, out)
+		} else if x := string(rb.Bytes()); x != text {
+			t.Fatalf("source buffer changed %q", x)
+		}
+	}
+}
+
+func TestSelectionsDelete(t *testing.T) {
+	text := "abcdefghij"
+	testcases := []struct {
+		sels Selections
+		n    int64
+		ref  string
+		out  Selections
+	}{
+		{Selections{{1, 1}, {5, 5}}, 1, "acdeghij", Selections{{1, 1}, {4, 4}}},
+		{Selections{{1, 1}, {5, 5}}, -1, "bcdfghij", Selections{{0, 0}, {3, 3}}},
+		{Selections{{0, 0}, {10, 10}}, -2, "abcdefgh", Selections{{0, 0}, {8, 8}}},
+		{Selections{{2, 2}, {3, 3}}, 3, "abghij", Selections{{2, 2}}},
+		{Selections{{1, 3}, {6, 4}, {8, 8}}, -1, "adgij", Selections{{1, 1}, {2, 2}, {3, 3}}},
+		{Selections{{9, 9}}, 5, "abcdefghi", Selections{{9, 9}}},
+	}
+	for _, tcase := range testcases {
+		rb := NewLinearBuffer([]byte(text))
+		sels := NewSelections(tcase.sels...)
+		buffer, out := sels.Delete(rb, tcase.n)
+		if x := string(buffer.Bytes()); x != tcase.ref {
+			t.Fatalf("%v expected %q, got %q", tcase.sels, tcase.ref, x)
+		} else if !reflect.DeepEqual(out, tcase.out) {
+			t.Fatalf("%v expected %v, got %v", tcase.sels, tcase.out, out)
+		}
+	}
+}
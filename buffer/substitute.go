@@ -0,0 +1,144 @@
+package buffer
+
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// Substitute replace upto n matches of re, within runes [start,
+// end) of buffer, with template, n < 0 replace all the matches.
+// Range boundaries are treated as beginning and end of text. In
+// template, $1 ${1} and \1 expand to the text matched by the first
+// capture group, $0 and \0 to the whole match, $$ and \\ to a
+// literal $ and \. Return the new buffer, along with {start, end}
+// pairs of replaced text within the new buffer.
+func Substitute(
+	buffer Buffer, re *Regexp, template string,
+	start, end, n int64) (Buffer, []int64, error) {
+
+	edits, err := substituteEdits(buffer, re, template, start, end, n)
+	if err != nil {
+		return buffer, nil, err
+	}
+	return applyEdits(buffer, edits), editRanges(edits), nil
+}
+
+// substituteEdits gather replacements, refer Substitute, in a
+// single pass over the range.
+func substituteEdits(
+	buffer Buffer, re *Regexp, template string,
+	start, end, n int64) ([]textEdit, error) {
+
+	if start < 0 || start > end || end > buffer.Length() {
+		return nil, ErrorIndexOutofbound
+	}
+	parts := parseTemplate(template)
+
+	edits := make([]textEdit, 0)
+	iter := FindSubmatch(re, buffer.StreamCount(start, end-start))
+	for ; n != 0; n-- {
+		loc := iter()
+		if loc == nil {
+			break
+		}
+		for i := range loc {
+			if loc[i] >= 0 {
+				loc[i] += start
+			}
+		}
+		text := expandTemplate(parts, buffer, loc)
+		edits = append(edits, textEdit{loc[0], loc[1], text, nil})
+	}
+	return edits, nil
+}
+
+// templatePart is either literal text, or the capture group to
+// expand, when group >= 0.
+type templatePart struct {
+	text  []rune
+	group int
+}
+
+// parseTemplate split template into literals and group references,
+// refer Substitute.
+func parseTemplate(template string) []templatePart {
+	parts, lit := make([]templatePart, 0), make([]rune, 0)
+	flush := func(group int) {
+		if len(lit) > 0 {
+			parts = append(parts, templatePart{text: lit, group: -1})
+			lit = make([]rune, 0)
+		}
+		if group >= 0 {
+			parts = append(parts, templatePart{group: group})
+		}
+	}
+	runes := []rune(template)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if (r != '$' && r != '\\') || i+1 == len(runes) {
+			lit = append(lit, r)
+			continue
+		}
+		next := runes[i+1]
+		switch {
+		case next == r: // $$ or \\
+			lit, i = append(lit, r), i+1
+		case r == '\\' && isDigit(next):
+			flush(int(next - '0'))
+			i++
+		case r == '$' && isDigit(next):
+			j, group := i+1, 0
+			for ; j < len(runes) && isDigit(runes[j]); j++ {
+				group = group*10 + int(runes[j]-'0')
+			}
+			flush(group)
+			i = j - 1
+		case r == '$' && next == '{':
+			j, group := i+2, 0
+			for ; j < len(runes) && isDigit(runes[j]); j++ {
+				group = group*10 + int(runes[j]-'0')
+			}
+			if j == i+2 || j == len(runes) || runes[j] != '}' {
+				lit = append(lit, r) // not a group reference.
+				continue
+			}
+			flush(group)
+			i = j
+		default:
+			lit = append(lit, r)
+		}
+	}
+	flush(-1)
+	return parts
+}
+
+// expandTemplate return replacement text for a match, loc has the
+// offsets of the match and its capture groups within buffer.
+// References to groups that didn't match, or that are not in the
+// pattern, expand to empty text.
+func expandTemplate(parts []templatePart, buffer Buffer, loc []int64) []rune {
+	text := make([]rune, 0)
+	for _, part := range parts {
+		if part.group < 0 {
+			text = append(text, part.text...)
+			continue
+		} else if 2*part.group+1 >= len(loc) || loc[2*part.group] < 0 {
+			continue
+		}
+		s, e := loc[2*part.group], loc[2*part.group+1]
+		if reader := buffer.StreamCount(s, e-s); reader != nil {
+			for {
+				r, _, err := reader.ReadRune()
+				if err != nil {
+					break
+				}
+				text = append(text, r)
+			}
+			reader.Close()
+		}
+	}
+	return text
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
@@ -0,0 +1,79 @@
+package buffer
+
+import "reflect"
+import "regexp"
+import "testing"
+
+func TestSubstitute(t *testing.T) {
+	testcases := []struct {
+		pattern, template, text string
+	}{
+		{`a(b*)`, `<$1>`, "ab abb a c"},
+		{`(\w+)@(\w+)`, `${2}.$1`, "x@y foo@bar"},
+		{`x*`, `-`, "abc"},
+		{`b`, `$$`, "abcb"},
+		{`(a)|(b)`, `[$1$2]`, "abab"},
+		{`中(文)`, `$1$1`, "中文 中国 中文"},
+		{`o`, `$9`, "foo"},
+		{`\s+`, ` `, "a \t b\n\nc"},
+	}
+	for _, tcase := range testcases {
+		ref := regexp.MustCompile(tcase.pattern)
+		out := ref.ReplaceAllString(tcase.text, tcase.template)
+		rb := NewRopebuffer([]byte(tcase.text), 8)
+		re := MustCompileRegexp(tcase.pattern)
+		buffer, ranges, err := Substitute(rb, re, tcase.template, 0, rb.Length(), -1)
+		if err != nil {
+			t.Fatal(err)
+		} else if x := string(buffer.Bytes()); x != out {
+			t.Fatalf("%q expected %q, got %q", tcase.pattern, out, x)
+		} else if string(rb.Bytes()) != tcase.text {
+			t.Fatalf("source buffer changed %q", rb.Bytes())
+		}
+		// replaced ranges hold the expansion for each match.
+		runes, i := []rune(string(buffer.Bytes())), 0
+		for _, loc := range ref.FindAllStringSubmatchIndex(tcase.text, -1) {
+			repl := string(ref.ExpandString(nil, tcase.template, tcase.text, loc))
+			if x := string(runes[ranges[i]:ranges[i+1]]); x != repl {
+				t.Fatalf("%q range %v expected %q, got %q", tcase.pattern, i/2, repl, x)
+			}
+			i += 2
+		}
+		if i != len(ranges) {
+			t.Fatalf("%q expected %v ranges, got %v", tcase.pattern, i/2, len(ranges)/2)
+		}
+	}
+}
+
+func TestSubstituteRange(t *testing.T) {
+	text := "one two three four five"
+	rb := NewLinearBuffer([]byte(text))
+	re := MustCompileRegexp(`(\w)(\w*)`)
+	testcases := []struct {
+		start, end, n int64
+		template, ref string
+		ranges        []int64
+	}{
+		{0, 23, -1, `\2\1`, "neo wot hreet ourf ivef", []int64{0, 3, 4, 7, 8, 13, 14, 18, 19, 23}},
+		{0, 23, 2, `\2\1`, "neo wot three four five", []int64{0, 3, 4, 7}},
+		{4, 13, -1, `[$0]`, "one [two] [three] four five", []int64{4, 9, 10, 17}},
+		{5, 13, 1, `[$0]`, "one t[wo] three four five", []int64{5, 9}},
+		{0, 23, 0, `x`, text, []int64{}},
+		{9, 9, -1, `x`, text, []int64{}},
+		{0, 23, -1, `\\$$\x`, `\$\x \$\x \$\x \$\x \$\x`, []int64{0, 4, 5, 9, 10, 14, 15, 19, 20, 24}},
+		{0, 3, -1, `${1`, "${1 two three four five", []int64{0, 3}},
+	}
+	for _, tcase := range testcases {
+		buffer, ranges, err := Substitute(rb, re, tcase.template, tcase.start, tcase.end, tcase.n)
+		if err != nil {
+			t.Fatal(err)
+		} else if x := string(buffer.Bytes()); x != tcase.ref {
+			t.Fatalf("%v expected %q, got %q", tcase.template, tcase.ref, x)
+		} else if !reflect.DeepEqual(ranges, tcase.ranges) {
+			t.Fatalf("%v expected %v, got %v", tcase.template, tcase.ranges, ranges)
+		}
+	}
+	if _, _, err := Substitute(rb, re, "", 10, 24, -1); err != ErrorIndexOutofbound {
+		t.Fatalf("expected %v, got %v", ErrorIndexOutofbound, err)
+	}
+}
@@ -0,0 +1,684 @@
+package buffer
+
+import "unicode"
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+// Text objects are iterated as {start, end} pairs of rune offsets,
+// like search matches, refer Finder. Words, sentences and paragraphs
+// are found going forward from a cursor, the first one being the
+// object containing the cursor, or backward, the first one being
+// the object containing the rune before the cursor. Blocks are
+// found starting after, or ending before, the cursor, refer
+// EnclosingBlock for the block around the cursor. Text is streamed
+// from buffer as objects are iterated, boundaries between objects
+// are decided by looking at no more than textLookaround runes
+// around them.
+
+// Words return an iterator over successive vi words in buffer,
+// going forward from rCur. A word is a sequence of letters, digits
+// and underscore, or a sequence of other non-blank characters, a
+// WORD, when big is true, is any sequence of non-blank characters.
+// Text is read as grapheme clusters, combining marks stay with
+// their base, and words break where the script changes between
+// ideographs, kana, hangul and other letters, or at emoji.
+func Words(buffer Buffer, rCur int64, big bool) Finder {
+	return textObjects(buffer, rCur, false, viWords(big))
+}
+
+// WordsBackward is like Words, going backward from rCur.
+func WordsBackward(buffer Buffer, rCur int64, big bool) Finder {
+	return textObjects(buffer, rCur, true, viWords(big))
+}
+
+// Sentences return an iterator over successive sentences in buffer,
+// going forward from rCur. A sentence ends after a sentence
+// terminator, like '.', '!', '?', '。' or '।', optionally followed
+// by closing brackets and quotes, and then by a blank or the end
+// of paragraph. Full width terminators need no blank after them.
+// Sentences don't span paragraphs, and blanks between sentences
+// are not part of either.
+func Sentences(buffer Buffer, rCur int64) Finder {
+	return textObjects(buffer, rCur, false, sentences)
+}
+
+// SentencesBackward is like Sentences, going backward from rCur.
+func SentencesBackward(buffer Buffer, rCur int64) Finder {
+	return textObjects(buffer, rCur, true, sentences)
+}
+
+// Paragraphs return an iterator over successive paragraphs in
+// buffer, going forward from rCur. A paragraph is a sequence of
+// lines separated by blank lines, lines with only white space, and
+// includes the line ending of its last line.
+func Paragraphs(buffer Buffer, rCur int64) Finder {
+	return textObjects(buffer, rCur, false, paragraphs)
+}
+
+// ParagraphsBackward is like Paragraphs, going backward from rCur.
+func ParagraphsBackward(buffer Buffer, rCur int64) Finder {
+	return textObjects(buffer, rCur, true, paragraphs)
+}
+
+// Blocks return an iterator over blocks, from open to close rune
+// including both, that start at or after rCur, in the order of
+// their start. Brackets nest, and blocks inside another block are
+// included. Quotes, open same as close, pair up within a line and
+// a quote escaped by backslash is skipped. Unbalanced brackets and
+// quotes are ignored.
+func Blocks(buffer Buffer, rCur int64, open, close rune) Finder {
+	if open == close {
+		return quotes(buffer, rCur, false, open)
+	}
+	return brackets(buffer, rCur, false, open, close)
+}
+
+// BlocksBackward is like Blocks, for blocks that end at or before
+// rCur, in the reverse order of their end.
+func BlocksBackward(buffer Buffer, rCur int64, open, close rune) Finder {
+	if open == close {
+		return quotes(buffer, rCur, true, open)
+	}
+	return brackets(buffer, rCur, true, open, close)
+}
+
+// EnclosingBlock return the innermost block, from open to close
+// rune including both, that contains runes [start, end) and is
+// larger than them. Pass {rCur, rCur+1} for the block around a
+// cursor, and the block itself for the block around it. Return
+// nil if there is no such block, refer Blocks.
+func EnclosingBlock(buffer Buffer, start, end int64, open, close rune) []int64 {
+	size := buffer.Length()
+	if start < 0 || end < start || end > size {
+		return nil
+	} else if open == close {
+		w := newRuneWindow(buffer)
+		defer w.close()
+		next := quotePairs(w, w.lineStart(start), open)
+		for loc := next(); loc != nil && loc[0] <= start; loc = next() {
+			if loc[1] >= end && loc[1]-loc[0] > end-start {
+				return loc
+			}
+		}
+		return nil
+	}
+
+	// block starting at start.
+	if r, ok := runeAt(buffer, start); ok && r == open {
+		if e := matchBracket(buffer, start, open, close); e > end {
+			return []int64{start, e}
+		}
+	}
+	// blocks starting before start, innermost first.
+	reader := buffer.BackStreamFrom(start)
+	if reader == nil {
+		return nil
+	}
+	defer reader.Close()
+	depth := 0
+	for s := start - 1; s >= 0; s-- {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			break
+		} else if r == close {
+			depth++
+		} else if r == open && depth > 0 {
+			depth--
+		} else if r == open {
+			if e := matchBracket(buffer, s, open, close); e >= end {
+				return []int64{s, e}
+			}
+		}
+	}
+	return nil
+}
+
+//---------------
+// local function
+//---------------
+
+// textLookaround is the no. of runes looked at, on either side,
+// to decide a boundary between text objects, text beyond it is
+// treated as the start or end of buffer.
+const textLookaround = 128
+
+// textWindow is the maximum no. of runes held by runeWindow.
+const textWindow = 4096
+
+// textSegment find text objects by their boundaries. Boundary
+// return true for a boundary before rune i, 0 < i < size, and
+// object return the text object, if any, in the segment [start, end)
+// between consecutive boundaries.
+type textSegment struct {
+	boundary func(w *runeWindow, i int64) bool
+	object   func(w *runeWindow, start, end int64) (s, e int64, ok bool)
+}
+
+// textObjects iterate over text objects going forward or backward
+// from rCur, a segment at a time.
+func textObjects(buffer Buffer, rCur int64, backward bool, seg textSegment) Finder {
+	w := newRuneWindow(buffer)
+	if rCur < 0 || rCur > w.size {
+		return Finder(func() []int64 { return nil })
+	}
+	isBoundary := func(i int64) bool {
+		return i <= 0 || i >= w.size || seg.boundary(w, i)
+	}
+	if backward {
+		end := rCur // first boundary at or after rCur.
+		for ; !isBoundary(end); end++ {
+		}
+		return Finder(func() []int64 {
+			for end > 0 {
+				start := end - 1
+				for ; !isBoundary(start); start-- {
+				}
+				s, e, ok := seg.object(w, start, end)
+				if end = start; ok && s < rCur {
+					return []int64{s, e}
+				}
+			}
+			w.close()
+			return nil
+		})
+	}
+	start := rCur // last boundary at or before rCur.
+	for ; !isBoundary(start); start-- {
+	}
+	return Finder(func() []int64 {
+		for start < w.size {
+			end := start + 1
+			for ; !isBoundary(end); end++ {
+			}
+			s, e, ok := seg.object(w, start, end)
+			if start = end; ok && e > rCur {
+				return []int64{s, e}
+			}
+		}
+		w.close()
+		return nil
+	})
+}
+
+var paragraphs = textSegment{
+	boundary: paragraphBoundary,
+	object: func(w *runeWindow, start, end int64) (int64, int64, bool) {
+		return start, end, !w.isBlankLine(start)
+	},
+}
+
+// paragraphBoundary between a blank line and a line that is not.
+func paragraphBoundary(w *runeWindow, i int64) bool {
+	return w.isLineStart(i) && w.isBlankLine(i-1) != w.isBlankLine(i)
+}
+
+var sentences = textSegment{
+	boundary: sentenceBoundary,
+	object: func(w *runeWindow, start, end int64) (int64, int64, bool) {
+		for ; start < end && w.isSpace(start); start++ {
+		}
+		for ; end > start && w.isSpace(end-1); end-- {
+		}
+		return start, end, start < end
+	},
+}
+
+// sentenceBoundary after a sentence terminator and the closing runes
+// that follow it, refer Sentences, and between paragraphs.
+func sentenceBoundary(w *runeWindow, i int64) bool {
+	if paragraphBoundary(w, i) {
+		return true
+	}
+	r, _ := w.at(i)
+	if l, _ := w.at(i - 1); !isSentenceClose(l) || isSentenceClose(r) {
+		return false
+	}
+	term := rune(-1) // first terminator among the closing runes.
+	for j := i - 1; j > i-textLookaround; j-- {
+		c, ok := w.at(j)
+		if !ok || !isSentenceClose(c) {
+			break
+		} else if unicode.Is(unicode.Sentence_Terminal, c) {
+			term = c
+		}
+	}
+	return term >= 0 && (unicode.IsSpace(r) || RuneWidth(term) == 2)
+}
+
+// isSentenceClose return true for runes that can follow a sentence
+// terminator within the sentence.
+func isSentenceClose(r rune) bool {
+	switch {
+	case r == '"' || r == '\'':
+		return true
+	case unicode.In(r, unicode.Pe, unicode.Pf):
+		return true
+	}
+	return unicode.Is(unicode.Sentence_Terminal, r)
+}
+
+// vi word classes, refer Words.
+const (
+	wordBlank = iota
+	wordPunct
+	wordLetter
+	wordEmoji
+	wordHan
+	wordHiragana
+	wordKatakana
+	wordHangul
+)
+
+func viWords(big bool) textSegment {
+	class := func(w *runeWindow, i int64) int {
+		r, _ := w.at(i)
+		return viWordClass(r, big)
+	}
+	return textSegment{
+		boundary: func(w *runeWindow, i int64) bool {
+			if !w.graphemeBoundary(i) {
+				return false
+			}
+			j := i - 1 // start of grapheme cluster before i.
+			for ; j > i-textLookaround && !w.graphemeBoundary(j); j-- {
+			}
+			return class(w, j) != class(w, i)
+		},
+		object: func(w *runeWindow, start, end int64) (int64, int64, bool) {
+			return start, end, class(w, start) != wordBlank
+		},
+	}
+}
+
+// viWordClass return the class of grapheme cluster starting with r.
+func viWordClass(r rune, big bool) int {
+	switch {
+	case unicode.IsSpace(r):
+		return wordBlank
+	case big:
+		return wordLetter
+	case unicode.Is(unicode.Han, r):
+		return wordHan
+	case unicode.Is(unicode.Hiragana, r):
+		return wordHiragana
+	case unicode.Is(unicode.Katakana, r):
+		return wordKatakana
+	case unicode.Is(unicode.Hangul, r):
+		return wordHangul
+	case isWordRune(r) || unicode.IsMark(r):
+		return wordLetter
+	case graphemePropOf(r) == gbPictographic:
+		return wordEmoji
+	}
+	return wordPunct
+}
+
+// quotes iterate over quoted blocks that end after rCur, or start
+// before rCur if backward, refer Blocks. Quotes pair up within a
+// line, hence lines are scanned from their start.
+func quotes(buffer Buffer, rCur int64, backward bool, quote rune) Finder {
+	w := newRuneWindow(buffer)
+	if rCur < 0 || rCur > w.size || (backward && rCur == 0) {
+		return Finder(func() []int64 { return nil })
+	} else if !backward {
+		next := quotePairs(w, w.lineStart(rCur), quote)
+		return Finder(func() []int64 {
+			for loc := next(); loc != nil; loc = next() {
+				if loc[1] > rCur {
+					return loc
+				}
+			}
+			w.close()
+			return nil
+		})
+	}
+
+	// quotes in a line are counted going forward, so that they can
+	// be paired going backward, n being the quotes yet to be seen.
+	start := w.lineStart(rCur - 1)
+	j, n, close := w.lineEnd(start), countQuotes(w, start, quote), int64(-1)
+	return Finder(func() []int64 {
+		for {
+			if j--; j < start && start == 0 {
+				w.close()
+				return nil
+			} else if j < start {
+				j, start = start, w.lineStart(start-1)
+				n, close = countQuotes(w, start, quote), -1
+				continue
+			}
+			if r, _ := w.at(j); r != quote || w.isEscaped(j) {
+				continue
+			} else if n--; n%2 == 1 {
+				close = j
+			} else if close >= 0 {
+				s, e := j, close+1
+				if close = -1; s < rCur {
+					return []int64{s, e}
+				}
+			}
+		}
+	})
+}
+
+// quotePairs iterate over quoted blocks, going forward from start
+// of a line, skipping quotes escaped by backslash.
+func quotePairs(w *runeWindow, start int64, quote rune) func() []int64 {
+	j, open, escaped := start, int64(-1), false
+	return func() []int64 {
+		for ; j < w.size; j++ {
+			r, _ := w.at(j)
+			switch {
+			case r == '\n' || r == '\r':
+				open, escaped = -1, false
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote && open < 0:
+				open = j
+			case r == quote:
+				s := open
+				j, open = j+1, -1
+				return []int64{s, j}
+			}
+		}
+		return nil
+	}
+}
+
+// countQuotes return the no. of quotes, not escaped, in the line
+// starting at start.
+func countQuotes(w *runeWindow, start int64, quote rune) (n int) {
+	escaped := false
+	for j := start; j < w.size; j++ {
+		switch r, _ := w.at(j); {
+		case r == '\n' || r == '\r':
+			return n
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == quote:
+			n++
+		}
+	}
+	return n
+}
+
+// brackets iterate over bracketed blocks starting at or after rCur,
+// or ending at or before rCur if backward, refer Blocks.
+func brackets(buffer Buffer, rCur int64, backward bool, open, close rune) Finder {
+	var reader RuneReader
+	if backward {
+		reader, open, close = buffer.BackStreamFrom(rCur), close, open
+	} else {
+		reader = buffer.StreamFrom(rCur)
+	}
+	if reader == nil {
+		return Finder(func() []int64 { return nil })
+	}
+
+	// blocks are complete when their close bracket is read, inner
+	// blocks first, and are returned once the outermost is complete.
+	pos, stack, blocks, done := int64(0), []int64{}, []int64{}, false
+	return Finder(func() []int64 {
+		for !done && (len(stack) > 0 || len(blocks) == 0) {
+			r, _, err := reader.ReadRune()
+			if err != nil {
+				reader.Close()
+				stack, done = stack[:0], true
+				break
+			}
+			if r == open {
+				stack = append(stack, pos)
+			} else if r == close && len(stack) > 0 {
+				s := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				blocks = insertBlock(blocks, s, pos+1)
+			}
+			pos++
+		}
+		if len(blocks) == 0 {
+			return nil
+		}
+		s, e := blocks[0], blocks[1]
+		blocks = blocks[2:]
+		if backward {
+			return []int64{rCur - e, rCur - s}
+		}
+		return []int64{rCur + s, rCur + e}
+	})
+}
+
+// insertBlock keep blocks sorted by their start.
+func insertBlock(blocks []int64, start, end int64) []int64 {
+	i := len(blocks)
+	for ; i > 0 && blocks[i-2] > start; i -= 2 {
+	}
+	blocks = append(blocks, 0, 0)
+	copy(blocks[i+2:], blocks[i:])
+	blocks[i], blocks[i+1] = start, end
+	return blocks
+}
+
+// matchBracket return the cursor after the close bracket matching
+// the open bracket at rCur, -1 if it is unbalanced.
+func matchBracket(buffer Buffer, rCur int64, open, close rune) int64 {
+	reader := buffer.StreamFrom(rCur + 1)
+	if reader == nil {
+		return -1
+	}
+	defer reader.Close()
+	depth := 0
+	for e := rCur + 1; ; e++ {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return -1
+		} else if r == open {
+			depth++
+		} else if r == close && depth > 0 {
+			depth--
+		} else if r == close {
+			return e + 1
+		}
+	}
+}
+
+func runeAt(buffer Buffer, rCur int64) (rune, bool) {
+	reader := buffer.StreamCount(rCur, 1)
+	if reader == nil {
+		return 0, false
+	}
+	defer reader.Close()
+	r, _, err := reader.ReadRune()
+	return r, err == nil
+}
+
+// runeWindow read runes of buffer through its streams, as they are
+// looked at, holding at most textWindow runes around them.
+type runeWindow struct {
+	buffer Buffer
+	size   int64
+	lo     int64  // offset of runes[0].
+	runes  []rune // runes [lo, lo+len(runes)).
+	fwd    RuneReader
+	bwd    RuneReader
+}
+
+func newRuneWindow(buffer Buffer) *runeWindow {
+	return &runeWindow{buffer: buffer, size: buffer.Length()}
+}
+
+// at return the rune at i, false if i is outside buffer. Runes far
+// away from the window are read afresh from i.
+func (w *runeWindow) at(i int64) (rune, bool) {
+	if i < 0 || i >= w.size {
+		return 0, false
+	}
+	hi := w.lo + int64(len(w.runes))
+	if i < w.lo-textWindow/4 || i >= hi+textWindow/4 {
+		w.close()
+		w.lo, w.runes, hi = i, w.runes[:0], i
+	}
+	for ; i >= hi; hi = w.lo + int64(len(w.runes)) {
+		if !w.readForward(hi) {
+			return 0, false
+		}
+	}
+	for i < w.lo {
+		if !w.readBackward() {
+			return 0, false
+		}
+	}
+	return w.runes[i-w.lo], true
+}
+
+// readForward a block of runes from hi, dropping runes from the
+// start of window beyond textWindow.
+func (w *runeWindow) readForward(hi int64) bool {
+	if w.fwd == nil {
+		if w.fwd = w.buffer.StreamFrom(hi); w.fwd == nil {
+			return false
+		}
+	}
+	n := 0
+	for ; n < textWindow/4; n++ {
+		r, _, err := w.fwd.ReadRune()
+		if err != nil {
+			break
+		}
+		w.runes = append(w.runes, r)
+	}
+	if drop := len(w.runes) - textWindow; drop > 0 {
+		w.runes = append(w.runes[:0], w.runes[drop:]...)
+		w.lo += int64(drop)
+		if w.bwd != nil {
+			w.bwd.Close()
+			w.bwd = nil
+		}
+	}
+	return n > 0
+}
+
+// readBackward a block of runes before lo, dropping runes from the
+// end of window beyond textWindow.
+func (w *runeWindow) readBackward() bool {
+	if w.bwd == nil {
+		if w.bwd = w.buffer.BackStreamFrom(w.lo); w.bwd == nil {
+			return false
+		}
+	}
+	block := make([]rune, 0, textWindow/4)
+	for len(block) < textWindow/4 {
+		r, _, err := w.bwd.ReadRune()
+		if err != nil {
+			break
+		}
+		block = append(block, r)
+	}
+	for i, j := 0, len(block)-1; i < j; i, j = i+1, j-1 {
+		block[i], block[j] = block[j], block[i]
+	}
+	w.runes = append(block, w.runes...)
+	w.lo -= int64(len(block))
+	if len(w.runes) > textWindow {
+		w.runes = w.runes[:textWindow]
+		if w.fwd != nil {
+			w.fwd.Close()
+			w.fwd = nil
+		}
+	}
+	return len(block) > 0
+}
+
+func (w *runeWindow) close() {
+	if w.fwd != nil {
+		w.fwd.Close()
+	}
+	if w.bwd != nil {
+		w.bwd.Close()
+	}
+	w.fwd, w.bwd = nil, nil
+}
+
+func (w *runeWindow) isSpace(i int64) bool {
+	r, ok := w.at(i)
+	return ok && unicode.IsSpace(r)
+}
+
+// isLineStart return true if a line starts at i, any of "\n",
+// "\r\n" or "\r" end a line, refer LineStart.
+func (w *runeWindow) isLineStart(i int64) bool {
+	l, ok := w.at(i - 1)
+	if r, _ := w.at(i); !ok || l == '\n' {
+		return true
+	} else if l == '\r' && r != '\n' {
+		return true
+	}
+	return false
+}
+
+// lineStart return the start of line containing i.
+func (w *runeWindow) lineStart(i int64) int64 {
+	for ; !w.isLineStart(i); i-- {
+	}
+	return i
+}
+
+// lineEnd return the start of next line, after the line ending,
+// for the line containing i.
+func (w *runeWindow) lineEnd(i int64) int64 {
+	for ; i < w.size; i++ {
+		if r, _ := w.at(i); r == '\n' {
+			return i + 1
+		} else if next, _ := w.at(i + 1); r == '\r' && next == '\n' {
+			return i + 2
+		} else if r == '\r' {
+			return i + 1
+		}
+	}
+	return w.size
+}
+
+// isBlankLine return true if the line containing i has only white
+// space.
+func (w *runeWindow) isBlankLine(i int64) bool {
+	for j := w.lineStart(i); j < w.size; j++ {
+		if r, _ := w.at(j); r == '\n' || r == '\r' {
+			return true
+		} else if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// graphemeBoundary return true for a grapheme cluster boundary
+// before rune i.
+func (w *runeWindow) graphemeBoundary(i int64) bool {
+	r, ok := w.at(i)
+	if !ok || i <= 0 {
+		return true
+	}
+	return graphemeBoundary(func(k int) (rune, bool) {
+		if k >= textLookaround {
+			return 0, false
+		}
+		return w.at(i - 1 - int64(k))
+	}, r)
+}
+
+// isEscaped return true if rune at i follows an odd no. of
+// backslashes.
+func (w *runeWindow) isEscaped(i int64) bool {
+	n := 0
+	for j := i - 1; j >= i-textLookaround; j-- {
+		if r, ok := w.at(j); !ok || r != '\\' {
+			break
+		}
+		n++
+	}
+	return n%2 == 1
+}
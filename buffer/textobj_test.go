@@ -0,0 +1,208 @@
+package buffer
+
+import "reflect"
+import "strings"
+import "testing"
+
+func TestWords(t *testing.T) {
+	text := "foo.bar(x, y_1)  naïve—été\n\nzz 漢字かなカナ😀😀 한국어abc"
+	testcases := []struct {
+		big  bool
+		refs []string
+	}{
+		{false, []string{
+			"foo", ".", "bar", "(", "x", ",", "y_1", ")", "naïve", "—",
+			"été", "zz", "漢字", "かな", "カナ", "😀😀", "한국어", "abc",
+		}},
+		{true, []string{
+			"foo.bar(x,", "y_1)", "naïve—été", "zz", "漢字かなカナ😀😀",
+			"한국어abc",
+		}},
+	}
+	runes := []rune(text)
+	buffers := []Buffer{NewLinearBuffer([]byte(text)), NewRopebuffer([]byte(text), 8)}
+	for _, buf := range buffers {
+		for _, tcase := range testcases {
+			words := collectObjects(runes, Words(buf, 0, tcase.big))
+			if !reflect.DeepEqual(words, tcase.refs) {
+				t.Fatalf("expected %q, got %q", tcase.refs, words)
+			}
+			words = collectObjects(runes, WordsBackward(buf, buf.Length(), tcase.big))
+			for i, j := 0, len(words)-1; i < j; i, j = i+1, j-1 {
+				words[i], words[j] = words[j], words[i]
+			}
+			if !reflect.DeepEqual(words, tcase.refs) {
+				t.Fatalf("expected %q, got %q", tcase.refs, words)
+			}
+		}
+		// cursor within a word, and in blanks.
+		if loc := Words(buf, 5, false)(); !reflect.DeepEqual(loc, []int64{4, 7}) {
+			t.Fatalf("unexpected %v", loc)
+		} else if loc := WordsBackward(buf, 5, false)(); !reflect.DeepEqual(loc, []int64{4, 7}) {
+			t.Fatalf("unexpected %v", loc)
+		} else if loc := Words(buf, 16, false)(); !reflect.DeepEqual(loc, []int64{17, 22}) {
+			t.Fatalf("unexpected %v", loc)
+		} else if loc := WordsBackward(buf, 16, false)(); !reflect.DeepEqual(loc, []int64{14, 15}) {
+			t.Fatalf("unexpected %v", loc)
+		} else if loc := Words(buf, buf.Length(), false)(); loc != nil {
+			t.Fatalf("unexpected %v", loc)
+		} else if loc := WordsBackward(buf, 0, false)(); loc != nil {
+			t.Fatalf("unexpected %v", loc)
+		}
+	}
+}
+
+func TestSentences(t *testing.T) {
+	text := "Hello there.  How are you? (Fine!) Pi is 3.14 today\n" +
+		"and not\nmore.\n\n  \n「こんにちは。」元気ですか？はい\n\nनमस्ते। अंत"
+	refs := []string{
+		"Hello there.", "How are you?", "(Fine!)", "Pi is 3.14 today\nand not\nmore.",
+		"「こんにちは。」", "元気ですか？", "はい", "नमस्ते।", "अंत",
+	}
+	runes := []rune(text)
+	buffers := []Buffer{NewLinearBuffer([]byte(text)), NewRopebuffer([]byte(text), 8)}
+	for _, buf := range buffers {
+		if x := collectObjects(runes, Sentences(buf, 0)); !reflect.DeepEqual(x, refs) {
+			t.Fatalf("expected %q, got %q", refs, x)
+		}
+		x := collectObjects(runes, SentencesBackward(buf, buf.Length()))
+		for i, j := 0, len(x)-1; i < j; i, j = i+1, j-1 {
+			x[i], x[j] = x[j], x[i]
+		}
+		if !reflect.DeepEqual(x, refs) {
+			t.Fatalf("expected %q, got %q", refs, x)
+		}
+		if x := collectObjects(runes, Sentences(buf, 20)); x[0] != "How are you?" {
+			t.Fatalf("unexpected %q", x[0])
+		} else if x := collectObjects(runes, SentencesBackward(buf, 13)); x[0] != "Hello there." {
+			t.Fatalf("unexpected %q", x[0])
+		}
+	}
+}
+
+func TestParagraphs(t *testing.T) {
+	text := "\none\ntwo\n\n \t\nthree\r\n\r\nfour"
+	refs := []string{"one\ntwo\n", "three\r\n", "four"}
+	runes := []rune(text)
+	buffers := []Buffer{NewLinearBuffer([]byte(text)), NewRopebuffer([]byte(text), 8)}
+	for _, buf := range buffers {
+		if x := collectObjects(runes, Paragraphs(buf, 0)); !reflect.DeepEqual(x, refs) {
+			t.Fatalf("expected %q, got %q", refs, x)
+		} else if x := collectObjects(runes, Paragraphs(buf, 6)); !reflect.DeepEqual(x, refs) {
+			t.Fatalf("expected %q, got %q", refs, x)
+		} else if x := collectObjects(runes, Paragraphs(buf, 10)); !reflect.DeepEqual(x, refs[1:]) {
+			t.Fatalf("expected %q, got %q", refs[1:], x)
+		}
+		x := collectObjects(runes, ParagraphsBackward(buf, buf.Length()))
+		if ref := []string{"four", "three\r\n", "one\ntwo\n"}; !reflect.DeepEqual(x, ref) {
+			t.Fatalf("expected %q, got %q", ref, x)
+		}
+		x = collectObjects(runes, ParagraphsBackward(buf, 10))
+		if ref := []string{"one\ntwo\n"}; !reflect.DeepEqual(x, ref) {
+			t.Fatalf("expected %q, got %q", ref, x)
+		}
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	text := "f(a, (b), g(c(d))) (x\n" + `say "hi \"you\"" and "bye" "x`
+	runes := []rune(text)
+	buffers := []Buffer{NewLinearBuffer([]byte(text)), NewRopebuffer([]byte(text), 8)}
+	for _, buf := range buffers {
+		x := collectObjects(runes, Blocks(buf, 0, '(', ')'))
+		ref := []string{"(a, (b), g(c(d)))", "(b)", "(c(d))", "(d)"}
+		if !reflect.DeepEqual(x, ref) {
+			t.Fatalf("expected %q, got %q", ref, x)
+		}
+		x = collectObjects(runes, Blocks(buf, 6, '(', ')'))
+		if ref := []string{"(c(d))", "(d)"}; !reflect.DeepEqual(x, ref) {
+			t.Fatalf("expected %q, got %q", ref, x)
+		}
+		x = collectObjects(runes, BlocksBackward(buf, buf.Length(), '(', ')'))
+		ref = []string{"(a, (b), g(c(d)))", "(c(d))", "(d)", "(b)"}
+		if !reflect.DeepEqual(x, ref) {
+			t.Fatalf("expected %q, got %q", ref, x)
+		}
+		x = collectObjects(runes, BlocksBackward(buf, 17, '(', ')'))
+		if ref := []string{"(c(d))", "(d)", "(b)"}; !reflect.DeepEqual(x, ref) {
+			t.Fatalf("expected %q, got %q", ref, x)
+		}
+		// quotes.
+		x = collectObjects(runes, Blocks(buf, 0, '"', '"'))
+		if ref := []string{`"hi \"you\""`, `"bye"`}; !reflect.DeepEqual(x, ref) {
+			t.Fatalf("expected %q, got %q", ref, x)
+		}
+		x = collectObjects(runes, BlocksBackward(buf, buf.Length(), '"', '"'))
+		if ref := []string{`"bye"`, `"hi \"you\""`}; !reflect.DeepEqual(x, ref) {
+			t.Fatalf("expected %q, got %q", ref, x)
+		}
+	}
+}
+
+func TestEnclosingBlock(t *testing.T) {
+	text := "f(a, (b), g(c(d))) (x\n" + `say "hi \"you\"" and "bye"`
+	buf := NewRopebuffer([]byte(text), 8)
+	testcases := []struct {
+		start, end  int64
+		open, close rune
+		ref         []int64
+	}{
+		{14, 15, '(', ')', []int64{13, 16}}, // on d
+		{13, 14, '(', ')', []int64{13, 16}}, // on the open bracket
+		{15, 16, '(', ')', []int64{13, 16}}, // on the close bracket
+		{13, 16, '(', ')', []int64{11, 17}}, // block around a block
+		{11, 17, '(', ')', []int64{1, 18}},
+		{1, 18, '(', ')', nil},
+		{8, 10, '(', ')', []int64{1, 18}},
+		{0, 1, '(', ')', nil},
+		{19, 20, '(', ')', nil}, // unbalanced
+		{28, 29, '"', '"', []int64{26, 38}},
+		{26, 38, '"', '"', nil},
+		{40, 41, '"', '"', nil},
+		{44, 45, '"', '"', []int64{43, 48}},
+	}
+	for i, tcase := range testcases {
+		loc := EnclosingBlock(buf, tcase.start, tcase.end, tcase.open, tcase.close)
+		if !reflect.DeepEqual(loc, tcase.ref) {
+			t.Fatalf("%v expected %v, got %v", i, tcase.ref, loc)
+		}
+	}
+}
+
+func TestTextObjectsLong(t *testing.T) {
+	// a single line, many times longer than textWindow.
+	text := strings.Repeat(`say "hi" now. `, 2000)
+	runes := []rune(text)
+	buf := NewRopebuffer([]byte(text), 8)
+	counts := []struct {
+		iter Finder
+		ref  int
+	}{
+		{Words(buf, 0, false), 6 * 2000},
+		{WordsBackward(buf, buf.Length(), false), 6 * 2000},
+		{Sentences(buf, 0), 2000},
+		{SentencesBackward(buf, buf.Length()), 2000},
+		{Paragraphs(buf, 0), 1},
+		{Blocks(buf, 0, '"', '"'), 2000},
+		{BlocksBackward(buf, buf.Length(), '"', '"'), 2000},
+	}
+	for i, tcase := range counts {
+		if x := collectObjects(runes, tcase.iter); len(x) != tcase.ref {
+			t.Fatalf("%v expected %v, got %v", i, tcase.ref, len(x))
+		}
+	}
+	rCur := int64(14*1000 + 5)
+	if loc := Blocks(buf, rCur, '"', '"')(); !reflect.DeepEqual(loc, []int64{rCur - 1, rCur + 3}) {
+		t.Fatalf("unexpected %v", loc)
+	} else if loc := SentencesBackward(buf, rCur)(); !reflect.DeepEqual(loc, []int64{rCur - 5, rCur + 8}) {
+		t.Fatalf("unexpected %v", loc)
+	}
+}
+
+func collectObjects(runes []rune, iter Finder) []string {
+	objects := []string{}
+	for loc := iter(); loc != nil; loc = iter() {
+		objects = append(objects, string(runes[loc[0]:loc[1]]))
+	}
+	return objects
+}
@@ -1,10 +1,77 @@
 package buffer
 
+import "unicode"
 import "unicode/utf8"
 import "fmt"
 
 var _ = fmt.Sprintf("dummy")
 
+// RawRune return the rune representing invalid utf8 byte b.
+func RawRune(b byte) rune {
+	return RuneRawBase + rune(b)
+}
+
+// RawByte return the invalid utf8 byte represented by rune r, ok
+// is false if r is not from the reserved range.
+func RawByte(r rune) (b byte, ok bool) {
+	if r >= RuneRawBase+0x80 && r <= RuneRawBase+0xff {
+		return byte(r - RuneRawBase), true
+	}
+	return 0, false
+}
+
+// decode first rune in bs, an invalid byte is decoded as
+// raw-rune of size 1.
+func decodeRune(bs []byte) (rune, int) {
+	r, size := utf8.DecodeRune(bs)
+	if r == utf8.RuneError && size == 1 {
+		return RawRune(bs[0]), 1
+	}
+	return r, size
+}
+
+// decode last rune in bs, an invalid byte is decoded as
+// raw-rune of size 1.
+func decodeLastRune(bs []byte) (rune, int) {
+	r, size := utf8.DecodeLastRune(bs)
+	if r == utf8.RuneError && size == 1 {
+		return RawRune(bs[len(bs)-1]), 1
+	}
+	return r, size
+}
+
+// encode rune r into bs, raw-rune is encoded as the invalid
+// byte it represents.
+func encodeRune(bs []byte, r rune) int {
+	if b, ok := RawByte(r); ok {
+		bs[0] = b
+		return 1
+	}
+	return utf8.EncodeRune(bs, r)
+}
+
+// no. of bytes required to encode rune r.
+func runeLen(r rune) int {
+	if _, ok := RawByte(r); ok {
+		return 1
+	}
+	if n := utf8.RuneLen(r); n > 0 {
+		return n
+	}
+	return 3 // encoded as utf8.RuneError
+}
+
+// alignRune return the first rune boundary in bs at or after off.
+// A byte that is not a utf8 continuation byte is a boundary, and
+// so is the byte after three continuation bytes, which cannot be
+// part of a valid rune.
+func alignRune(bs []byte, off int64) int64 {
+	end := off + utf8.UTFMax - 1
+	for ; off < int64(len(bs)) && off < end && !utf8.RuneStart(bs[off]); off++ {
+	}
+	return off
+}
+
 // figure out unicode boundary within `text`,
 // if `reverse` is true, figure out the unicode
 // boundary from backward.
@@ -29,15 +96,13 @@ func getRuneStart(text []byte, reverse bool) (int64, error) {
 }
 
 // decode array of utf8 encoded bytes to unicode runes,
+// invalid bytes are decoded as raw-runes,
 // stop decoding once `rn` number of runes are decoded.
 // return the number of runes decoded, bytes consumed.
 func bytes2NRunes(bs []byte, rn int64, acc []rune) (int64, int64, error) {
 	size, count := int64(0), int64(0)
 	for size < int64(len(bs)) {
-		r, sz := utf8.DecodeRune(bs[size:])
-		if r == utf8.RuneError {
-			return 0, 0, ErrorInvalidEncoding
-		}
+		r, sz := decodeRune(bs[size:])
 		acc[count] = r
 		size, count = size+int64(sz), count+1
 		if count >= rn {
@@ -47,40 +112,83 @@ func bytes2NRunes(bs []byte, rn int64, acc []rune) (int64, int64, error) {
 	return count, size, nil
 }
 
-// decode utf8 encoded bytes to unicode runes.
+// decode utf8 encoded bytes to unicode runes, invalid bytes
+// are decoded as raw-runes.
 func bytes2Runes(bs []byte) []rune {
 	runes := make([]rune, len(bs))
 	i, n := 0, 0
 	for ; i < len(bs); n++ {
-		r, sz := utf8.DecodeRune(bs[i:])
-		if r == utf8.RuneError {
-			panic(ErrorInvalidEncoding)
-		}
+		r, sz := decodeRune(bs[i:])
 		runes[n] = r
 		i += sz
 	}
 	return runes[:n]
 }
 
-// encode unicode runes to utf8 encoded bytes.
+// encode unicode runes to utf8 encoded bytes, raw-runes are
+// encoded back to the invalid bytes.
 func runes2Bytes(rs []rune) []byte {
 	bytes := make([]byte, len(rs)*4)
 	off := 0
 	for _, r := range rs {
-		sz := utf8.EncodeRune(bytes[off:], r)
+		sz := encodeRune(bytes[off:], r)
 		off += sz
 	}
 	return bytes[:off]
 }
 
+// runes2Segments encode rs like runes2Bytes, cutting a new segment
+// wherever raw-runes would otherwise make up a valid rune.
+func runes2Segments(rs []rune) [][]byte {
+	bytes, segs := make([]byte, len(rs)*4), make([][]byte, 0, 1)
+	start, off := 0, 0
+	for _, r := range rs {
+		sz := encodeRune(bytes[off:], r)
+		if _, ok := RawByte(r); ok && !joinable(bytes[start:off], bytes[off:off+sz]) {
+			segs, start = append(segs, bytes[start:off:off]), off
+		}
+		off += sz
+	}
+	return append(segs, bytes[start:off:off])
+}
+
+// joinable check whether left and right decode to the same runes
+// when joined, which is not the case when invalid bytes at the end
+// of left and stray continuation bytes at the start of right make
+// up a valid rune.
+func joinable(left, right []byte) bool {
+	if len(left) == 0 || len(right) == 0 || utf8.RuneStart(right[0]) {
+		return true
+	}
+	if n := len(left) - (utf8.UTFMax - 1); n > 0 {
+		left = left[n:]
+	}
+	if n := utf8.UTFMax - 1; len(right) > n {
+		right = right[:n]
+	}
+	var seam [2 * (utf8.UTFMax - 1)]byte
+	n := copy(seam[:], left)
+	n += copy(seam[n:], right)
+	rn := utf8.RuneCount(left) + utf8.RuneCount(right)
+	return rn == utf8.RuneCount(seam[:n])
+}
+
+// return byte offset in utf8 encoded bytes, after skipping `rn`
+// number of runes.
+func runeOffset(bs []byte, rn int64) int64 {
+	off := int64(0)
+	for ; rn > 0; rn-- {
+		_, size := utf8.DecodeRune(bs[off:])
+		off += int64(size)
+	}
+	return off
+}
+
 func runePositions(bs []byte) []int64 {
 	offs := make([]int64, len(bs))
 	i, n := 0, 0
 	for ; i < len(bs); n++ {
-		r, sz := utf8.DecodeRune(bs[i:])
-		if r == utf8.RuneError {
-			panic(utf8.RuneError)
-		}
+		_, sz := utf8.DecodeRune(bs[i:])
 		offs[n] = int64(i)
 		i += sz
 	}
@@ -94,3 +202,9 @@ func reverseRunes(runes []rune) []rune {
 	}
 	return reversed
 }
+
+// isWordRune return true for letters, digits and underscore, the
+// runes that make up a word.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -2,10 +2,24 @@ package buffer
 
 import "testing"
 import "fmt"
+import "io"
+import "log"
+import "io/ioutil"
+import "math/rand"
 import "unicode/utf8"
 
 var _ = fmt.Sprintf("dummy")
 
+var sampleData []byte // contains 2.5MB data.
+
+func init() {
+	var err error
+	sampleData, err = ioutil.ReadFile("../tools/monstrun/sample.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
 var testChinese = `左司馬販（《春秋左傳·哀公四年》 #2.2）
 當為左司馬「眅」，音「攀」上聲。 
 並非「販賣」之「販」`
@@ -99,6 +113,45 @@ func TestReverseRunes(t *testing.T) {
 	}
 }
 
+func TestRawRunes(t *testing.T) {
+	bs := make([]byte, 64*1024)
+	rand.Read(bs)
+	runes := bytes2Runes(bs)
+	if x, y := utf8.RuneCount(bs), len(runes); x != y {
+		t.Fatalf("expected %v, got %v", x, y)
+	} else if x := runes2Bytes(runes); string(x) != string(bs) {
+		t.Fatalf("mismatch in round trip")
+	}
+	for _, r := range runes {
+		if x, ok := RawByte(r); ok && x < 0x80 {
+			t.Fatalf("unexpected raw byte %x", x)
+		}
+	}
+	// backward decoding agrees with forward decoding.
+	for off, i := len(bs), len(runes)-1; off > 0; i-- {
+		r, size := decodeLastRune(bs[:off])
+		if r != runes[i] {
+			t.Fatalf("at %v expected %q, got %q", off, runes[i], r)
+		}
+		off -= size
+	}
+	// rune boundaries are stable on either side of alignRune.
+	for i := 0; i < 1000; i++ {
+		off := alignRune(bs, rand.Int63n(int64(len(bs))))
+		left, right := bytes2Runes(bs[:off]), bytes2Runes(bs[off:])
+		if x := len(left) + len(right); x != len(runes) {
+			t.Fatalf("at %v expected %v runes, got %v", off, len(runes), x)
+		}
+	}
+	if x := runes2Bytes([]rune{RawRune(0xff), 'a'}); string(x) != "\xffa" {
+		t.Fatalf("expected %q, got %q", "\xffa", x)
+	} else if x := runes2Bytes([]rune{0xd800}); string(x) != "\uFFFD" {
+		t.Fatalf("expected %q, got %q", "\uFFFD", x)
+	} else if x := runeLen(RawRune(0x80)); x != 1 {
+		t.Fatalf("expected %v, got %v", 1, x)
+	}
+}
+
 func BenchmarkGetRuneStart(b *testing.B) {
 	bytes := []byte(testChinese)
 	for j := 0; j < b.N; j++ {
@@ -155,3 +208,15 @@ func BenchmarkReverseRunes(b *testing.B) {
 	}
 	b.SetBytes(int64(len(testChinese)))
 }
+
+func readAllRunes(reader RuneReader) ([]rune, int) {
+	runes, total := make([]rune, 0), 0
+	for {
+		r, size, err := reader.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		runes, total = append(runes, r), total+size
+	}
+	return runes, total
+}
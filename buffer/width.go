@@ -0,0 +1,149 @@
+package buffer
+
+import "unicode"
+import "fmt"
+
+import "golang.org/x/text/width"
+
+var _ = fmt.Sprintf("dummy")
+
+// AmbiguousWidth is the number of terminal columns taken by
+// East Asian Ambiguous characters, set to 2 for terminals using
+// CJK legacy fonts.
+var AmbiguousWidth = 1
+
+// DefaultTabstop is the number of columns between tab-stops.
+const DefaultTabstop = int64(8)
+
+// RuneWidth return the number of terminal columns taken by rune r,
+// zero for control characters, combining marks and format
+// characters, two for East Asian Wide and Fullwidth characters.
+// Note that East Asian Width comes from golang.org/x/text/width,
+// refer width.UnicodeVersion, and general categories from package
+// unicode, refer unicode.Version, both of which may lag behind
+// GraphemeUnicodeVersion. Runes assigned in later versions are
+// treated as narrow.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case r < 0x300: // fast path for latin.
+		if r == 0xad { // soft hyphen
+			return 0
+		}
+		return runeEastAsianWidth(r)
+	}
+	if _, ok := RawByte(r); ok { // displayed as replacement character.
+		return 1
+	}
+	switch graphemePropOf(r) {
+	case gbExtend, gbZWJ, gbV, gbT, gbControl:
+		return 0
+	}
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	return runeEastAsianWidth(r)
+}
+
+// GraphemeWidth return the number of terminal columns taken by a
+// grapheme cluster, which is the width of its first spacing rune,
+// widened to 2 by emoji presentation selector or for a pair of
+// regional indicators, and narrowed to 1 by text presentation
+// selector.
+func GraphemeWidth(cluster []rune) int {
+	w := 0
+	for i, r := range cluster {
+		switch {
+		case r == 0xfe0f && w == 1: // VS16, emoji presentation
+			w = 2
+		case r == 0xfe0e && w == 2: // VS15, text presentation
+			w = 1
+		case w == 0:
+			w = RuneWidth(r)
+		case i == 1 && graphemePropOf(r) == gbRegional:
+			w = 2
+		}
+	}
+	return w
+}
+
+//----------------
+// buffer columns
+//----------------
+
+// RuneToColumn return the visual column for cursor rCur within
+// its line, counting from 0, where tabs are expanded to the next
+// multiple of tabstop. If rCur falls inside a grapheme cluster,
+// column of the cluster is returned. Return -1 if rCur is invalid.
+func RuneToColumn(buffer Buffer, rCur, tabstop int64) int64 {
+	start := LineStart(buffer, rCur)
+	if start < 0 {
+		return -1
+	}
+	reader := buffer.StreamFrom(start)
+	defer reader.Close()
+	gr := NewGraphemeReader(reader, false)
+
+	col := int64(0)
+	for pos := start; pos < rCur; {
+		cluster, err := gr.ReadGrapheme()
+		if err != nil {
+			break
+		} else if pos+int64(len(cluster)) > rCur {
+			break
+		}
+		col += clusterColumns(cluster, col, tabstop)
+		pos += int64(len(cluster))
+	}
+	return col
+}
+
+// ColumnToRune return the cursor of grapheme cluster occupying
+// visual column within the line containing rCur, where tabs are
+// expanded to the next multiple of tabstop. If column falls
+// beyond the end of line, cursor at the end of line is returned.
+// Return -1 if rCur is invalid.
+func ColumnToRune(buffer Buffer, rCur, column, tabstop int64) int64 {
+	start := LineStart(buffer, rCur)
+	if start < 0 {
+		return -1
+	}
+	reader := buffer.StreamFrom(start)
+	defer reader.Close()
+	gr := NewGraphemeReader(reader, false)
+
+	pos, col := start, int64(0)
+	for {
+		cluster, err := gr.ReadGrapheme()
+		if err != nil || cluster[0] == '\r' || cluster[0] == '\n' {
+			return pos
+		}
+		col += clusterColumns(cluster, col, tabstop)
+		if col > column {
+			return pos
+		}
+		pos += int64(len(cluster))
+	}
+}
+
+// clusterColumns return the columns taken by cluster at col.
+func clusterColumns(cluster []rune, col, tabstop int64) int64 {
+	if cluster[0] == '\t' {
+		if tabstop <= 0 {
+			tabstop = DefaultTabstop
+		}
+		return tabstop - (col % tabstop)
+	}
+	return int64(GraphemeWidth(cluster))
+}
+
+func runeEastAsianWidth(r rune) int {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	case width.EastAsianAmbiguous:
+		return AmbiguousWidth
+	}
+	return 1
+}
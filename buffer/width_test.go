@@ -0,0 +1,117 @@
+package buffer
+
+import "testing"
+
+func TestRuneWidth(t *testing.T) {
+	testcases := []struct {
+		r rune
+		w int
+	}{
+		{'a', 1}, {' ', 1}, {'\t', 0}, {'\n', 0}, {0x7f, 0}, {0xad, 0},
+		{'é', 1}, {0x0301, 0}, {0x200d, 0}, {0x200b, 0}, {0xfe0f, 0},
+		{'左', 2}, {'（', 2}, {'｡', 1}, {'한', 2}, {0x1161, 0},
+		{0x1f600, 2}, {0x1f1ee, 1}, {0x2764, 1}, {0x00b7, 1},
+	}
+	for _, tcase := range testcases {
+		if x := RuneWidth(tcase.r); x != tcase.w {
+			t.Fatalf("%q expected %v, got %v", tcase.r, tcase.w, x)
+		}
+	}
+
+	defer func(w int) { AmbiguousWidth = w }(AmbiguousWidth)
+	AmbiguousWidth = 2
+	if x := RuneWidth(0x00b7); x != 2 {
+		t.Fatalf("expected %v, got %v", 2, x)
+	}
+}
+
+func TestGraphemeWidth(t *testing.T) {
+	testcases := []struct {
+		text string
+		w    int
+	}{
+		{"e\u0301", 1},
+		{"\u1100\u1161\u11a8", 2},
+		{"\U0001F468\u200d\U0001F469\u200d\U0001F467", 2},
+		{"\U0001F1EE\U0001F1F3", 2},
+		{"\u2764\ufe0f", 2},
+		{"\U0001F600\ufe0e", 1},
+		{"\u0301", 0},
+		{"", 0},
+	}
+	for _, tcase := range testcases {
+		if x := GraphemeWidth([]rune(tcase.text)); x != tcase.w {
+			t.Fatalf("%q expected %v, got %v", tcase.text, tcase.w, x)
+		}
+	}
+}
+
+func TestColumns(t *testing.T) {
+	text := "ab\tc\n" + // tab expands to column 8
+		"左司馬販·x\n" + // wide and ambiguous
+		"e\u0301\U0001F468\u200d\U0001F469\tz" // combining, zwj and tab
+	rb := NewRopebuffer([]byte(text), 8)
+	testcases := []struct {
+		rCur, col int64
+	}{
+		{0, 0}, {2, 2}, {3, 8}, {4, 9}, {5, 0},
+		{6, 2}, {9, 8}, {10, 9}, {11, 10}, {12, 0},
+		{13, 0}, {14, 1}, {15, 1}, {16, 1}, {17, 3}, {18, 8}, {19, 9},
+	}
+	for _, tcase := range testcases {
+		if x := RuneToColumn(rb, tcase.rCur, 8); x != tcase.col {
+			t.Fatalf("rune %v expected %v, got %v", tcase.rCur, tcase.col, x)
+		}
+	}
+	if x := RuneToColumn(rb, -1, 8); x != -1 {
+		t.Fatalf("expected %v, got %v", -1, x)
+	}
+
+	testcases = []struct {
+		rCur, col int64
+	}{
+		{0, 0}, {2, 2}, {2, 7}, {3, 8}, {4, 9}, {4, 100},
+		{5, 0}, {5, 1}, {6, 2}, {6, 3}, {9, 8}, {10, 9}, {11, 10},
+		{12, 0}, {14, 1}, {14, 2}, {17, 3}, {17, 7}, {18, 8}, {19, 100},
+	}
+	lines := []int64{0, 0, 0, 0, 0, 0, 5, 5, 5, 5, 5, 5, 5, 12, 12, 12, 12, 12, 12, 12}
+	for i, tcase := range testcases {
+		if x := ColumnToRune(rb, lines[i], tcase.col, 8); x != tcase.rCur {
+			t.Fatalf("column %v expected %v, got %v", tcase.col, tcase.rCur, x)
+		}
+	}
+	// round trip with a different tabstop.
+	for rCur := int64(0); rCur <= rb.Length(); rCur = NextGrapheme(rb, rCur) {
+		col := RuneToColumn(rb, rCur, 4)
+		if x := ColumnToRune(rb, rCur, col, 4); x != rCur {
+			t.Fatalf("rune %v column %v, got %v", rCur, col, x)
+		}
+		if rCur == rb.Length() {
+			break
+		}
+	}
+}
+
+func TestColumnsChinese(t *testing.T) {
+	rb := NewRopebuffer([]byte(testChinese), 16)
+	ref := []rune(testChinese)
+	col := int64(0)
+	for rCur, r := range ref {
+		if x := RuneToColumn(rb, int64(rCur), DefaultTabstop); x != col {
+			t.Fatalf("rune %v expected %v, got %v", rCur, col, x)
+		}
+		col += int64(RuneWidth(r))
+		if r == '\n' {
+			col = 0
+		}
+	}
+}
+
+func BenchmarkRuneToColumn(b *testing.B) {
+	rb := NewRopebuffer(sampleData, testRopeBufferCapacity)
+	l := rb.Length()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		RuneToColumn(rb, int64(i*1009)%l, DefaultTabstop)
+	}
+}
@@ -0,0 +1,235 @@
+package buffer
+
+import "unicode"
+import "fmt"
+
+var _ = fmt.Sprintf("dummy")
+
+//go:generate go run ../tools/ucdtables -table word -out word_tables.go
+
+// wordProp is Word_Break property of a code point, as defined by
+// UAX #29.
+type wordProp byte
+
+const (
+	wbAny wordProp = iota
+	wbCR
+	wbLF
+	wbNewline
+	wbExtend
+	wbZWJ
+	wbRegional
+	wbFormat
+	wbKatakana
+	wbHebrewLetter
+	wbALetter
+	wbSingleQuote
+	wbDoubleQuote
+	wbMidNumLet
+	wbMidLetter
+	wbMidNum
+	wbNumeric
+	wbExtendNumLet
+	wbWSegSpace
+)
+
+type wordRange struct {
+	lo, hi rune
+	prop   wordProp
+}
+
+// UnicodeWords return an iterator over successive words in buffer,
+// going forward from rCur, as segmented by UAX #29 word boundaries.
+// Each word is a pair of rune offsets {start, end}, the first one
+// being the word containing rCur, if any. Segments without letters
+// or numbers, like spaces and punctuation, are skipped.
+func UnicodeWords(buffer Buffer, rCur int64) Finder {
+	return textObjects(buffer, rCur, false, unicodeWords)
+}
+
+// UnicodeWordsBackward is like UnicodeWords, going backward from
+// rCur, the first one being the word containing rCur-1, if any.
+func UnicodeWordsBackward(buffer Buffer, rCur int64) Finder {
+	return textObjects(buffer, rCur, true, unicodeWords)
+}
+
+// unicodeWords are segments with letters or numbers.
+var unicodeWords = textSegment{
+	boundary: func(w *runeWindow, i int64) bool {
+		return wordBoundary(func(j int64) (rune, bool) {
+			if j < i-textLookaround || j > i+textLookaround {
+				return 0, false
+			}
+			return w.at(j)
+		}, i)
+	},
+	object: func(w *runeWindow, start, end int64) (int64, int64, bool) {
+		for j := start; j < end; j++ {
+			if r, _ := w.at(j); unicode.IsLetter(r) || unicode.IsNumber(r) {
+				return start, end, true
+			}
+		}
+		return start, end, false
+	},
+}
+
+// wordBoundaries return the offsets of word boundaries in runes,
+// including 0 and len(runes).
+func wordBoundaries(runes []rune) []int {
+	at := func(i int64) (rune, bool) {
+		if i < 0 || i >= int64(len(runes)) {
+			return 0, false
+		}
+		return runes[i], true
+	}
+	boundaries := []int{0}
+	for i := 1; i < len(runes); i++ {
+		if wordBoundary(at, int64(i)) {
+			boundaries = append(boundaries, i)
+		}
+	}
+	if len(runes) > 0 {
+		boundaries = append(boundaries, len(runes))
+	}
+	return boundaries
+}
+
+// wordBoundary apply UAX #29 rules to check for boundary between
+// runes i-1 and i, at return the rune at an offset and false
+// outside the text.
+func wordBoundary(at func(i int64) (rune, bool), i int64) bool {
+	props := func(j int64) (wordProp, bool) {
+		r, ok := at(j)
+		if !ok {
+			return wbAny, false
+		}
+		return wordPropOf(r), true
+	}
+	left, _ := at(i - 1)
+	right, _ := at(i)
+	lp, rp := wordPropOf(left), wordPropOf(right)
+	switch {
+	case lp == wbCR && rp == wbLF: // WB3
+		return false
+	case lp == wbCR || lp == wbLF || lp == wbNewline: // WB3a
+		return true
+	case rp == wbCR || rp == wbLF || rp == wbNewline: // WB3b
+		return true
+	case lp == wbZWJ && graphemePropOf(right) == gbPictographic:
+		return false // WB3c
+	case lp == wbWSegSpace && rp == wbWSegSpace: // WB3d
+		return false
+	case wbIgnored(rp): // WB4
+		return false
+	}
+
+	// rules after WB4 skip Extend, Format and ZWJ.
+	j := wordLeft(props, i)
+	l, _ := props(j)
+	ll, _ := props(wordLeft(props, j))
+	r, rr := rp, wbAny
+	if k := wordRight(props, i); k >= 0 {
+		rr, _ = props(k)
+	}
+	switch {
+	case wbAHLetter(l) && wbAHLetter(r): // WB5
+		return false
+	case wbAHLetter(l) && wbMidLetterQ(r) && wbAHLetter(rr): // WB6
+		return false
+	case wbAHLetter(ll) && wbMidLetterQ(l) && wbAHLetter(r): // WB7
+		return false
+	case l == wbHebrewLetter && r == wbSingleQuote: // WB7a
+		return false
+	case l == wbHebrewLetter && r == wbDoubleQuote && rr == wbHebrewLetter:
+		return false // WB7b
+	case ll == wbHebrewLetter && l == wbDoubleQuote && r == wbHebrewLetter:
+		return false // WB7c
+	case l == wbNumeric && r == wbNumeric: // WB8
+		return false
+	case wbAHLetter(l) && r == wbNumeric: // WB9
+		return false
+	case l == wbNumeric && wbAHLetter(r): // WB10
+		return false
+	case ll == wbNumeric && wbMidNumQ(l) && r == wbNumeric: // WB11
+		return false
+	case l == wbNumeric && wbMidNumQ(r) && rr == wbNumeric: // WB12
+		return false
+	case l == wbKatakana && r == wbKatakana: // WB13
+		return false
+	case r == wbExtendNumLet && (wbAHLetter(l) || l == wbNumeric ||
+		l == wbKatakana || l == wbExtendNumLet): // WB13a
+		return false
+	case l == wbExtendNumLet && (wbAHLetter(r) || r == wbNumeric ||
+		r == wbKatakana): // WB13b
+		return false
+	case l == wbRegional && r == wbRegional: // WB15, WB16
+		n := 0 // count regional indicators before the boundary.
+		for k := j; ; k = wordLeft(props, k) {
+			if p, ok := props(k); !ok || p != wbRegional {
+				break
+			}
+			n++
+		}
+		return n%2 == 0
+	}
+	return true // WB999
+}
+
+// wordLeft return the offset of rune before i, skipping Extend,
+// Format and ZWJ that attach to it.
+func wordLeft(props func(int64) (wordProp, bool), i int64) int64 {
+	j := i - 1
+	for {
+		p, _ := props(j)
+		pp, ok := props(j - 1)
+		if !ok || !wbIgnored(p) {
+			return j
+		} else if pp == wbCR || pp == wbLF || pp == wbNewline {
+			return j
+		}
+		j--
+	}
+}
+
+// wordRight return the offset of rune after i, skipping Extend,
+// Format and ZWJ, -1 at the end of text.
+func wordRight(props func(int64) (wordProp, bool), i int64) int64 {
+	for k := i + 1; ; k++ {
+		if p, ok := props(k); !ok {
+			return -1
+		} else if !wbIgnored(p) {
+			return k
+		}
+	}
+}
+
+func wbIgnored(p wordProp) bool {
+	return p == wbExtend || p == wbFormat || p == wbZWJ
+}
+
+func wbAHLetter(p wordProp) bool {
+	return p == wbALetter || p == wbHebrewLetter
+}
+
+func wbMidLetterQ(p wordProp) bool {
+	return p == wbMidLetter || p == wbMidNumLet || p == wbSingleQuote
+}
+
+func wbMidNumQ(p wordProp) bool {
+	return p == wbMidNum || p == wbMidNumLet || p == wbSingleQuote
+}
+
+func wordPropOf(r rune) wordProp {
+	lo, hi := 0, len(wordTable)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if rng := wordTable[mid]; r < rng.lo {
+			hi = mid
+		} else if r > rng.hi {
+			lo = mid + 1
+		} else {
+			return rng.prop
+		}
+	}
+	return wbAny
+}
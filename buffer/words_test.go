@@ -0,0 +1,74 @@
+package buffer
+
+import "testing"
+
+func TestWordBreakTest(t *testing.T) {
+	tests := loadGraphemeBreakTest(t, "testdata/WordBreakTest.txt")
+	if len(tests) < 1000 {
+		t.Fatalf("expected atleast 1000 test cases, got %v", len(tests))
+	}
+	for _, segments := range tests {
+		text, refs := []rune{}, []int{0}
+		for _, segment := range segments {
+			text = append(text, segment...)
+			refs = append(refs, len(text))
+		}
+		boundaries := wordBoundaries(text)
+		if len(boundaries) != len(refs) {
+			t.Fatalf("%q: expected %v, got %v", string(text), refs, boundaries)
+		}
+		for i := range refs {
+			if boundaries[i] != refs[i] {
+				t.Fatalf("%q: expected %v, got %v", string(text), refs, boundaries)
+			}
+		}
+	}
+}
+
+func TestUnicodeWords(t *testing.T) {
+	text := "The quick (“brown”) fox can’t jump 32.3 feet, right?\n" +
+		"日本語のテキスト Привет, мир! 3.14\r\nनमस्ते दुनिया"
+	refs := []string{
+		"The", "quick", "brown", "fox", "can’t", "jump", "32.3", "feet",
+		"right", "日", "本", "語", "の", "テキスト", "Привет", "мир", "3.14",
+		"नमस्ते", "दुनिया",
+	}
+	runes := []rune(text)
+	for _, buf := range []Buffer{NewLinearBuffer([]byte(text)), NewRopebuffer([]byte(text), 8)} {
+		words := []string{}
+		for iter := UnicodeWords(buf, 0); ; {
+			loc := iter()
+			if loc == nil {
+				break
+			}
+			words = append(words, string(runes[loc[0]:loc[1]]))
+		}
+		if len(words) != len(refs) {
+			t.Fatalf("expected %q, got %q", refs, words)
+		}
+		for i, ref := range refs {
+			if words[i] != ref {
+				t.Fatalf("expected %q, got %q", ref, words[i])
+			}
+		}
+		// backward from the end.
+		iter := UnicodeWordsBackward(buf, buf.Length())
+		for i := len(refs) - 1; i >= 0; i-- {
+			loc := iter()
+			if loc == nil {
+				t.Fatalf("expected %q", refs[i])
+			} else if x := string(runes[loc[0]:loc[1]]); x != refs[i] {
+				t.Fatalf("expected %q, got %q", refs[i], x)
+			}
+		}
+		if loc := iter(); loc != nil {
+			t.Fatalf("unexpected %v", loc)
+		}
+		// first word contains the cursor.
+		if loc := UnicodeWords(buf, 6)(); loc[0] != 4 || loc[1] != 9 {
+			t.Fatalf("unexpected %v", loc)
+		} else if loc := UnicodeWordsBackward(buf, 6)(); loc[0] != 4 || loc[1] != 9 {
+			t.Fatalf("unexpected %v", loc)
+		}
+	}
+}
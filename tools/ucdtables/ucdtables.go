@@ -0,0 +1,279 @@
+// ucdtables generate the grapheme and word break tables in package
+// buffer from unicode character database, refer go:generate lines
+// in buffer/grapheme.go and buffer/words.go.
+package main
+
+import "bufio"
+import "bytes"
+import "flag"
+import "fmt"
+import "go/format"
+import "io"
+import "io/ioutil"
+import "log"
+import "net/http"
+import "os"
+import "path"
+import "sort"
+import "strconv"
+import "strings"
+
+var options struct {
+	table   string
+	version string
+	ucd     string
+	out     string
+}
+
+func argParse() {
+	flag.StringVar(&options.table, "table", "",
+		"table to generate, grapheme or word")
+	flag.StringVar(&options.version, "version", "16.0.0",
+		"version of unicode standard")
+	flag.StringVar(&options.ucd, "ucd", "",
+		"url or directory of unicode character database, "+
+			"default https://www.unicode.org/Public/<version>/ucd")
+	flag.StringVar(&options.out, "out", "",
+		"file to write the generated table, default stdout")
+	flag.Parse()
+
+	if options.ucd == "" {
+		options.ucd = "https://www.unicode.org/Public/" + options.version + "/ucd"
+	}
+}
+
+func main() {
+	argParse()
+	var src []byte
+	switch options.table {
+	case "grapheme":
+		src = graphemeTables()
+	case "word":
+		src = wordTables()
+	default:
+		log.Fatalf("unknown table %q", options.table)
+	}
+	src, err := format.Source(src)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if options.out == "" {
+		os.Stdout.Write(src)
+	} else if err := ioutil.WriteFile(options.out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// grapheme property names, as in GraphemeBreakProperty.txt, and
+// their constants in package buffer.
+var graphemeProps = map[string]string{
+	"CR":                 "gbCR",
+	"LF":                 "gbLF",
+	"Control":            "gbControl",
+	"Extend":             "gbExtend",
+	"ZWJ":                "gbZWJ",
+	"Regional_Indicator": "gbRegional",
+	"Prepend":            "gbPrepend",
+	"SpacingMark":        "gbSpacingMark",
+	"L":                  "gbL",
+	"V":                  "gbV",
+	"T":                  "gbT",
+	"LV":                 "gbLV",
+	"LVT":                "gbLVT",
+}
+
+// word property names, as in WordBreakProperty.txt, and their
+// constants in package buffer.
+var wordProps = map[string]string{
+	"CR":                 "wbCR",
+	"LF":                 "wbLF",
+	"Newline":            "wbNewline",
+	"Extend":             "wbExtend",
+	"ZWJ":                "wbZWJ",
+	"Regional_Indicator": "wbRegional",
+	"Format":             "wbFormat",
+	"Katakana":           "wbKatakana",
+	"Hebrew_Letter":      "wbHebrewLetter",
+	"ALetter":            "wbALetter",
+	"Single_Quote":       "wbSingleQuote",
+	"Double_Quote":       "wbDoubleQuote",
+	"MidNumLet":          "wbMidNumLet",
+	"MidLetter":          "wbMidLetter",
+	"MidNum":             "wbMidNum",
+	"Numeric":            "wbNumeric",
+	"ExtendNumLet":       "wbExtendNumLet",
+	"WSegSpace":          "wbWSegSpace",
+}
+
+// graphemeTables fold InCB=Consonant and Extended_Pictographic into
+// Grapheme_Cluster_Break property, for code points that have none.
+func graphemeTables() []byte {
+	props := make(map[rune]string)
+	incbExtend := make(map[rune]string)
+	parse("auxiliary/GraphemeBreakProperty.txt", func(lo, hi rune, fields []string) {
+		name, ok := graphemeProps[fields[0]]
+		if !ok {
+			log.Fatalf("unknown grapheme property %q", fields[0])
+		}
+		setRange(props, lo, hi, name)
+	})
+	parse("DerivedCoreProperties.txt", func(lo, hi rune, fields []string) {
+		if len(fields) < 2 || fields[0] != "InCB" {
+			return
+		}
+		for r := lo; r <= hi; r++ {
+			switch fields[1] {
+			case "Consonant":
+				if _, ok := props[r]; !ok {
+					props[r] = "gbConsonant"
+				}
+			case "Extend":
+				incbExtend[r] = "gbExtend"
+			}
+		}
+	})
+	parse("emoji/emoji-data.txt", func(lo, hi rune, fields []string) {
+		if fields[0] != "Extended_Pictographic" {
+			return
+		}
+		for r := lo; r <= hi; r++ {
+			if _, ok := props[r]; !ok {
+				props[r] = "gbPictographic"
+			}
+		}
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by tools/ucdtables from Unicode %v UCD. DO NOT EDIT.\n\n", options.version)
+	fmt.Fprintf(&buf, "package buffer\n\n")
+	fmt.Fprintf(&buf, "// GraphemeUnicodeVersion is the version of unicode standard used\n")
+	fmt.Fprintf(&buf, "// for grapheme cluster segmentation.\n")
+	fmt.Fprintf(&buf, "const GraphemeUnicodeVersion = %q\n\n", options.version)
+	fmt.Fprintf(&buf, "// graphemeTable sorted list of code-point ranges and their\n")
+	fmt.Fprintf(&buf, "// Grapheme_Cluster_Break property, InCB=Consonant and\n")
+	fmt.Fprintf(&buf, "// Extended_Pictographic are folded in as properties of their own.\n")
+	fmt.Fprintf(&buf, "// Code points not listed are gbAny.\n")
+	writeTable(&buf, "graphemeTable", "graphemeRange", props)
+	fmt.Fprintf(&buf, "\n// incbExtendTable sorted list of code-point ranges with\n")
+	fmt.Fprintf(&buf, "// Indic_Conjunct_Break=Extend property.\n")
+	writeTable(&buf, "incbExtendTable", "graphemeRange", incbExtend)
+	return buf.Bytes()
+}
+
+func wordTables() []byte {
+	props := make(map[rune]string)
+	parse("auxiliary/WordBreakProperty.txt", func(lo, hi rune, fields []string) {
+		name, ok := wordProps[fields[0]]
+		if !ok {
+			log.Fatalf("unknown word property %q", fields[0])
+		}
+		setRange(props, lo, hi, name)
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by tools/ucdtables from Unicode %v UCD. DO NOT EDIT.\n\n", options.version)
+	fmt.Fprintf(&buf, "package buffer\n\n")
+	fmt.Fprintf(&buf, "// wordTable sorted list of code-point ranges and their Word_Break\n")
+	fmt.Fprintf(&buf, "// property. Code points not listed are wbAny.\n")
+	writeTable(&buf, "wordTable", "wordRange", props)
+	return buf.Bytes()
+}
+
+// parse ucd file, calling fn for every code-point range with the
+// rest of fields in the line.
+func parse(filename string, fn func(lo, hi rune, fields []string)) {
+	rd := open(filename)
+	defer rd.Close()
+
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if len(fields) < 2 {
+			log.Fatalf("%v: malformed line %q", filename, line)
+		}
+		lo, hi := parseRange(filename, fields[0])
+		fn(lo, hi, fields[1:])
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("%v: %v", filename, err)
+	}
+}
+
+// parseRange of the form XXXX or XXXX..YYYY.
+func parseRange(filename, s string) (lo, hi rune) {
+	parts := strings.SplitN(s, "..", 2)
+	x, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		log.Fatalf("%v: %v", filename, err)
+	}
+	lo, hi = rune(x), rune(x)
+	if len(parts) == 2 {
+		if x, err = strconv.ParseUint(parts[1], 16, 32); err != nil {
+			log.Fatalf("%v: %v", filename, err)
+		}
+		hi = rune(x)
+	}
+	return lo, hi
+}
+
+// open ucd file from directory or url.
+func open(filename string) io.ReadCloser {
+	if strings.HasPrefix(options.ucd, "http://") ||
+		strings.HasPrefix(options.ucd, "https://") {
+
+		url := options.ucd + "/" + filename
+		resp, err := http.Get(url)
+		if err != nil {
+			log.Fatal(err)
+		} else if resp.StatusCode != http.StatusOK {
+			log.Fatalf("%v: %v", url, resp.Status)
+		}
+		return resp.Body
+	}
+	fd, err := os.Open(path.Join(options.ucd, filename))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return fd
+}
+
+func setRange(props map[rune]string, lo, hi rune, name string) {
+	for r := lo; r <= hi; r++ {
+		if old, ok := props[r]; ok && old != name {
+			log.Fatalf("%U has properties %v and %v", r, old, name)
+		}
+		props[r] = name
+	}
+}
+
+// writeTable with adjacent code points of same property merged into
+// a single range.
+func writeTable(w io.Writer, name, typ string, props map[rune]string) {
+	runes := make([]rune, 0, len(props))
+	for r := range props {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	fmt.Fprintf(w, "var %v = []%v{\n", name, typ)
+	for i := 0; i < len(runes); {
+		lo, j := runes[i], i
+		for j+1 < len(runes) && runes[j+1] == runes[j]+1 &&
+			props[runes[j+1]] == props[lo] {
+			j++
+		}
+		fmt.Fprintf(w, "\t{0x%04x, 0x%04x, %v},\n", lo, runes[j], props[lo])
+		i = j + 1
+	}
+	fmt.Fprintf(w, "}\n")
+}